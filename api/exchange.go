@@ -0,0 +1,35 @@
+package api
+
+import (
+    "context"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+// Venue agnostic view of an exchange, strategies should depend on this and
+// not on concrete client (krakenftr, paper trading, mocks, ...)
+// Every call takes ctx, cancelling it aborts in-flight request (and retries)
+// Kept to what every venue has; narrower needs (funding, transfers, ...) are
+// small interfaces declared where they are consumed (see jobs)
+
+
+type Exchange interface {
+    // Market data
+    GetOHLC(ctx context.Context, tickType, symbol, resolution string, sinceDays int) (*types.CandleResponseWithMeta, error)
+    GetTicker(ctx context.Context, symbol string) (*types.TickerResponse, error)
+
+    // Account
+    GetOpenPositions(ctx context.Context) (*types.OpenPositionResponse, error)
+    GetOpenOrders(ctx context.Context) (*types.OpenOrdersResponse, error)
+    GetOrderFills(ctx context.Context, lastFillTime time.Time) (*types.FillsResponse, error)
+    FetchFillsSince(ctx context.Context, since time.Time) ([]types.Fill, error)
+
+    // Trading
+    SendOrder(ctx context.Context, orderReq types.SendOrderRequest) (*types.SendOrderResponse, error)
+    BatchSendOrders(ctx context.Context, orderReqList []types.SendOrderRequest) (*types.BatchOrderResponse, error)
+    BatchCancelOrders(ctx context.Context, orderIDs []string) (*types.BatchOrderResponse, error)
+    EditOrder(ctx context.Context, editReq types.EditOrderRequest) (*types.EditOrderResponse, error)
+    CancelOrder(ctx context.Context, orderId, cliOrdId string) (*types.CancelOrderResponse, error)
+    CancelAllOrders(ctx context.Context, symbol string) (*types.CancelAllOrdersResponse, error)
+}
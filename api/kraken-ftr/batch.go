@@ -0,0 +1,242 @@
+package krakenftr
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/url"
+    "strconv"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+// One batchorder builder for send, edit and cancel instructions. Batches
+// larger than MaxBatchSize are split into several calls (in order), results
+// come back per instruction: sends are matched by order_tag, edits and
+// cancels by order_id/cliOrdId. Never retried, same as BatchSendOrders.
+
+
+// Instructions per batchorder call
+const MaxBatchSize = 100
+
+
+var ErrBatchAborted = errors.New("not sent, earlier batchorder call failed")
+
+
+type batchItem struct {
+    order       string      // send, edit, cancel
+    send        types.SendOrderRequest
+    edit        types.EditOrderRequest
+    orderId     string      // cancel
+    cliOrdId    string      // cancel
+}
+
+
+// ex.: NewBatch().Send(entry).Edit(moveStop).Cancel(oldTp, "")
+type Batch struct {
+    items   []batchItem
+}
+
+
+func NewBatch() *Batch {
+    return &Batch{}
+}
+
+
+func (b *Batch) Send(orders ...types.SendOrderRequest) *Batch {
+    for _, order := range orders {
+        b.items = append(b.items, batchItem{order: "send", send: order})
+    }
+    return b
+}
+
+
+func (b *Batch) Edit(edits ...types.EditOrderRequest) *Batch {
+    for _, edit := range edits {
+        b.items = append(b.items, batchItem{order: "edit", edit: edit})
+    }
+    return b
+}
+
+
+// One of orderId/cliOrdId
+func (b *Batch) Cancel(orderId, cliOrdId string) *Batch {
+    b.items = append(b.items, batchItem{order: "cancel", orderId: orderId, cliOrdId: cliOrdId})
+    return b
+}
+
+
+func (b *Batch) Len() int {
+    return len(b.items)
+}
+
+
+//{{{ Execute
+// Result per instruction (same order as built). Instructions failing local
+// checks get Err and are left out, the rest is sent. error is only returned
+// when a batchorder call failed, its instructions and everything after get Err
+func (exch *Exchange) ExecuteBatch(ctx context.Context, batch *Batch) ([]types.BatchResult, error) {
+    results := make([]types.BatchResult, len(batch.items))
+    instructions := make([]map[string]any, len(batch.items))
+    for i, item := range batch.items {
+        results[i] = types.BatchResult{Index: i, Order: item.order, OrderTag: strconv.Itoa(i)}
+        var err error
+        switch item.order {
+        case "send":
+            var prepared []types.SendOrderRequest
+            if prepared, err = exch.prepareOrders(ctx, []types.SendOrderRequest{item.send}); err == nil {
+                results[i].CliOrdId = prepared[0].CliOrdId
+                instructions[i], err = structToMapBSO(prepared[0], i)
+            }
+        case "edit":
+            results[i].OrderId, results[i].CliOrdId = item.edit.OrderId, item.edit.CliOrdId
+            if err = item.edit.Validate(); err == nil {
+                instructions[i] = editInstruction(item.edit)
+            }
+        case "cancel":
+            results[i].OrderId, results[i].CliOrdId = item.orderId, item.cliOrdId
+            if (item.orderId == "") == (item.cliOrdId == "") {
+                err = &types.ValidationError{Field: "orderId", Reason: "exactly one of orderId, cliOrdId required"}
+            } else {
+                instructions[i] = cancelInstruction(item.orderId, item.cliOrdId)
+            }
+        }
+        if err != nil {
+            results[i].Err = fmt.Errorf("batch %d (%s): %w", i, item.order, err)
+        }
+    }
+
+    var pending []int
+    for i := range results {
+        if results[i].Err == nil {
+            pending = append(pending, i)
+        }
+    }
+    _, err := exch.runBatch(ctx, pending, instructions, results)
+    return results, err
+}
+
+
+// Sends instructions[pending] in MaxBatchSize chunks and fills results,
+// returns serverTime of last call
+func (exch *Exchange) runBatch(ctx context.Context, pending []int, instructions []map[string]any, results []types.BatchResult) (string, error) {
+    serverTime := ""
+    for start := 0; start < len(pending); start += MaxBatchSize {
+        chunk := pending[start:min(start+MaxBatchSize, len(pending))]
+        batchOrder := make([]map[string]any, len(chunk))
+        for j, i := range chunk {
+            batchOrder[j] = instructions[i]
+        }
+        data, err := json.Marshal(map[string]any{"batchOrder": batchOrder})
+        var resp types.BatchOrderResponse
+        if err == nil {
+            body := url.Values{"json": {string(data)}}.Encode()
+            err = exch.doSigned(ctx, "POST", "/derivatives/api/v3/batchorder", "", "", body, &resp)
+        }
+        if err != nil {
+            for _, i := range chunk {
+                results[i].Err = err
+            }
+            for _, i := range pending[start+len(chunk):] {
+                results[i].Err = fmt.Errorf("%w: %w", ErrBatchAborted, err)
+            }
+            return serverTime, err
+        }
+        serverTime = resp.ServerTime
+        matchStatuses(chunk, resp.BatchStatus, results)
+    }
+    return serverTime, nil
+}
+
+
+// Kraken does not promise batchStatus order, sends carry order_tag, edits and
+// cancels are found by id; whatever is left is taken in order
+func matchStatuses(chunk []int, statuses []types.BatchStatus, results []types.BatchResult) {
+    byTag := map[string]int{}
+    for _, i := range chunk {
+        if results[i].Order == "send" {
+            byTag[results[i].OrderTag] = i
+        }
+    }
+    matched := map[int]bool{}
+    var leftover []types.BatchStatus
+    for _, status := range statuses {
+        i, ok := -1, false
+        if status.OrderTag != "" {
+            i, ok = byTag[status.OrderTag]
+        }
+        for _, j := range chunk {
+            if ok {
+                break
+            }
+            r := results[j]
+            if matched[j] || r.Order == "send" {
+                continue
+            }
+            if (r.OrderId != "" && r.OrderId == status.OrderId) || (r.CliOrdId != "" && r.CliOrdId == status.CliOrdId) {
+                i, ok = j, true
+            }
+        }
+        if !ok || matched[i] {
+            leftover = append(leftover, status)
+            continue
+        }
+        matched[i] = true
+        applyStatus(&results[i], status)
+    }
+    for _, i := range chunk {
+        if matched[i] {
+            continue
+        }
+        if len(leftover) == 0 {
+            results[i].Err = fmt.Errorf("batch %d (%s): no status in response", i, results[i].Order)
+            continue
+        }
+        applyStatus(&results[i], leftover[0])
+        leftover = leftover[1:]
+    }
+}
+
+
+func applyStatus(result *types.BatchResult, status types.BatchStatus) {
+    result.Status = status.Status
+    if status.OrderId != "" {
+        result.OrderId = status.OrderId
+    }
+}
+//}}} Execute
+
+
+//{{{ Instructions
+func editInstruction(edit types.EditOrderRequest) map[string]any {
+    m := map[string]any{"order": "edit"}
+    if edit.OrderId != "" {
+        m["order_id"] = edit.OrderId
+    }
+    if edit.CliOrdId != "" {
+        m["cliOrdId"] = edit.CliOrdId
+    }
+    if edit.Size != nil {
+        m["size"] = *edit.Size
+    }
+    if edit.LimitPrice != nil {
+        m["limitPrice"] = *edit.LimitPrice
+    }
+    if edit.StopPrice != nil {
+        m["stopPrice"] = *edit.StopPrice
+    }
+    return m
+}
+
+
+func cancelInstruction(orderId, cliOrdId string) map[string]any {
+    m := map[string]any{"order": "cancel"}
+    if orderId != "" {
+        m["order_id"] = orderId
+    } else {
+        m["cliOrdId"] = cliOrdId
+    }
+    return m
+}
+//}}} Instructions
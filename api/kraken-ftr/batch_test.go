@@ -0,0 +1,183 @@
+package krakenftr
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "testing"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+
+
+//{{{ Test batch builder
+// Mixed batch, statuses reversed by fake, every result still lands on its input
+func TestExecuteBatchMixed(t *testing.T) {
+    floatPtr := func(f float64) *float64 { return &f }
+    exch, srv := newFakeExchange(t)
+    srv.SetBatchStatusReversed(true)
+    resting, err := exch.SendOrder(t.Context(), types.SendOrderRequest{OrderType: "post", Symbol: "PF_BCHUSD", Side: "buy", Size: 1, LimitPrice: 520, CliOrdId: "resting"})
+    if err != nil {
+        t.Fatalf("SendOrder failed: %v", err)
+    }
+    if _, err := exch.SendOrder(t.Context(), types.SendOrderRequest{OrderType: "post", Symbol: "PF_BCHUSD", Side: "sell", Size: 1, LimitPrice: 580, CliOrdId: "old-tp"}); err != nil {
+        t.Fatalf("SendOrder failed: %v", err)
+    }
+
+    batch := NewBatch().
+        Send(types.SendOrderRequest{OrderType: "post", Symbol: "PF_BCHUSD", Side: "buy", Size: 1, LimitPrice: 510, CliOrdId: "new-1"}).
+        Send(types.SendOrderRequest{OrderType: "post", Symbol: "PF_BCHUSD", Side: "buy", Size: 1, LimitPrice: 560, CliOrdId: "crosses"}).
+        Edit(types.EditOrderRequest{OrderId: resting.SendStatus.OrderId, LimitPrice: floatPtr(515)}).
+        Cancel("", "old-tp").
+        Cancel("does-not-exist", "").
+        Send(types.SendOrderRequest{OrderType: "post", Symbol: "PF_BCHUSD", Side: "buy", Size: -1, LimitPrice: 500})
+    results, err := exch.ExecuteBatch(t.Context(), batch)
+    if err != nil {
+        t.Fatalf("ExecuteBatch failed: %v", err)
+    }
+
+    expected := []struct {
+        order       string
+        cliOrdId    string
+        status      string
+        accepted    bool
+        localErr    bool
+    }{
+        {"send",    "new-1",    "placed",           true,   false},
+        {"send",    "crosses",  "postWouldExecute", false,  false},
+        {"edit",    "",         "edited",           true,   false},
+        {"cancel",  "old-tp",   "cancelled",        true,   false},
+        {"cancel",  "",         "notFound",         false,  false},
+        {"send",    "",         "",                 false,  true},
+    }
+    if len(results) != len(expected) {
+        t.Fatalf("Wrong number of results\nExpected:\t%d\nGot:\t\t%d", len(expected), len(results))
+    }
+    for i, exp := range expected {
+        r := results[i]
+        if r.Index != i || r.Order != exp.order || r.Status != exp.status || r.Accepted() != exp.accepted || (r.Err != nil) != exp.localErr {
+            t.Errorf("Wrong result %d\nExpected:\t%+v\nGot:\t\t%+v", i, exp, r)
+        }
+        if exp.cliOrdId != "" && r.CliOrdId != exp.cliOrdId {
+            t.Errorf("Wrong cliOrdId %d\nExpected:\t%s\nGot:\t\t%s", i, exp.cliOrdId, r.CliOrdId)
+        }
+    }
+    var validationErr *types.ValidationError
+    if !errors.As(results[5].Err, &validationErr) {
+        t.Errorf("Expected *types.ValidationError, got %v", results[5].Err)
+    }
+    if results[2].OrderId != resting.SendStatus.OrderId || results[3].OrderId == "" {
+        t.Errorf("Order ids not mapped: %+v %+v", results[2], results[3])
+    }
+
+    open := srv.OpenOrders()
+    if len(open) != 2 || open[0].LimitPrice != 515 || open[1].CliOrdId == nil || *open[1].CliOrdId != "new-1" {
+        t.Errorf("Wrong open orders after batch: %+v", open)
+    }
+}
+
+
+// Oversized batch is split, one call per MaxBatchSize
+func TestExecuteBatchSplit(t *testing.T) {
+    _, srv := newFakeExchange(t)
+    transport := &countingTransport{}
+    exch := New(srv.URL, fakePublicKey, fakePrivateKey, WithHTTPClient(&http.Client{Timeout: 5 * time.Second, Transport: transport}))
+
+    batch := NewBatch()
+    n := MaxBatchSize*2 + 5
+    for i := range n {
+        batch.Send(types.SendOrderRequest{OrderType: "post", Symbol: "PF_BCHUSD", Side: "buy", Size: 1, LimitPrice: 400 + float64(i)/10, CliOrdId: fmt.Sprintf("split-%d", i)})
+    }
+    results, err := exch.ExecuteBatch(t.Context(), batch)
+    if err != nil {
+        t.Fatalf("ExecuteBatch failed: %v", err)
+    }
+    if got := transport.count.Load(); got != 3 {
+        t.Errorf("Wrong number of batchorder calls\nExpected:\t3\nGot:\t\t%d", got)
+    }
+    for i, r := range results {
+        if !r.Accepted() || r.CliOrdId != fmt.Sprintf("split-%d", i) {
+            t.Fatalf("Wrong result %d: %+v", i, r)
+        }
+    }
+    if got := len(srv.OpenOrders()); got != n {
+        t.Errorf("Wrong number of open orders\nExpected:\t%d\nGot:\t\t%d", n, got)
+    }
+}
+
+
+// Failed call: its instructions get the error, later chunks are not sent
+func TestExecuteBatchAborted(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    batch := NewBatch()
+    for i := range MaxBatchSize + 1 {
+        batch.Send(types.SendOrderRequest{OrderType: "post", Symbol: "PF_BCHUSD", Side: "buy", Size: 1, LimitPrice: 400 + float64(i)/10})
+    }
+    srv.QueueFailure(502, "bad gateway")
+    results, err := exch.ExecuteBatch(t.Context(), batch)
+    var statusErr *StatusError
+    if !errors.As(err, &statusErr) {
+        t.Fatalf("Expected *StatusError, got %v", err)
+    }
+    if !errors.As(results[0].Err, &statusErr) || errors.Is(results[0].Err, ErrBatchAborted) {
+        t.Errorf("Wrong error for failed chunk: %v", results[0].Err)
+    }
+    if last := results[MaxBatchSize].Err; !errors.Is(last, ErrBatchAborted) {
+        t.Errorf("Expected ErrBatchAborted for later chunk, got %v", last)
+    }
+    if got := len(srv.OpenOrders()); got != 0 {
+        t.Errorf("Orders sent after failed chunk: %d", got)
+    }
+}
+
+
+// Second chunk fails: first one is live and comes back with order ids
+func TestBatchPartialFailure(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    n := MaxBatchSize + 5
+    orders := make([]types.SendOrderRequest, n)
+    for i := range orders {
+        orders[i] = types.SendOrderRequest{OrderType: "post", Symbol: "PF_BCHUSD", Side: "buy", Size: 1, LimitPrice: 400 + float64(i)/10}
+    }
+    srv.QueueFailureAfter(1, 502, "bad gateway")
+    sent, err := exch.BatchSendOrders(t.Context(), orders)
+    var statusErr *StatusError
+    if !errors.As(err, &statusErr) {
+        t.Fatalf("Expected *StatusError, got %v", err)
+    }
+    if sent == nil || len(sent.BatchStatus) != n {
+        t.Fatalf("Expected partial response with %d statuses, got %+v", n, sent)
+    }
+    orderIds := []string{}
+    for i, status := range sent.BatchStatus {
+        placed := i < MaxBatchSize
+        if placed && (status.Err != nil || status.Status != "placed" || status.OrderId == "") {
+            t.Fatalf("Wrong status %d of sent chunk: %+v", i, status)
+        }
+        if !placed && (status.Err == nil || status.OrderId != "") {
+            t.Fatalf("Wrong status %d of failed chunk: %+v", i, status)
+        }
+        if placed {
+            orderIds = append(orderIds, status.OrderId)
+        }
+    }
+    if got := len(srv.OpenOrders()); got != MaxBatchSize {
+        t.Errorf("Wrong number of open orders\nExpected:\t%d\nGot:\t\t%d", MaxBatchSize, got)
+    }
+
+    // Same for cancel, ids from partial response are enough to clean up
+    srv.QueueFailureAfter(1, 502, "bad gateway")
+    cancelled, err := exch.BatchCancelOrders(t.Context(), append(orderIds, "unknown-1", "unknown-2"))
+    if !errors.As(err, &statusErr) {
+        t.Fatalf("Expected *StatusError, got %v", err)
+    }
+    if cancelled == nil || cancelled.BatchStatus[0].Status != "cancelled" || cancelled.BatchStatus[MaxBatchSize].Err == nil {
+        t.Fatalf("Wrong partial cancel response: %+v", cancelled)
+    }
+    if got := len(srv.OpenOrders()); got != 0 {
+        t.Errorf("Wrong number of open orders\nExpected:\t0\nGot:\t\t%d", got)
+    }
+}
+//}}} Test batch builder
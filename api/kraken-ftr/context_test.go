@@ -0,0 +1,93 @@
+package krakenftr
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+
+// Counts requests going through custom transport
+type countingTransport struct {
+    count   atomic.Int32
+}
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    c.count.Add(1)
+    return http.DefaultTransport.RoundTrip(req)
+}
+
+
+//{{{ Test context
+func TestContextCancelledBeforeCall(t *testing.T) {
+    exch, _ := newFakeExchange(t)
+    ctx, cancel := context.WithCancel(t.Context())
+    cancel()
+    _, err := exch.GetOpenOrders(ctx)
+    if !errors.Is(err, context.Canceled) {
+        t.Fatalf("Expected context.Canceled, got %T: %v", err, err)
+    }
+}
+
+
+// Hung server: deadline aborts request, nothing is retried past it
+func TestContextDeadlineHungServer(t *testing.T) {
+    hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        <-r.Context().Done()
+    }))
+    t.Cleanup(hung.Close)
+    exch := New(hung.URL, fakePublicKey, fakePrivateKey)
+
+    ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+    defer cancel()
+    start := time.Now()
+    _, err := exch.GetOpenPositions(ctx)
+    if !errors.Is(err, context.DeadlineExceeded) {
+        t.Fatalf("Expected context.DeadlineExceeded, got %T: %v", err, err)
+    }
+    var transportErr *TransportError
+    if !errors.As(err, &transportErr) {
+        t.Errorf("Expected *TransportError, got %T", err)
+    }
+    if elapsed := time.Since(start); elapsed > 2*time.Second {
+        t.Errorf("Cancellation took too long: %v", elapsed)
+    }
+}
+
+
+// Backoff sleep is cut short by ctx
+func TestContextCancelsBackoff(t *testing.T) {
+    exch, srv := newFakeExchange(t, WithRetryPolicy(RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour}))
+    exch.sleep = sleepCtx
+    srv.QueueFailure(502, "bad gateway")
+
+    ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+    defer cancel()
+    _, err := exch.GetOpenOrders(ctx)
+    if !errors.Is(err, context.DeadlineExceeded) {
+        t.Fatalf("Expected context.DeadlineExceeded, got %T: %v", err, err)
+    }
+}
+
+
+func TestWithHTTPClient(t *testing.T) {
+    _, srv := newFakeExchange(t)
+    transport := &countingTransport{}
+    client := &http.Client{Timeout: 5 * time.Second, Transport: transport}
+    exch := New(srv.URL, fakePublicKey, fakePrivateKey, WithHTTPClient(client))
+
+    if _, err := exch.GetOpenPositions(t.Context()); err != nil {
+        t.Fatalf("GetOpenPositions failed: %v", err)
+    }
+    if _, err := exch.GetInstruments(t.Context()); err != nil {
+        t.Fatalf("GetInstruments failed: %v", err)
+    }
+    // Signed and public both go through same client
+    if got := transport.count.Load(); got != 2 {
+        t.Errorf("Wrong number of requests through custom client\nExpected:\t2\nGot:\t\t%d", got)
+    }
+}
+//}}} Test context
@@ -0,0 +1,98 @@
+package krakenftr
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+// Heartbeat for cancelallordersafter: re-arms the switch every Every so orders
+// stay alive only while this process does. Stopping (ctx done) disarms it;
+// a crash or lost network does not, Kraken cancels everything after Timeout.
+
+
+const disarmTimeout = 5 * time.Second
+
+
+type DeadManSwitch struct {
+    exch        *Exchange
+    Timeout     time.Duration   // whole seconds, Kraken recommends 60s
+    Every       time.Duration   // re-arm interval, has to be well below Timeout
+    Errors      chan error      // failed re-arms, dropped when nobody reads; closed by Run
+
+    started     atomic.Bool     // Run is one-shot, see ErrAlreadyRun
+    mu          sync.Mutex
+    triggerTime string
+}
+
+
+// Re-arms every timeout/4, ex.: NewDeadManSwitch(60*time.Second) => every 15s
+func (exch *Exchange) NewDeadManSwitch(timeout time.Duration) *DeadManSwitch {
+    return &DeadManSwitch{
+        exch:       exch,
+        Timeout:    timeout,
+        Every:      timeout / 4,
+        Errors:     make(chan error, 16),
+    }
+}
+
+
+// Last triggerTime Kraken reported, empty before first arm / after disarm
+func (d *DeadManSwitch) TriggerTime() string {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    return d.triggerTime
+}
+
+
+// Blocks until ctx is done, first arm failing is returned right away.
+// Failed re-arm goes to Errors and is tried again next tick, switch stays
+// armed from previous call meanwhile. One-shot, second call returns ErrAlreadyRun
+func (d *DeadManSwitch) Run(ctx context.Context) error {
+    if !d.started.CompareAndSwap(false, true) {
+        return ErrAlreadyRun
+    }
+    defer close(d.Errors)
+    if d.Timeout < time.Second || d.Every <= 0 || d.Every >= d.Timeout {
+        return fmt.Errorf("dead man switch: need Timeout >= 1s and 0 < Every < Timeout, got %v/%v", d.Timeout, d.Every)
+    }
+    if err := d.arm(ctx, d.Timeout); err != nil {
+        return err
+    }
+
+    ticker := time.NewTicker(d.Every)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            // ctx is gone, disarm gets its own short deadline
+            disarmCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), disarmTimeout)
+            defer cancel()
+            return errors.Join(ctx.Err(), d.arm(disarmCtx, 0))
+        case <-ticker.C:
+            if err := d.arm(ctx, d.Timeout); err != nil && ctx.Err() == nil {
+                select {
+                case d.Errors <- err:
+                default:
+                }
+            }
+        }
+    }
+}
+
+
+func (d *DeadManSwitch) arm(ctx context.Context, timeout time.Duration) error {
+    resp, err := d.exch.CancelAllOrdersAfter(ctx, timeout)
+    if err != nil {
+        return err
+    }
+    d.mu.Lock()
+    d.triggerTime = resp.Status.TriggerTime
+    if timeout == 0 {
+        d.triggerTime = ""
+    }
+    d.mu.Unlock()
+    return nil
+}
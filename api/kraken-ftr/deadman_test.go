@@ -0,0 +1,85 @@
+package krakenftr
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+
+
+//{{{ Test dead man switch
+// Armed switch fires once fake clock passes trigger time
+func TestCancelAllOrdersAfter(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    now := time.Date(2025, 9, 23, 12, 0, 0, 0, time.UTC)
+    srv.SetClock(func() time.Time { return now })
+    if _, err := exch.SendOrder(t.Context(), types.SendOrderRequest{OrderType: "lmt", Symbol: "PF_BCHUSD", Side: "buy", Size: 1, LimitPrice: 500}); err != nil {
+        t.Fatalf("SendOrder failed: %v", err)
+    }
+
+    armed, err := exch.CancelAllOrdersAfter(t.Context(), 60*time.Second)
+    if err != nil {
+        t.Fatalf("CancelAllOrdersAfter failed: %v", err)
+    }
+    if armed.Status.TriggerTime != "2025-09-23T12:01:00.000Z" {
+        t.Errorf("Wrong trigger time\nExpected:\t2025-09-23T12:01:00.000Z\nGot:\t\t%s", armed.Status.TriggerTime)
+    }
+    now = now.Add(59 * time.Second)
+    if open, _ := exch.GetOpenOrders(t.Context()); len(open.OpenOrders) != 1 {
+        t.Fatalf("Orders cancelled before trigger time")
+    }
+    now = now.Add(time.Second)
+    if open, _ := exch.GetOpenOrders(t.Context()); len(open.OpenOrders) != 0 {
+        t.Errorf("Orders not cancelled after trigger time: %+v", open.OpenOrders)
+    }
+
+    var validationErr *types.ValidationError
+    if _, err := exch.CancelAllOrdersAfter(t.Context(), 500*time.Millisecond); !errors.As(err, &validationErr) {
+        t.Errorf("Expected *types.ValidationError for sub second timeout, got %v", err)
+    }
+}
+
+
+// Heartbeat keeps re-arming, stopping disarms
+func TestDeadManSwitchRun(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    dms := exch.NewDeadManSwitch(60 * time.Second)
+    dms.Every = 10 * time.Millisecond
+    ctx, cancel := context.WithCancel(t.Context())
+    done := make(chan error)
+    go func() { done <- dms.Run(ctx) }()
+
+    waitFor(t, "re-arms", func() bool {
+        _, arms := srv.DeadManSwitch()
+        return arms >= 3
+    })
+    if dms.TriggerTime() == "" {
+        t.Errorf("TriggerTime not set while running")
+    }
+    cancel()
+    if err := <-done; !errors.Is(err, context.Canceled) {
+        t.Errorf("Expected context.Canceled, got %v", err)
+    }
+    if at, _ := srv.DeadManSwitch(); !at.IsZero() || dms.TriggerTime() != "" {
+        t.Errorf("Switch still armed after stop: %v", at)
+    }
+    // Errors is closed already, second Run must not close it again
+    if err := dms.Run(t.Context()); !errors.Is(err, ErrAlreadyRun) {
+        t.Errorf("Expected ErrAlreadyRun, got %v", err)
+    }
+}
+
+
+func TestDeadManSwitchInvalid(t *testing.T) {
+    exch, _ := newFakeExchange(t)
+    dms := exch.NewDeadManSwitch(60 * time.Second)
+    dms.Every = 2 * time.Minute
+    if err := dms.Run(t.Context()); err == nil {
+        t.Errorf("Expected error when Every >= Timeout")
+    }
+}
+//}}} Test dead man switch
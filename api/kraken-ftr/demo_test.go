@@ -17,21 +17,15 @@ import (
 
 
 var demo *Exchange
-var sleepTime time.Duration
 
 func TestMain(m *testing.M) {
     apiKeyPublic := os.Getenv("KRAKEN_API_KEY_PUBLIC")
     apiKeyPrivate := os.Getenv("KRAKEN_API_KEY_PRIVATE")
-    if apiKeyPublic == "" || apiKeyPrivate == "" {
-        panic("API keys not set ")
+    // Without keys demo tests are skipped, offline tests (fake server) still run
+    if apiKeyPublic != "" && apiKeyPrivate != "" {
+        // Initialize once for all tests
+        demo = New(DemoURL, apiKeyPublic, apiKeyPrivate)
     }
-    // Initialize once for all tests
-    demo = &Exchange{
-        baseURL: "https://demo-futures.kraken.com",
-        publicKey: apiKeyPublic,
-        privateKey: apiKeyPrivate,
-    }
-    sleepTime = 4 * time.Second
 
     // Run tests
     code := m.Run()
@@ -39,13 +33,20 @@ func TestMain(m *testing.M) {
 }
 
 
+func requireDemo(t *testing.T) {
+    t.Helper()
+    if demo == nil {
+        t.Skip("KRAKEN_API_KEY_PUBLIC/KRAKEN_API_KEY_PRIVATE not set")
+    }
+}
+
+
 //{{{ Test GetOHLC
 func TestGetOHLC(t *testing.T) {
+    requireDemo(t)
     num_days := 13
-    t.Logf("Sleep %d sec", sleepTime/1000000000)
-    time.Sleep(sleepTime)
 
-    candles, err := demo.GetOHLC("trade", "PF_BCHUSD", "1d", num_days)
+    candles, err := demo.GetOHLC(t.Context(), "trade", "PF_BCHUSD", "1d", num_days)
     if err != nil {
         t.Fatalf("GetOHLC failed: %v", err)
     }
@@ -67,9 +68,8 @@ func TestGetOHLC(t *testing.T) {
 
 //{{{ Test GetOpenPositions
 func TestGetOpenPositions(t *testing.T) {
-    t.Logf("Sleep %d sec", sleepTime/1000000000)
-    time.Sleep(sleepTime)
-    result, err := demo.GetOpenPositions()
+    requireDemo(t)
+    result, err := demo.GetOpenPositions(t.Context())
     if err != nil {
         t.Fatalf("GetOpenPostions failed: %v", err)
     }
@@ -82,9 +82,8 @@ func TestGetOpenPositions(t *testing.T) {
 
 
 func TestGetTicker(t *testing.T) {
-    t.Logf("Sleep %d sec", sleepTime/1000000000)
-    time.Sleep(sleepTime)
-    result, err := demo.GetTicker("PF_BCHUSD")
+    requireDemo(t)
+    result, err := demo.GetTicker(t.Context(), "PF_BCHUSD")
     if err != nil {
         t.Fatalf("GetTicker failed: %v", err)
     }
@@ -99,9 +98,8 @@ func TestGetTicker(t *testing.T) {
 
 //{{{ Test GetActiveOrders
 func TestGetActiveOrders(t *testing.T) {
-    t.Logf("Sleep %d sec", sleepTime/1000000000)
-    time.Sleep(sleepTime)
-    result, err := demo.GetOpenOrders()
+    requireDemo(t)
+    result, err := demo.GetOpenOrders(t.Context())
     if err != nil {
         t.Fatalf("GetOpenOrders failed: %v", err)
     }
@@ -116,6 +114,7 @@ func TestGetActiveOrders(t *testing.T) {
 
 //{{{ Test BatchOrder
 func TestBatchOrder(t *testing.T) {
+    requireDemo(t)
     // PREPARATION
     orderIDs := []string{}
     postOrderReq := types.SendOrderRequest {
@@ -147,9 +146,7 @@ func TestBatchOrder(t *testing.T) {
     batchOrder = append(batchOrder, stpOrderReq)
 
     // SEND BATCH ORDERS
-    t.Logf("Sleep %d sec", sleepTime/1000000000)
-    time.Sleep(sleepTime)
-    result, err := demo.BatchSendOrders(batchOrder)
+    result, err := demo.BatchSendOrders(t.Context(), batchOrder)
     if err != nil {
         t.Fatalf("BatchSendOrders failed: %v", err)
     }
@@ -162,9 +159,7 @@ func TestBatchOrder(t *testing.T) {
     t.Logf("Sent order ID's: %v", orderIDs)
 
     // CANCEL BATCH ORDERS
-    t.Logf("Sleep %d sec", sleepTime/1000000000)
-    time.Sleep(sleepTime)
-    result, err = demo.BatchCancelOrders(orderIDs)
+    result, err = demo.BatchCancelOrders(t.Context(), orderIDs)
     if err != nil {
         t.Fatalf("BatchCancelOrders failed: %v", err)
     }
@@ -178,9 +173,8 @@ func TestBatchOrder(t *testing.T) {
 
 //{{{ Test GetOrderFills
 func TestGetOrderFills(t *testing.T) {
-    t.Logf("Sleep %d sec", sleepTime/1000000000)
-    time.Sleep(sleepTime)
-    result, err := demo.GetOrderFills(0) // 0 for last 100 fills
+    requireDemo(t)
+    result, err := demo.GetOrderFills(t.Context(), time.Time{}) // zero for last 100 fills
     if err != nil {
         t.Fatalf("GetOrderFills failed: %v", err)
     }
@@ -209,7 +203,7 @@ trigger entry example:
     if err := lmtOrderReq.CreateTriggerEntryOrderId(); err != nil {
         t.Fatalf("CreateTriggerEntryOrderId failed: %v", err)
     }
-    result, err := demo.SendOrder(lmtOrderReq)
+    result, err := demo.SendOrder(t.Context(), lmtOrderReq)
 
 
 limit/post example:
@@ -221,7 +215,7 @@ limit/post example:
         LimitPrice: 550,
     }
     lmtOrderReq.CreateLimitOrderId()
-    result, err := demo.SendOrder(lmtOrderReq)
+    result, err := demo.SendOrder(t.Context(), lmtOrderReq)
 */
 //}}}
 
@@ -0,0 +1,153 @@
+package krakenftr
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "time"
+)
+// Every request method returns one of these (wrapped or not), so callers
+// can branch with errors.As, ex.:
+//  var rateErr *krakenftr.RateLimitError
+//  if errors.As(err, &rateErr) { back off }
+// Cancelled/expired ctx: errors.Is(err, context.Canceled / context.DeadlineExceeded)
+
+
+//{{{ Error types
+// Request never got proper response (DNS, connection reset, timeout, ...)
+type TransportError struct {
+    Method  string
+    URL     string
+    Err     error
+}
+func (e *TransportError) Error() string {
+    return fmt.Sprintf("%s %s: transport error: %v", e.Method, e.URL, e.Err)
+}
+func (e *TransportError) Unwrap() error { return e.Err }
+
+
+// Non 2xx status without Kraken error code in body
+type StatusError struct {
+    Endpoint    string
+    StatusCode  int
+    Body        string
+}
+func (e *StatusError) Error() string {
+    return fmt.Sprintf("%s: unexpected HTTP status %d: %s", e.Endpoint, e.StatusCode, e.Body)
+}
+
+
+// Kraken rejected key/signature, or signature could not be created locally (Err set)
+type AuthError struct {
+    Endpoint    string
+    Code        string
+    Err         error
+}
+func (e *AuthError) Error() string {
+    if e.Err != nil {
+        return fmt.Sprintf("%s: failed to sign request: %v", e.Endpoint, e.Err)
+    }
+    return fmt.Sprintf("%s: authentication failed: %s", e.Endpoint, e.Code)
+}
+func (e *AuthError) Unwrap() error { return e.Err }
+
+
+// `apiLimitExceeded`, HTTP 429, or local limiter (Code `clientRateLimit`, RetryAfter set)
+type RateLimitError struct {
+    Endpoint    string
+    Code        string
+    RetryAfter  time.Duration
+}
+func (e *RateLimitError) Error() string {
+    if e.RetryAfter > 0 {
+        return fmt.Sprintf("%s: rate limit exceeded: %s, retry after %s", e.Endpoint, e.Code, e.RetryAfter)
+    }
+    return fmt.Sprintf("%s: rate limit exceeded: %s", e.Endpoint, e.Code)
+}
+
+
+// `nonceBelowThreshold`, `nonceDuplicate`, or local NonceSource failed (Err set)
+type NonceError struct {
+    Endpoint    string
+    Code        string
+    Err         error
+}
+func (e *NonceError) Error() string {
+    if e.Err != nil {
+        return fmt.Sprintf("%s: nonce source failed: %v", e.Endpoint, e.Err)
+    }
+    return fmt.Sprintf("%s: nonce rejected: %s", e.Endpoint, e.Code)
+}
+func (e *NonceError) Unwrap() error { return e.Err }
+
+
+// Any other `result: error`, Code is Kraken's error string as is
+type ExchangeError struct {
+    Endpoint    string
+    Code        string
+}
+func (e *ExchangeError) Error() string {
+    return fmt.Sprintf("%s: rejected by exchange: %s", e.Endpoint, e.Code)
+}
+
+
+// Response body is not what we expected
+type DecodeError struct {
+    Endpoint    string
+    Err         error
+}
+func (e *DecodeError) Error() string {
+    return fmt.Sprintf("%s: failed to decode response: %v", e.Endpoint, e.Err)
+}
+func (e *DecodeError) Unwrap() error { return e.Err }
+//}}} Error types
+
+
+//{{{ Classify
+// Error code from Kraken (`error` field) into typed error
+func classifyCode(endpoint, code string) error {
+    switch code {
+    case "authenticationError":
+        return &AuthError{Endpoint: endpoint, Code: code}
+    case "apiLimitExceeded":
+        return &RateLimitError{Endpoint: endpoint, Code: code}
+    case "nonceBelowThreshold", "nonceDuplicate":
+        return &NonceError{Endpoint: endpoint, Code: code}
+    }
+    return &ExchangeError{Endpoint: endpoint, Code: code}
+}
+
+
+// nil when response is 2xx and not `result: error`
+func checkResponse(endpoint string, statusCode int, body []byte) error {
+    // Kraken sometimes puts error code into body even on non 2xx
+    var envelope struct {
+        Result  string  `json:"result"`
+        Error   string  `json:"error"`
+    }
+    decodeErr := json.Unmarshal(body, &envelope)
+    if decodeErr == nil && (envelope.Result == "error" || envelope.Error != "") {
+        code := envelope.Error
+        if code == "" {
+            code = "unknownError"
+        }
+        return classifyCode(endpoint, code)
+    }
+
+    if statusCode >= 200 && statusCode < 300 {
+        return nil
+    }
+    switch statusCode {
+    case http.StatusTooManyRequests:
+        return &RateLimitError{Endpoint: endpoint, Code: http.StatusText(statusCode)}
+    case http.StatusUnauthorized, http.StatusForbidden:
+        return &AuthError{Endpoint: endpoint, Code: http.StatusText(statusCode)}
+    }
+    // Cap body, HTML error pages can be huge
+    text := string(body)
+    if len(text) > 512 {
+        text = text[:512]
+    }
+    return &StatusError{Endpoint: endpoint, StatusCode: statusCode, Body: text}
+}
+//}}} Classify
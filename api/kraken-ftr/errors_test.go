@@ -0,0 +1,91 @@
+package krakenftr
+
+import (
+    "errors"
+    "testing"
+)
+
+
+//{{{ Test error classification
+func TestErrorClassification(t *testing.T) {
+    tests := []struct {
+        name        string
+        status      int
+        body        string
+        check       func(error) bool
+    }{
+        {
+            name:   "RateLimitCode",
+            status: 200,
+            body:   `{"result":"error","error":"apiLimitExceeded","serverTime":"2025-09-23T16:55:10.557Z"}`,
+            check:  func(err error) bool { var e *RateLimitError; return errors.As(err, &e) && e.Code == "apiLimitExceeded" },
+        }, {
+            name:   "RateLimitStatus",
+            status: 429,
+            body:   `Too Many Requests`,
+            check:  func(err error) bool { var e *RateLimitError; return errors.As(err, &e) },
+        }, {
+            name:   "Nonce",
+            status: 200,
+            body:   `{"result":"error","error":"nonceBelowThreshold"}`,
+            check:  func(err error) bool { var e *NonceError; return errors.As(err, &e) && e.Code == "nonceBelowThreshold" },
+        }, {
+            name:   "Auth",
+            status: 200,
+            body:   `{"result":"error","error":"authenticationError"}`,
+            check:  func(err error) bool { var e *AuthError; return errors.As(err, &e) },
+        }, {
+            name:   "ExchangeRejected",
+            status: 400,
+            body:   `{"result":"error","error":"accountInactive"}`,
+            check:  func(err error) bool { var e *ExchangeError; return errors.As(err, &e) && e.Code == "accountInactive" },
+        }, {
+            name:   "Status",
+            status: 502,
+            body:   `<html>bad gateway</html>`,
+            check:  func(err error) bool { var e *StatusError; return errors.As(err, &e) && e.StatusCode == 502 },
+        }, {
+            name:   "Decode",
+            status: 200,
+            body:   `{"result":"success","openOrders":`,
+            check:  func(err error) bool { var e *DecodeError; return errors.As(err, &e) },
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            exch, srv := newFakeExchange(t, WithRetryPolicy(NoRetry))
+            srv.QueueFailure(tc.status, tc.body)
+            result, err := exch.GetOpenOrders(t.Context())
+            if err == nil {
+                t.Fatalf("Expected error, got result: %+v", result)
+            }
+            if !tc.check(err) {
+                t.Errorf("Wrong error type: %T %v", err, err)
+            }
+        })
+    }
+}
+
+
+func TestTransportError(t *testing.T) {
+    exch, srv := newFakeExchange(t, WithRetryPolicy(NoRetry))
+    srv.Close()
+    _, err := exch.GetTicker(t.Context(), "PF_BCHUSD")
+    var transportErr *TransportError
+    if !errors.As(err, &transportErr) {
+        t.Fatalf("Expected TransportError, got: %T %v", err, err)
+    }
+}
+
+
+func TestSignError(t *testing.T) {
+    _, srv := newFakeExchange(t)
+    exch := New(srv.URL, fakePublicKey, "not base64 !!!")
+    _, err := exch.GetOpenPositions(t.Context())
+    var authErr *AuthError
+    if !errors.As(err, &authErr) || authErr.Err == nil {
+        t.Fatalf("Expected AuthError with cause, got: %T %v", err, err)
+    }
+}
+//}}} Test error classification
@@ -0,0 +1,633 @@
+package krakenftr
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "testing"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/api"
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/api/kraken-ftr/krakenfake"
+)
+// Offline tests against krakenfake, no keys, no network, no sleep
+
+
+const (
+    fakePublicKey   = "fake-public-key"
+    fakePrivateKey  = "BwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyAhIiMkJSYnKCkqKywtLi8wMTIzNDU2Nzg5Ojs8PT4/QEFCQ0RFRg=="
+)
+
+
+// Fake with BCH market at 550 and Exchange pointed to it, retry backoff does not sleep
+func newFakeExchange(t *testing.T, opts ...Option) (*Exchange, *krakenfake.Server) {
+    t.Helper()
+    srv := krakenfake.New(fakePublicKey, fakePrivateKey)
+    t.Cleanup(srv.Close)
+    srv.SetTicker(types.Ticker{Symbol: "PF_BCHUSD", MarkPrice: 550})
+
+    exch := New(srv.URL, fakePublicKey, fakePrivateKey, opts...)
+    exch.sleep = func(context.Context, time.Duration) error { return nil }
+    return exch, srv
+}
+
+
+//{{{ Test signature
+func TestFakeSignature(t *testing.T) {
+    tests := []struct {
+        name            string
+        publicKey       string
+        privateKey      string
+        expectAuthErr   bool
+    }{
+        {"Succ",                fakePublicKey,  fakePrivateKey, false},
+        {"FailWrongPublicKey",  "nope",         fakePrivateKey, true},
+        {"FailWrongPrivateKey", fakePublicKey,  "c2VjcmV0",     true},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            _, srv := newFakeExchange(t)
+            var exch api.Exchange = New(srv.URL, tc.publicKey, tc.privateKey)
+            result, err := exch.GetOpenPositions(t.Context())
+            var authErr *AuthError
+            if errors.As(err, &authErr) != tc.expectAuthErr {
+                t.Fatalf("Unexpected error: %v", err)
+            }
+            if tc.expectAuthErr {
+                if authErr.Code != "authenticationError" {
+                    t.Errorf("Wrong code\nExpected:\tauthenticationError\nGot:\t\t%s", authErr.Code)
+                }
+                return
+            }
+            if result.Result != "success" {
+                t.Errorf("Wrong result\nExpected:\tsuccess\nGot:\t\t%s", result.Result)
+            }
+        })
+    }
+}
+//}}} Test signature
+
+
+//{{{ Test GetOHLC
+func TestFakeGetOHLC(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    now := time.Now()
+    candles := []types.Candle{}
+    for i := 20; i > 0; i-- {
+        candles = append(candles, types.Candle{
+            Time:   now.Add(-time.Duration(i) * 24 * time.Hour).UnixMilli(),
+            Open:   500, High: 560, Low: 490, Close: 550, Volume: 12.5,
+        })
+    }
+    srv.SetCandles("trade", "PF_BCHUSD", "1d", candles)
+
+    result, err := exch.GetOHLC(t.Context(), "trade", "PF_BCHUSD", "1d", 13)
+    if err != nil {
+        t.Fatalf("GetOHLC failed: %v", err)
+    }
+    // Candle that is exactly 13 days old falls out due to time passing between calls
+    if got := len(result.Response.Candles); got < 12 || got > 13 {
+        t.Errorf("Wrong number of candles\nExpected:\t12-13\nGot:\t\t%d", got)
+    }
+    if result.Response.Candles[0].Open != 500 || result.Response.Candles[0].Volume != 12.5 {
+        t.Errorf("Candle not decoded: %+v", result.Response.Candles[0])
+    }
+    if result.Meta.Symbol != "PF_BCHUSD" || result.Meta.SinceDays != 13 {
+        t.Errorf("Wrong meta: %+v", result.Meta)
+    }
+}
+
+
+// Pages through more_candles, trims to range, fills holes, rejects bad enums
+func TestFakeGetOHLCRange(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+    candles := []types.Candle{}
+    for i := range 30 {
+        if i == 12 || i == 13 {
+            continue
+        }
+        candles = append(candles, types.Candle{Time: start.Add(time.Duration(i) * time.Hour).UnixMilli(), Open: 500, High: 510, Low: 495, Close: 500 + float64(i), Volume: 1})
+    }
+    srv.SetCandles("trade", "PF_BCHUSD", "1h", candles)
+    srv.SetCandleLimit(7)
+
+    tests := []struct {
+        name        string
+        query       types.CandleQuery
+        expected    int
+        expectErr   bool
+    }{
+        {"SuccAllPages",    types.CandleQuery{TickType: "trade", Symbol: "PF_BCHUSD", Resolution: "1h", From: start, To: start.Add(29 * time.Hour)},                         30, false},
+        {"SuccWindow",      types.CandleQuery{TickType: "trade", Symbol: "PF_BCHUSD", Resolution: "1h", From: start.Add(10 * time.Hour), To: start.Add(19 * time.Hour)},   10, false},
+        {"SuccOpenEnd",     types.CandleQuery{TickType: "trade", Symbol: "PF_BCHUSD", Resolution: "1h", From: start.Add(25 * time.Hour)},                                    5,  false},
+        {"SuccEmpty",       types.CandleQuery{TickType: "mark", Symbol: "PF_BCHUSD", Resolution: "1h", From: start},                                                         0,  false},
+        {"FailTickType",    types.CandleQuery{TickType: "last", Symbol: "PF_BCHUSD", Resolution: "1h", From: start},                                                         0,  true},
+        {"FailResolution",  types.CandleQuery{TickType: "trade", Symbol: "PF_BCHUSD", Resolution: "2h", From: start},                                                        0,  true},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            got, err := exch.GetOHLCRange(t.Context(), tc.query)
+            if (err != nil) != tc.expectErr {
+                t.Fatalf("Unexpected error: %v", err)
+            }
+            if len(got) != tc.expected {
+                t.Fatalf("Wrong number of candles\nExpected:\t%d\nGot:\t\t%d", tc.expected, len(got))
+            }
+            for i := 1; i < len(got); i++ {
+                if got[i].Time-got[i-1].Time != time.Hour.Milliseconds() {
+                    t.Fatalf("Gap or disorder at %d: %d after %d", i, got[i].Time, got[i-1].Time)
+                }
+            }
+            if len(got) > 0 && got[0].Time != tc.query.From.UnixMilli() {
+                t.Errorf("Wrong first candle\nExpected:\t%d\nGot:\t\t%d", tc.query.From.UnixMilli(), got[0].Time)
+            }
+        })
+    }
+
+    // Same pages, hole at 12-13 left as is
+    raw, err := exch.FetchCandles(t.Context(), tests[0].query)
+    if err != nil {
+        t.Fatalf("FetchCandles failed: %v", err)
+    }
+    if len(raw) != 28 || raw[12].Time != start.Add(14*time.Hour).UnixMilli() {
+        t.Errorf("Wrong raw candles\nExpected:\t28, 13th at hour 14\nGot:\t\t%d", len(raw))
+    }
+
+    _, err = exch.GetOHLC(t.Context(), "index", "PF_BCHUSD", "1h", 1)
+    var validationErr *types.ValidationError
+    if !errors.As(err, &validationErr) || validationErr.Field != "tickType" {
+        t.Errorf("Expected tickType ValidationError, got: %v", err)
+    }
+}
+//}}} Test GetOHLC
+
+
+//{{{ Test GetTicker
+func TestFakeGetTicker(t *testing.T) {
+    exch, _ := newFakeExchange(t)
+    result, err := exch.GetTicker(t.Context(), "PF_BCHUSD")
+    if err != nil {
+        t.Fatalf("GetTicker failed: %v", err)
+    }
+    if result.Result != "success" || result.Ticker.MarkPrice != 550 {
+        t.Errorf("Wrong ticker: %+v", result)
+    }
+}
+//}}} Test GetTicker
+
+
+//{{{ Test GetAccounts
+func TestFakeGetAccounts(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    srv.SetAccounts(types.Accounts{
+        Cash:   &types.CashAccount{Balances: map[string]float64{"usd": 25}},
+        Margin: map[string]types.MarginAccount{"fi_xbtusd": {Currency: "xbt", Auxiliary: types.MarginAuxiliary{Pv: 0.1}}},
+        Flex:   &types.FlexAccount{
+            Currencies:         map[string]types.FlexAccountCurrency{"USD": {Quantity: 1000, Value: 1000, Collateral: 1000, Available: 900}},
+            MarginEquity:       1050,
+            AvailableMargin:    800,
+        },
+    })
+    result, err := exch.GetAccounts(t.Context())
+    if err != nil {
+        t.Fatalf("GetAccounts failed: %v", err)
+    }
+    accounts := result.Accounts
+    if accounts.Cash == nil || accounts.Cash.Balances["usd"] != 25 || accounts.Flex == nil || accounts.Flex.Currencies["USD"].Available != 900 {
+        t.Errorf("Wrong accounts: %+v", accounts)
+    }
+    if m, ok := accounts.Margin["fi_xbtusd"]; !ok || m.Auxiliary.Pv != 0.1 {
+        t.Errorf("Wrong margin account: %+v", accounts.Margin)
+    }
+    // xbt account is not counted, needs price
+    if accounts.Equity() != 1050 || accounts.AvailableMargin() != 800 {
+        t.Errorf("Wrong equity/available margin\nExpected:\t1050 800\nGot:\t\t%v %v", accounts.Equity(), accounts.AvailableMargin())
+    }
+}
+//}}} Test GetAccounts
+
+
+//{{{ Test order lifecycle
+func TestFakeOrderLifecycle(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+
+    // Resting post order
+    postOrderReq := types.SendOrderRequest{
+        OrderType:  "post",
+        Symbol:     "PF_BCHUSD",
+        Side:       "buy",
+        Size:       0.25,
+        LimitPrice: 524.0,
+    }
+    postOrderReq.CreateLimitOrderId()
+    sent, err := exch.SendOrder(t.Context(), postOrderReq)
+    if err != nil {
+        t.Fatalf("SendOrder failed: %v", err)
+    }
+    if sent.SendStatus.Status != "placed" || sent.SendStatus.OrderId == "" {
+        t.Fatalf("Order not placed: %+v", sent)
+    }
+
+    openOrders, err := exch.GetOpenOrders(t.Context())
+    if err != nil {
+        t.Fatalf("GetOpenOrders failed: %v", err)
+    }
+    if len(openOrders.OpenOrders) != 1 || openOrders.OpenOrders[0].OrderId != sent.SendStatus.OrderId {
+        t.Fatalf("Wrong open orders: %+v", openOrders.OpenOrders)
+    }
+    if cliOrdId := openOrders.OpenOrders[0].CliOrdId; cliOrdId == nil || *cliOrdId != postOrderReq.CliOrdId {
+        t.Errorf("cliOrdId not kept: %v", cliOrdId)
+    }
+
+    // Market drops through limit, order fills and opens long
+    srv.SetTicker(types.Ticker{Symbol: "PF_BCHUSD", MarkPrice: 520})
+    fills, err := exch.GetOrderFills(t.Context(), time.Time{})
+    if err != nil {
+        t.Fatalf("GetOrderFills failed: %v", err)
+    }
+    if len(fills.Fills) != 1 || fills.Fills[0].Price != 524.0 || fills.Fills[0].FillType != "maker" {
+        t.Fatalf("Wrong fills: %+v", fills.Fills)
+    }
+    positions, err := exch.GetOpenPositions(t.Context())
+    if err != nil {
+        t.Fatalf("GetOpenPositions failed: %v", err)
+    }
+    if positions.OpenPositions == nil || len(*positions.OpenPositions) != 1 {
+        t.Fatalf("Wrong positions: %+v", positions.OpenPositions)
+    }
+    if pos := (*positions.OpenPositions)[0]; pos.Side != "long" || pos.Size != 0.25 || pos.Price != 524.0 {
+        t.Errorf("Wrong position: %+v", pos)
+    }
+
+    // Post that would cross is rejected
+    postOrderReq.LimitPrice = 530
+    postOrderReq.CreateLimitOrderId()
+    sent, err = exch.SendOrder(t.Context(), postOrderReq)
+    if err != nil {
+        t.Fatalf("SendOrder failed: %v", err)
+    }
+    if sent.SendStatus.Status != "postWouldExecute" {
+        t.Errorf("Wrong status\nExpected:\tpostWouldExecute\nGot:\t\t%s", sent.SendStatus.Status)
+    }
+}
+//}}} Test order lifecycle
+
+
+//{{{ Test edit/cancel
+func TestFakeEditOrder(t *testing.T) {
+    floatPtr := func(f float64) *float64 { return &f }
+    tests := []struct {
+        name            string
+        edit            types.EditOrderRequest
+        byOrderId       bool    // placed order's id is filled in
+        expectStatus    string
+        expectSize      float64
+        expectPrice     float64
+        expectErr       bool
+    }{
+        {"SuccPrice",           types.EditOrderRequest{LimitPrice: floatPtr(521)},                  true,  "edited",                   1, 521, false},
+        {"SuccSize",            types.EditOrderRequest{Size: floatPtr(2)},                          true,  "edited",                   2, 520, false},
+        {"SuccByCliOrdId",      types.EditOrderRequest{CliOrdId: "edit-me", Size: floatPtr(3)},     false, "edited",                   3, 520, false},
+        {"FailPostCrosses",     types.EditOrderRequest{LimitPrice: floatPtr(551)},                  true,  "postWouldExecute",         1, 520, false},
+        {"FailNotAStop",        types.EditOrderRequest{StopPrice: floatPtr(500)},                   true,  "orderForEditNotAStop",     1, 520, false},
+        {"FailNotFound",        types.EditOrderRequest{OrderId: "nope", Size: floatPtr(2)},         false, "orderForEditNotFound",     1, 520, false},
+        {"FailNothingToEdit",   types.EditOrderRequest{},                                           true,  "",                         1, 520, true},
+        {"FailBothIds",         types.EditOrderRequest{CliOrdId: "edit-me", Size: floatPtr(2)},     true,  "",                         1, 520, true},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            exch, srv := newFakeExchange(t)
+            sent, err := exch.SendOrder(t.Context(), types.SendOrderRequest{OrderType: "post", Symbol: "PF_BCHUSD", Side: "buy", Size: 1, LimitPrice: 520, CliOrdId: "edit-me"})
+            if err != nil {
+                t.Fatalf("SendOrder failed: %v", err)
+            }
+            edit := tc.edit
+            if tc.byOrderId {
+                edit.OrderId = sent.SendStatus.OrderId
+            }
+            resp, err := exch.EditOrder(t.Context(), edit)
+            if (err != nil) != tc.expectErr {
+                t.Fatalf("Wrong error\nExpected:\t%v\nGot:\t\t%v", tc.expectErr, err)
+            }
+            if err == nil && resp.EditStatus.Status != tc.expectStatus {
+                t.Errorf("Wrong status\nExpected:\t%s\nGot:\t\t%s", tc.expectStatus, resp.EditStatus.Status)
+            }
+            open := srv.OpenOrders()
+            if len(open) != 1 || open[0].UnfilledSize != tc.expectSize || open[0].LimitPrice != tc.expectPrice {
+                t.Errorf("Wrong order after edit: %+v", open)
+            }
+        })
+    }
+}
+
+
+func TestFakeCancelOrders(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    srv.SetTicker(types.Ticker{Symbol: "PF_XRPUSD", MarkPrice: 2.5})
+    orders := []types.SendOrderRequest{
+        {OrderType: "lmt", Symbol: "PF_BCHUSD", Side: "buy", Size: 1, LimitPrice: 500, CliOrdId: "bch-1"},
+        {OrderType: "lmt", Symbol: "PF_BCHUSD", Side: "sell", Size: 1, LimitPrice: 600, CliOrdId: "bch-2"},
+        {OrderType: "lmt", Symbol: "PF_BCHUSD", Side: "buy", Size: 1, LimitPrice: 501, CliOrdId: "bch-3"},
+        {OrderType: "lmt", Symbol: "PF_XRPUSD", Side: "buy", Size: 10, LimitPrice: 2, CliOrdId: "xrp-1"},
+    }
+    var orderIds []string
+    for _, o := range orders {
+        sent, err := exch.SendOrder(t.Context(), o)
+        if err != nil {
+            t.Fatalf("SendOrder failed: %v", err)
+        }
+        orderIds = append(orderIds, sent.SendStatus.OrderId)
+    }
+
+    // Single, by id then by cliOrdId; second cancel of same order is notFound
+    cancelled, err := exch.CancelOrder(t.Context(), orderIds[0], "")
+    if err != nil || cancelled.CancelStatus.Status != "cancelled" || cancelled.CancelStatus.OrderId != orderIds[0] {
+        t.Fatalf("CancelOrder by id failed: %+v %v", cancelled, err)
+    }
+    cancelled, err = exch.CancelOrder(t.Context(), "", "bch-2")
+    if err != nil || cancelled.CancelStatus.Status != "cancelled" || cancelled.CancelStatus.OrderId != orderIds[1] {
+        t.Fatalf("CancelOrder by cliOrdId failed: %+v %v", cancelled, err)
+    }
+    if cancelled, err = exch.CancelOrder(t.Context(), orderIds[0], ""); err != nil || cancelled.CancelStatus.Status != "notFound" {
+        t.Errorf("Expected notFound, got %+v %v", cancelled, err)
+    }
+    if _, err := exch.CancelOrder(t.Context(), "", ""); err == nil {
+        t.Errorf("Expected error without orderId/cliOrdId")
+    }
+
+    // All for one symbol, then everything
+    all, err := exch.CancelAllOrders(t.Context(), "PF_BCHUSD")
+    if err != nil {
+        t.Fatalf("CancelAllOrders failed: %v", err)
+    }
+    if s := all.CancelStatus; s.Status != "cancelled" || s.CancelOnly != "PF_BCHUSD" || len(s.CancelledOrders) != 1 || s.CancelledOrders[0].CliOrdId != "bch-3" {
+        t.Errorf("Wrong cancel all for symbol: %+v", s)
+    }
+    if all, err = exch.CancelAllOrders(t.Context(), ""); err != nil || all.CancelStatus.CancelOnly != "all" || len(all.CancelStatus.CancelledOrders) != 1 {
+        t.Errorf("Wrong cancel all: %+v %v", all, err)
+    }
+    if all, err = exch.CancelAllOrders(t.Context(), ""); err != nil || all.CancelStatus.Status != "noOrdersToCancel" {
+        t.Errorf("Expected noOrdersToCancel, got %+v %v", all, err)
+    }
+}
+//}}} Test edit/cancel
+
+
+//{{{ Test BatchOrder
+func TestFakeBatchOrder(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+
+    postOrderReq := types.SendOrderRequest{
+        OrderType:  "post",
+        Symbol:     "PF_BCHUSD",
+        Side:       "buy",
+        Size:       0.25,
+        LimitPrice: 524.0,
+    }
+    postOrderReq.CreateLimitOrderId()
+    stopPrice := 524.0
+    triggerSignal := "last"
+    stpOrderReq := types.SendOrderRequest{
+        OrderType:      "stp",
+        Symbol:         "PF_BCHUSD",
+        Side:           "sell",
+        Size:           0.25,
+        LimitPrice:     520.0,
+        StopPrice:      &stopPrice,
+        TriggerSignal:  &triggerSignal,
+    }
+    if err := stpOrderReq.CreateTriggerEntryOrderId(); err != nil {
+        t.Fatalf("CreateTriggerEntryOrderId failed: %v", err)
+    }
+
+    result, err := exch.BatchSendOrders(t.Context(), []types.SendOrderRequest{postOrderReq, stpOrderReq})
+    if err != nil {
+        t.Fatalf("BatchSendOrders failed: %v", err)
+    }
+    if len(result.BatchStatus) != 2 {
+        t.Fatalf("Wrong batch status: %+v", result.BatchStatus)
+    }
+    orderIDs := []string{}
+    for _, status := range result.BatchStatus {
+        if status.Status != "placed" {
+            t.Errorf("Order not placed: %+v", status)
+        }
+        orderIDs = append(orderIDs, status.OrderId)
+    }
+    if got := len(srv.OpenOrders()); got != 2 {
+        t.Fatalf("Wrong number of open orders\nExpected:\t2\nGot:\t\t%d", got)
+    }
+
+    result, err = exch.BatchCancelOrders(t.Context(), append(orderIDs, "does-not-exist"))
+    if err != nil {
+        t.Fatalf("BatchCancelOrders failed: %v", err)
+    }
+    expected := []string{"cancelled", "cancelled", "notFound"}
+    for i, status := range result.BatchStatus {
+        if status.Status != expected[i] {
+            t.Errorf("Wrong cancel status %d\nExpected:\t%s\nGot:\t\t%s", i, expected[i], status.Status)
+        }
+    }
+    if got := len(srv.OpenOrders()); got != 0 {
+        t.Errorf("Orders left after cancel: %d", got)
+    }
+}
+//}}} Test BatchOrder
+
+
+//{{{ Test GetOrderFills
+func TestFakeGetOrderFills(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    srv.AddFills(
+        types.Fill{FillId: "f1", Symbol: "PF_BCHUSD", Side: "buy", OrderId: "o1", Size: 1, Price: 500, FillTime: "2025-09-20T10:00:00.000Z", FillType: "maker"},
+        types.Fill{FillId: "f2", Symbol: "PF_BCHUSD", Side: "sell", OrderId: "o2", Size: 1, Price: 510, FillTime: "2025-09-21T10:00:00.000Z", FillType: "taker"},
+    )
+    result, err := exch.GetOrderFills(t.Context(), time.Time{})
+    if err != nil {
+        t.Fatalf("GetOrderFills failed: %v", err)
+    }
+    if result.Result != "success" || len(result.Fills) != 2 {
+        t.Fatalf("Wrong fills: %+v", result)
+    }
+    // Newest first
+    if result.Fills[0].FillId != "f2" {
+        t.Errorf("Wrong order\nExpected:\tf2\nGot:\t\t%s", result.Fills[0].FillId)
+    }
+}
+//}}} Test GetOrderFills
+
+
+//{{{ Test FetchFillsSince
+func TestFakeFetchFillsSince(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    // 250 fills one minute apart, 3 pages
+    start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+    for i := 0; i < 250; i++ {
+        srv.AddFills(types.Fill{
+            FillId:     fmt.Sprintf("fill-%03d", i),
+            Symbol:     "PF_BCHUSD",
+            Side:       "buy",
+            OrderId:    fmt.Sprintf("order-%03d", i),
+            Size:       0.1,
+            Price:      500,
+            FillTime:   start.Add(time.Duration(i) * time.Minute).Format(timeLayout),
+            FillType:   "maker",
+        })
+    }
+
+    tests := []struct {
+        name        string
+        since       time.Time
+        expectLen   int
+        expectFirst string
+    }{
+        {"SuccAll",         time.Time{},                        250,    "fill-000"},
+        {"SuccSinceMiddle", start.Add(120 * time.Minute),       130,    "fill-120"},
+        {"SuccSinceLast",   start.Add(249 * time.Minute),       1,      "fill-249"},
+        {"SuccFuture",      start.Add(1000 * time.Minute),      0,      ""},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            fills, err := exch.FetchFillsSince(t.Context(), tc.since)
+            if err != nil {
+                t.Fatalf("FetchFillsSince failed: %v", err)
+            }
+            if len(fills) != tc.expectLen {
+                t.Fatalf("Wrong number of fills\nExpected:\t%d\nGot:\t\t%d", tc.expectLen, len(fills))
+            }
+            if tc.expectLen == 0 {
+                return
+            }
+            if fills[0].FillId != tc.expectFirst {
+                t.Errorf("Wrong first fill\nExpected:\t%s\nGot:\t\t%s", tc.expectFirst, fills[0].FillId)
+            }
+            // Chronological, no duplicates
+            for i := 1; i < len(fills); i++ {
+                if fills[i-1].FillTime >= fills[i].FillTime {
+                    t.Fatalf("Fills not in order at %d: %s >= %s", i, fills[i-1].FillTime, fills[i].FillTime)
+                }
+            }
+        })
+    }
+}
+
+
+// One taker sweep = many fills in same millisecond, some of them land on next page
+func TestFakeFetchFillsSameMillisecond(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+    // Oldest 5 share one millisecond, page of 100 newest ends in middle of them
+    for i := 0; i < 102; i++ {
+        fillTime := start
+        if i >= 5 {
+            fillTime = start.Add(time.Duration(i) * time.Second)
+        }
+        srv.AddFills(types.Fill{
+            FillId:     fmt.Sprintf("fill-%03d", i),
+            Symbol:     "PF_BCHUSD",
+            Side:       "buy",
+            OrderId:    "sweep",
+            Size:       0.1,
+            Price:      500,
+            FillTime:   fillTime.Format(timeLayout),
+            FillType:   "taker",
+        })
+    }
+
+    fills, err := exch.FetchFillsSince(t.Context(), time.Time{})
+    if err != nil {
+        t.Fatalf("FetchFillsSince failed: %v", err)
+    }
+    if len(fills) != 102 {
+        t.Fatalf("Wrong number of fills\nExpected:\t102\nGot:\t\t%d", len(fills))
+    }
+    seen := map[string]bool{}
+    for _, fill := range fills {
+        if seen[fill.FillId] {
+            t.Fatalf("Duplicate fill %s", fill.FillId)
+        }
+        seen[fill.FillId] = true
+    }
+}
+//}}} Test FetchFillsSince
+
+
+//{{{ Test pre-trade validation
+func TestFakeSendOrderValidation(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    floatPtr := func(f float64) *float64 { return &f }
+    strPtr := func(s string) *string { return &s }
+
+    tests := []struct {
+        name        string
+        req         types.SendOrderRequest
+    }{
+        {"FailSide",            types.SendOrderRequest{Symbol: "PF_BCHUSD", OrderType: "lmt", Side: "long", Size: 1, LimitPrice: 500}},
+        {"FailStpNoTrigger",    types.SendOrderRequest{Symbol: "PF_BCHUSD", OrderType: "stp", Side: "sell", Size: 1, StopPrice: floatPtr(500)}},
+        // Sell stop above 550 mark would trigger right away
+        {"FailStopWrongSide",   types.SendOrderRequest{Symbol: "PF_BCHUSD", OrderType: "stp", Side: "sell", Size: 1, StopPrice: floatPtr(600), TriggerSignal: strPtr("mark")}},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            _, err := exch.SendOrder(t.Context(), tc.req)
+            var validationErr *types.ValidationError
+            if !errors.As(err, &validationErr) {
+                t.Fatalf("Expected ValidationError, got: %v", err)
+            }
+            _, err = exch.BatchSendOrders(t.Context(), []types.SendOrderRequest{tc.req})
+            if !errors.As(err, &validationErr) {
+                t.Fatalf("Batch: expected ValidationError, got: %v", err)
+            }
+        })
+    }
+    if got := len(srv.OpenOrders()); got != 0 {
+        t.Errorf("Invalid orders reached exchange: %d", got)
+    }
+}
+//}}} Test pre-trade validation
+
+
+//{{{ Test cliOrdId reconciliation
+func TestFakeCliOrdIdReconcile(t *testing.T) {
+    exch, _ := newFakeExchange(t)
+    generator, err := types.NewSequenceGeneratorWithSession("grid", "s1", 0)
+    if err != nil {
+        t.Fatalf("NewSequenceGeneratorWithSession failed: %v", err)
+    }
+    exch.SetCliOrdIdGenerator(generator)
+
+    // Same price twice, used to collide
+    req := types.SendOrderRequest{Symbol: "PF_BCHUSD", OrderType: "post", Side: "buy", Size: 0.1, LimitPrice: 500}
+    for i := 0; i < 2; i++ {
+        sent, err := exch.SendOrder(t.Context(), req)
+        if err != nil {
+            t.Fatalf("SendOrder failed: %v", err)
+        }
+        if sent.SendStatus.Status != "placed" {
+            t.Fatalf("Order %d not placed: %s", i, sent.SendStatus.Status)
+        }
+    }
+
+    openOrders, err := exch.GetOpenOrders(t.Context())
+    if err != nil {
+        t.Fatalf("GetOpenOrders failed: %v", err)
+    }
+    for i, order := range openOrders.OpenOrders {
+        meta, err := order.CliOrdIdMeta()
+        if err != nil {
+            t.Fatalf("CliOrdIdMeta failed: %v", err)
+        }
+        if meta.Tag != "grid" || meta.LimitPrice != 500 || meta.Seq != uint64(i+1) {
+            t.Errorf("Wrong meta: %+v", meta)
+        }
+    }
+}
+//}}} Test cliOrdId reconciliation
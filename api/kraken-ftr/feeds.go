@@ -0,0 +1,525 @@
+package krakenftr
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "sort"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+import (
+    "github.com/gorilla/websocket"
+)
+// WebSocket feeds delivered on typed channels: public (ticker, book, trade,
+// heartbeat) and private (open_orders, fills, open_positions, balances, see
+// feeds_private.go). Run keeps connection alive: reconnects with backoff,
+// subscribes again, and on book sequence gap resubscribes product to get fresh snapshot.
+//  feeds := exch.NewFeedClient()
+//  feeds.Subscribe(krakenftr.FeedBook, "PF_BCHUSD")
+//  go feeds.Run(ctx)
+//  for ev := range feeds.Book { ... }
+
+
+const (
+    LiveWSURL = "wss://futures.kraken.com/ws/v1"
+    DemoWSURL = "wss://demo-futures.kraken.com/ws/v1"
+)
+const (
+    FeedTicker      = "ticker"
+    FeedBook        = "book"        // book_snapshot + book deltas
+    FeedTrade       = "trade"       // trade_snapshot + trades
+    FeedHeartbeat   = "heartbeat"
+)
+const feedBuffer = 256
+// Second Run of FeedClient/DeadManSwitch, first one already closed its channels
+var ErrAlreadyRun = errors.New("already ran, Run is one-shot")
+
+
+//{{{ Errors
+// Delivered on Errors channel, Run keeps going after all of them
+
+// Connection dropped (or could not be made), Run reconnects after backoff
+type FeedDisconnectError struct {
+    Err     error
+}
+func (e *FeedDisconnectError) Error() string {
+    return fmt.Sprintf("feed disconnected: %v", e.Err)
+}
+func (e *FeedDisconnectError) Unwrap() error { return e.Err }
+
+
+// Message(s) lost between Expected and Got, book is resubscribed
+type SeqGapError struct {
+    Feed        string
+    ProductId   string
+    Expected    int64
+    Got         int64
+}
+func (e *SeqGapError) Error() string {
+    return fmt.Sprintf("%s %s: sequence gap, expected %d got %d", e.Feed, e.ProductId, e.Expected, e.Got)
+}
+
+
+// `event: error` or `event: alert` from Kraken, ex.: bad product id
+type FeedError struct {
+    Event   string
+    Message string
+}
+func (e *FeedError) Error() string {
+    return fmt.Sprintf("feed %s: %s", e.Event, e.Message)
+}
+//}}} Errors
+
+
+//{{{ Client
+type FeedClient struct {
+    url             string
+    dialer          *websocket.Dialer
+    reconnect       RetryPolicy     // only BaseDelay/MaxDelay, reconnects forever
+    pingInterval    time.Duration   // read deadline is 3x this
+
+    // Consumer must drain channels of feeds it subscribed to, full channel blocks reading
+    Tickers     chan types.TickerEvent
+    Book        chan types.BookEvent
+    Trades      chan types.TradeEvent
+    Heartbeats  chan types.Heartbeat
+    OpenOrders  chan types.OpenOrdersEvent
+    Fills       chan types.FillsEvent
+    Positions   chan types.PositionsEvent
+    Balances    chan types.BalancesEvent
+    Errors      chan error          // never blocks, dropped when full
+
+    apiKey      string              // empty => public feeds only
+    privateKey  string
+
+    started     atomic.Bool                 // Run is one-shot, see ErrAlreadyRun
+    mu          sync.Mutex
+    subs        map[string]map[string]bool  // feed => product ids ("" for heartbeat)
+    conn        *websocket.Conn
+    writeMu     sync.Mutex
+    bookSeq     map[string]int64            // per connection, set by snapshot
+    tradeSeq    map[string]int64            // survives reconnect, drops replayed trades
+    challenge   string                      // per connection, see feeds_private.go
+    signedChallenge string
+    challengeAsked  bool                    // one request per connection, reply covers all private feeds
+}
+
+
+// wsURL: LiveWSURL, DemoWSURL, ...
+func NewFeedClient(wsURL string) *FeedClient {
+    return &FeedClient{
+        url:            wsURL,
+        dialer:         websocket.DefaultDialer,
+        reconnect:      RetryPolicy{BaseDelay: 500 * time.Millisecond, MaxDelay: 30 * time.Second},
+        pingInterval:   30 * time.Second,
+        Tickers:        make(chan types.TickerEvent, feedBuffer),
+        Book:           make(chan types.BookEvent, feedBuffer),
+        Trades:         make(chan types.TradeEvent, feedBuffer),
+        Heartbeats:     make(chan types.Heartbeat, feedBuffer),
+        OpenOrders:     make(chan types.OpenOrdersEvent, feedBuffer),
+        Fills:          make(chan types.FillsEvent, feedBuffer),
+        Positions:      make(chan types.PositionsEvent, feedBuffer),
+        Balances:       make(chan types.BalancesEvent, feedBuffer),
+        Errors:         make(chan error, feedBuffer),
+        subs:           map[string]map[string]bool{},
+        bookSeq:        map[string]int64{},
+        tradeSeq:       map[string]int64{},
+    }
+}
+
+
+// Same host as REST baseURL, ex.: https://futures.kraken.com => wss://futures.kraken.com/ws/v1
+// Uses Exchange's API keys for private feeds and dials through its
+// http.Client transport (proxy, TLS config), see dialerFor
+func (exch *Exchange) NewFeedClient() *FeedClient {
+    wsURL := exch.baseURL
+    wsURL = strings.Replace(wsURL, "https://", "wss://", 1)
+    wsURL = strings.Replace(wsURL, "http://", "ws://", 1)
+    c := NewFeedClient(wsURL + "/ws/v1")
+    c.dialer = dialerFor(exch.client)
+    c.apiKey = exch.publicKey
+    c.privateKey = exch.privateKey
+    return c
+}
+
+
+// Takes proxy, TLS config, dial func and handshake timeout from client's
+// *http.Transport (nil => http.DefaultTransport); other RoundTripper-s
+// can not be dialed through, those get websocket.DefaultDialer
+func dialerFor(client *http.Client) *websocket.Dialer {
+    dialer := *websocket.DefaultDialer
+    var rt http.RoundTripper = http.DefaultTransport
+    if client != nil && client.Transport != nil {
+        rt = client.Transport
+    }
+    transport, ok := rt.(*http.Transport)
+    if !ok {
+        return &dialer
+    }
+    dialer.Proxy = transport.Proxy
+    dialer.NetDialContext = transport.DialContext
+    if transport.TLSClientConfig != nil {
+        dialer.TLSClientConfig = transport.TLSClientConfig.Clone()
+    }
+    if transport.TLSHandshakeTimeout > 0 {
+        dialer.HandshakeTimeout = transport.TLSHandshakeTimeout
+    }
+    return &dialer
+}
+
+
+// Remembered for reconnects, sent right away when connected
+// heartbeat and private feeds take no product ids
+func (c *FeedClient) Subscribe(feed string, productIds ...string) error {
+    if privateFeeds[feed] && c.apiKey == "" {
+        return ErrNoCredentials
+    }
+    c.mu.Lock()
+    if c.subs[feed] == nil {
+        c.subs[feed] = map[string]bool{}
+    }
+    if len(productIds) == 0 {
+        c.subs[feed][""] = true
+    }
+    for _, id := range productIds {
+        c.subs[feed][id] = true
+    }
+    conn := c.conn
+    signed := c.signedChallenge != ""
+    c.mu.Unlock()
+
+    if conn == nil {
+        return nil
+    }
+    if privateFeeds[feed] && !signed {
+        // Subscribed once challenge is signed
+        return c.requestChallenge(conn)
+    }
+    return c.send(conn, "subscribe", feed, productIds)
+}
+
+
+func (c *FeedClient) Unsubscribe(feed string, productIds ...string) error {
+    c.mu.Lock()
+    if len(productIds) == 0 {
+        delete(c.subs, feed)
+    }
+    for _, id := range productIds {
+        delete(c.subs[feed], id)
+    }
+    conn := c.conn
+    c.mu.Unlock()
+
+    if conn == nil {
+        return nil
+    }
+    return c.send(conn, "unsubscribe", feed, productIds)
+}
+
+
+func (c *FeedClient) send(conn *websocket.Conn, event, feed string, productIds []string) error {
+    msg := map[string]any{"event": event, "feed": feed}
+    if len(productIds) > 0 {
+        msg["product_ids"] = productIds
+    }
+    if privateFeeds[feed] {
+        c.mu.Lock()
+        msg["api_key"] = c.apiKey
+        msg["original_challenge"] = c.challenge
+        msg["signed_challenge"] = c.signedChallenge
+        c.mu.Unlock()
+    }
+    c.writeMu.Lock()
+    defer c.writeMu.Unlock()
+    return conn.WriteJSON(msg)
+}
+
+
+// Blocks until ctx is done, then closes all channels. One-shot, second call
+// returns ErrAlreadyRun
+func (c *FeedClient) Run(ctx context.Context) error {
+    if !c.started.CompareAndSwap(false, true) {
+        return ErrAlreadyRun
+    }
+    defer c.closeChannels()
+    for attempt := 1; ; attempt++ {
+        connected, err := c.runOnce(ctx)
+        if ctx.Err() != nil {
+            return ctx.Err()
+        }
+        if connected {
+            attempt = 1
+        }
+        c.report(&FeedDisconnectError{Err: err})
+        if err := sleepCtx(ctx, c.reconnect.backoff(attempt)); err != nil {
+            return err
+        }
+    }
+}
+
+
+// true when connection was made (backoff starts over)
+func (c *FeedClient) runOnce(ctx context.Context) (bool, error) {
+    conn, _, err := c.dialer.DialContext(ctx, c.url, nil)
+    if err != nil {
+        return false, err
+    }
+    defer conn.Close()
+    stop := context.AfterFunc(ctx, func() { conn.Close() })
+    defer stop()
+
+    c.mu.Lock()
+    c.conn = conn
+    c.bookSeq = map[string]int64{}
+    c.challenge = ""
+    c.signedChallenge = ""
+    c.challengeAsked = false
+    subs := map[string][]string{}
+    needsChallenge := false
+    for feed, ids := range c.subs {
+        if privateFeeds[feed] {
+            needsChallenge = needsChallenge || len(ids) > 0
+            continue
+        }
+        for id := range ids {
+            if id != "" {
+                subs[feed] = append(subs[feed], id)
+            } else if subs[feed] == nil {
+                subs[feed] = []string{}
+            }
+        }
+    }
+    c.mu.Unlock()
+    defer func() {
+        c.mu.Lock()
+        c.conn = nil
+        c.mu.Unlock()
+    }()
+
+    for feed, ids := range subs {
+        sort.Strings(ids)
+        if err := c.send(conn, "subscribe", feed, ids); err != nil {
+            return true, err
+        }
+    }
+    if needsChallenge {
+        if err := c.requestChallenge(conn); err != nil {
+            return true, err
+        }
+    }
+
+    // Keep alive, Kraken drops idle connections
+    deadline := 3 * c.pingInterval
+    conn.SetReadDeadline(time.Now().Add(deadline))
+    conn.SetPongHandler(func(string) error {
+        return conn.SetReadDeadline(time.Now().Add(deadline))
+    })
+    done := make(chan struct{})
+    defer close(done)
+    go func() {
+        ticker := time.NewTicker(c.pingInterval)
+        defer ticker.Stop()
+        for {
+            select {
+            case <-done:
+                return
+            case <-ticker.C:
+                conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.pingInterval))
+            }
+        }
+    }()
+
+    for {
+        _, data, err := conn.ReadMessage()
+        if err != nil {
+            return true, err
+        }
+        conn.SetReadDeadline(time.Now().Add(deadline))
+        c.dispatch(ctx, conn, data)
+    }
+}
+
+
+func (c *FeedClient) closeChannels() {
+    close(c.Tickers)
+    close(c.Book)
+    close(c.Trades)
+    close(c.Heartbeats)
+    close(c.OpenOrders)
+    close(c.Fills)
+    close(c.Positions)
+    close(c.Balances)
+    close(c.Errors)
+}
+
+
+func (c *FeedClient) report(err error) {
+    select {
+    case c.Errors <- err:
+    default:
+    }
+}
+//}}} Client
+
+
+//{{{ Dispatch
+func deliver[T any](ctx context.Context, ch chan T, v T) {
+    select {
+    case ch <- v:
+    case <-ctx.Done():
+    }
+}
+
+
+func (c *FeedClient) dispatch(ctx context.Context, conn *websocket.Conn, data []byte) {
+    var envelope struct {
+        Event       string  `json:"event"`
+        Feed        string  `json:"feed"`
+        ProductId   string  `json:"product_id"`
+        Message     string  `json:"message"`
+    }
+    if err := json.Unmarshal(data, &envelope); err != nil {
+        c.report(&DecodeError{Endpoint: "ws", Err: err})
+        return
+    }
+    switch envelope.Event {
+    case "":
+    case "error", "alert":
+        c.report(&FeedError{Event: envelope.Event, Message: envelope.Message})
+        return
+    case "challenge":
+        c.onChallenge(conn, envelope.Message)
+        return
+    default:
+        // info, subscribed, unsubscribed
+        return
+    }
+
+    var err error
+    switch envelope.Feed {
+    case "ticker":
+        var ev types.TickerEvent
+        if err = json.Unmarshal(data, &ev); err == nil {
+            deliver(ctx, c.Tickers, ev)
+        }
+    case "book_snapshot":
+        var snap types.BookSnapshot
+        if err = json.Unmarshal(data, &snap); err == nil {
+            c.mu.Lock()
+            c.bookSeq[snap.ProductId] = snap.Seq
+            c.mu.Unlock()
+            deliver(ctx, c.Book, types.BookEvent{Snapshot: &snap})
+        }
+    case "book":
+        var delta types.BookDelta
+        if err = json.Unmarshal(data, &delta); err == nil && c.bookInSeq(conn, delta) {
+            deliver(ctx, c.Book, types.BookEvent{Delta: &delta})
+        }
+    case "trade_snapshot":
+        var snap types.TradeSnapshot
+        if err = json.Unmarshal(data, &snap); err == nil {
+            sort.Slice(snap.Trades, func(i, j int) bool { return snap.Trades[i].Seq < snap.Trades[j].Seq })
+            for _, trade := range snap.Trades {
+                if c.tradeIsNew(trade, false) {
+                    deliver(ctx, c.Trades, trade)
+                }
+            }
+        }
+    case "trade":
+        var trade types.TradeEvent
+        if err = json.Unmarshal(data, &trade); err == nil && c.tradeIsNew(trade, true) {
+            deliver(ctx, c.Trades, trade)
+        }
+    case "heartbeat":
+        var hb types.Heartbeat
+        if err = json.Unmarshal(data, &hb); err == nil {
+            deliver(ctx, c.Heartbeats, hb)
+        }
+    default:
+        _, err = c.dispatchPrivate(ctx, envelope.Feed, data)
+    }
+    if err != nil {
+        c.report(&DecodeError{Endpoint: "ws " + envelope.Feed, Err: err})
+    }
+}
+
+
+// Delta must follow snapshot/previous delta, on gap book is resubscribed
+// and deltas are dropped until new snapshot arrives
+func (c *FeedClient) bookInSeq(conn *websocket.Conn, delta types.BookDelta) bool {
+    c.mu.Lock()
+    last, ok := c.bookSeq[delta.ProductId]
+    if !ok {
+        // Waiting for snapshot
+        c.mu.Unlock()
+        return false
+    }
+    if delta.Seq <= last {
+        c.mu.Unlock()
+        return false
+    }
+    if delta.Seq == last+1 {
+        c.bookSeq[delta.ProductId] = delta.Seq
+        c.mu.Unlock()
+        return true
+    }
+    delete(c.bookSeq, delta.ProductId)
+    c.mu.Unlock()
+
+    c.report(&SeqGapError{Feed: FeedBook, ProductId: delta.ProductId, Expected: last + 1, Got: delta.Seq})
+    c.resubscribeBook(conn, delta.ProductId)
+    return false
+}
+
+
+// Asks for fresh book_snapshot, ex.: types.OrderBook returned *BookCorruptError;
+// deltas are dropped until it arrives. No-op while disconnected, reconnect
+// brings snapshot anyway
+func (c *FeedClient) ResyncBook(productId string) {
+    c.mu.Lock()
+    conn := c.conn
+    delete(c.bookSeq, productId)
+    c.mu.Unlock()
+    if conn != nil {
+        c.resubscribeBook(conn, productId)
+    }
+}
+
+
+// New subscription sends fresh snapshot
+func (c *FeedClient) resubscribeBook(conn *websocket.Conn, productId string) {
+    if err := c.send(conn, "unsubscribe", FeedBook, []string{productId}); err != nil {
+        c.report(err)
+    }
+    if err := c.send(conn, "subscribe", FeedBook, []string{productId}); err != nil {
+        c.report(err)
+    }
+}
+
+
+// Drops trades already delivered (snapshot after reconnect), reports gaps
+// in live trades; trades can not be recovered so nothing is resubscribed
+func (c *FeedClient) tradeIsNew(trade types.TradeEvent, live bool) bool {
+    c.mu.Lock()
+    last, ok := c.tradeSeq[trade.ProductId]
+    if ok && trade.Seq <= last {
+        c.mu.Unlock()
+        return false
+    }
+    c.tradeSeq[trade.ProductId] = trade.Seq
+    c.mu.Unlock()
+
+    if live && ok && trade.Seq != last+1 {
+        c.report(&SeqGapError{Feed: FeedTrade, ProductId: trade.ProductId, Expected: last + 1, Got: trade.Seq})
+    }
+    return true
+}
+//}}} Dispatch
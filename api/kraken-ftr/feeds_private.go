@@ -0,0 +1,125 @@
+package krakenftr
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "crypto/sha512"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "sort"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+import (
+    "github.com/gorilla/websocket"
+)
+// Private feeds need signed challenge (per connection):
+//  -> {"event":"challenge","api_key":...}
+//  <- {"event":"challenge","message":"<uuid>"}
+//  -> {"event":"subscribe","feed":"fills","api_key":...,"original_challenge":...,"signed_challenge":...}
+// signed_challenge = base64(HMAC-SHA512(base64dec(privateKey), SHA256(challenge)))
+
+
+const (
+    FeedOpenOrders      = "open_orders"
+    FeedFills           = "fills"
+    FeedOpenPositions   = "open_positions"
+    FeedBalances        = "balances"
+)
+var privateFeeds = map[string]bool{
+    FeedOpenOrders:     true,
+    FeedFills:          true,
+    FeedOpenPositions:  true,
+    FeedBalances:       true,
+}
+
+
+var ErrNoCredentials = errors.New("private feed needs API keys, use Exchange.NewFeedClient")
+
+
+func signChallenge(privateKey, challenge string) (string, error) {
+    digest := sha256.Sum256([]byte(challenge))
+    key, err := base64.StdEncoding.DecodeString(privateKey)
+    if err != nil {
+        return "", err
+    }
+    hmacHash := hmac.New(sha512.New, key)
+    hmacHash.Write(digest[:])
+    return base64.StdEncoding.EncodeToString(hmacHash.Sum(nil)), nil
+}
+
+
+// No-op when already asked on this connection
+func (c *FeedClient) requestChallenge(conn *websocket.Conn) error {
+    c.mu.Lock()
+    asked := c.challengeAsked
+    c.challengeAsked = true
+    c.mu.Unlock()
+    if asked {
+        return nil
+    }
+    c.writeMu.Lock()
+    defer c.writeMu.Unlock()
+    return conn.WriteJSON(map[string]any{"event": "challenge", "api_key": c.apiKey})
+}
+
+
+// Signs challenge and sends every private subscription
+func (c *FeedClient) onChallenge(conn *websocket.Conn, challenge string) {
+    signed, err := signChallenge(c.privateKey, challenge)
+    if err != nil {
+        c.report(&AuthError{Endpoint: "ws challenge", Err: err})
+        return
+    }
+    c.mu.Lock()
+    c.challenge = challenge
+    c.signedChallenge = signed
+    var feeds []string
+    for feed := range c.subs {
+        if privateFeeds[feed] {
+            feeds = append(feeds, feed)
+        }
+    }
+    c.mu.Unlock()
+
+    sort.Strings(feeds)
+    for _, feed := range feeds {
+        if err := c.send(conn, "subscribe", feed, nil); err != nil {
+            c.report(err)
+        }
+    }
+}
+
+
+// Private feed messages, false when feed is not one of them
+func (c *FeedClient) dispatchPrivate(ctx context.Context, feed string, data []byte) (bool, error) {
+    var err error
+    switch feed {
+    case "open_orders_snapshot", "open_orders":
+        var ev types.OpenOrdersEvent
+        if err = json.Unmarshal(data, &ev); err == nil {
+            deliver(ctx, c.OpenOrders, ev)
+        }
+    case "fills_snapshot", "fills":
+        var ev types.FillsEvent
+        if err = json.Unmarshal(data, &ev); err == nil {
+            deliver(ctx, c.Fills, ev)
+        }
+    case "open_positions":
+        var ev types.PositionsEvent
+        if err = json.Unmarshal(data, &ev); err == nil {
+            deliver(ctx, c.Positions, ev)
+        }
+    case "balances_snapshot", "balances":
+        var ev types.BalancesEvent
+        if err = json.Unmarshal(data, &ev); err == nil {
+            deliver(ctx, c.Balances, ev)
+        }
+    default:
+        return false, nil
+    }
+    return true, err
+}
@@ -0,0 +1,157 @@
+package krakenftr
+
+import (
+    "errors"
+    "testing"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+
+
+//{{{ Test challenge
+func TestFeedPrivateNoCredentials(t *testing.T) {
+    feeds := NewFeedClient(DemoWSURL)
+    if err := feeds.Subscribe(FeedFills); !errors.Is(err, ErrNoCredentials) {
+        t.Errorf("Expected ErrNoCredentials, got %v", err)
+    }
+}
+
+
+func TestFeedPrivateWrongKey(t *testing.T) {
+    feeds, _ := newFakeFeeds(t)
+    feeds.privateKey = "c2VjcmV0"
+    feeds.Subscribe(FeedOpenOrders)
+    feedErr := recvErr[*FeedError](t, feeds.Errors)
+    if feedErr.Message != "Invalid challenge" {
+        t.Errorf("Wrong error\nExpected:\tInvalid challenge\nGot:\t\t%s", feedErr.Message)
+    }
+}
+//}}} Test challenge
+
+
+//{{{ Test private feeds
+// Snapshot on subscribe, REST placed/cancelled orders follow on feed
+func TestFeedOpenOrders(t *testing.T) {
+    feeds, srv := newFakeFeeds(t)
+    exch := New(srv.URL, fakePublicKey, fakePrivateKey)
+    cliOrdId := "feed-resting-1"
+    resting := types.SendOrderRequest{OrderType: "lmt", Symbol: "PF_BCHUSD", Side: "buy", Size: 1, LimitPrice: 500, CliOrdId: cliOrdId}
+    if _, err := exch.SendOrder(t.Context(), resting); err != nil {
+        t.Fatalf("SendOrder failed: %v", err)
+    }
+
+    tracker := types.NewOrderTracker()
+    feeds.Subscribe(FeedOpenOrders)
+    snap := recv(t, feeds.OpenOrders)
+    if !snap.IsSnapshot() || len(snap.Orders) != 1 || snap.Orders[0].CliOrdId != cliOrdId || snap.Orders[0].Side() != "buy" {
+        t.Fatalf("Wrong snapshot: %+v", snap)
+    }
+    tracker.Apply(snap)
+
+    second := types.SendOrderRequest{OrderType: "lmt", Symbol: "PF_BCHUSD", Side: "sell", Size: 2, LimitPrice: 600}
+    if _, err := exch.SendOrder(t.Context(), second); err != nil {
+        t.Fatalf("SendOrder failed: %v", err)
+    }
+    placed := recv(t, feeds.OpenOrders)
+    if placed.IsCancel || placed.Order == nil || placed.Order.Qty != 2 || placed.Order.Side() != "sell" {
+        t.Fatalf("Wrong new order event: %+v", placed)
+    }
+    tracker.Apply(placed)
+    if len(tracker.Open()) != 2 {
+        t.Errorf("Wrong number of tracked orders\nExpected:\t2\nGot:\t\t%d", len(tracker.Open()))
+    }
+
+    if _, err := exch.BatchCancelOrders(t.Context(), []string{placed.Order.OrderId}); err != nil {
+        t.Fatalf("BatchCancelOrders failed: %v", err)
+    }
+    cancelled := recv(t, feeds.OpenOrders)
+    if !cancelled.IsCancel || cancelled.OrderId != placed.Order.OrderId || cancelled.Reason != "cancelled_by_user" {
+        t.Fatalf("Wrong cancel event: %+v", cancelled)
+    }
+    tracker.Apply(cancelled)
+    if _, ok := tracker.ByCliOrdId(cliOrdId); !ok || len(tracker.Open()) != 1 {
+        t.Errorf("Wrong tracked orders after cancel: %+v", tracker.Open())
+    }
+}
+
+
+// Resting order filled by market move: fill, position and full_fill cancel
+func TestFeedFillsPositions(t *testing.T) {
+    feeds, srv := newFakeFeeds(t)
+    exch := New(srv.URL, fakePublicKey, fakePrivateKey)
+    feeds.Subscribe(FeedOpenOrders)
+    feeds.Subscribe(FeedFills)
+    feeds.Subscribe(FeedOpenPositions)
+    if snap := recv(t, feeds.Fills); !snap.IsSnapshot() || len(snap.Fills) != 0 {
+        t.Fatalf("Wrong fills snapshot: %+v", snap)
+    }
+    if snap := recv(t, feeds.Positions); len(snap.Positions) != 0 {
+        t.Fatalf("Wrong positions snapshot: %+v", snap)
+    }
+    recv(t, feeds.OpenOrders)
+
+    cliOrdId := "feed-fill-1"
+    order := types.SendOrderRequest{OrderType: "lmt", Symbol: "PF_BCHUSD", Side: "buy", Size: 3, LimitPrice: 540, CliOrdId: cliOrdId}
+    if _, err := exch.SendOrder(t.Context(), order); err != nil {
+        t.Fatalf("SendOrder failed: %v", err)
+    }
+    recv(t, feeds.OpenOrders)
+    srv.SetTicker(types.Ticker{Symbol: "PF_BCHUSD", MarkPrice: 539})
+
+    fills := recv(t, feeds.Fills)
+    if fills.IsSnapshot() || len(fills.Fills) != 1 {
+        t.Fatalf("Wrong fills event: %+v", fills)
+    }
+    fill := fills.Fills[0].ToFill()
+    if fill.Side != "buy" || fill.Size != 3 || fill.Price != 540 || fill.FillType != "maker" || fill.CliOrdId == nil || *fill.CliOrdId != cliOrdId {
+        t.Errorf("Wrong fill: %+v", fill)
+    }
+    positions := recv(t, feeds.Positions)
+    if len(positions.Positions) != 1 || positions.Positions[0].Balance != 3 || positions.Positions[0].EntryPrice != 540 {
+        t.Errorf("Wrong positions: %+v", positions)
+    }
+    if gone := recv(t, feeds.OpenOrders); !gone.IsCancel || gone.Reason != "full_fill" || gone.CliOrdId != cliOrdId {
+        t.Errorf("Wrong open_orders event after fill: %+v", gone)
+    }
+}
+
+
+func TestFeedBalances(t *testing.T) {
+    feeds, srv := newFakeFeeds(t)
+    feeds.Subscribe(FeedBalances)
+    if snap := recv(t, feeds.Balances); snap.Feed != "balances_snapshot" {
+        t.Fatalf("Wrong balances snapshot: %+v", snap)
+    }
+    srv.SetBalances(types.FlexFuturesBalance{
+        Currencies:         map[string]types.FlexCurrency{"USD": {Quantity: 1000, Value: 1000, CollateralValue: 1000}},
+        AvailableMargin:    900,
+    })
+    balances := recv(t, feeds.Balances)
+    if balances.FlexFutures.AvailableMargin != 900 || balances.FlexFutures.Currencies["USD"].Quantity != 1000 {
+        t.Errorf("Wrong balances: %+v", balances)
+    }
+}
+
+
+// Private subscriptions survive reconnect, new challenge is signed
+func TestFeedPrivateReconnect(t *testing.T) {
+    feeds, srv := newFakeFeeds(t)
+    exch := New(srv.URL, fakePublicKey, fakePrivateKey)
+    feeds.Subscribe(FeedOpenOrders)
+    recv(t, feeds.OpenOrders)
+
+    srv.DropFeedConnections()
+    recvErr[*FeedDisconnectError](t, feeds.Errors)
+    if snap := recv(t, feeds.OpenOrders); !snap.IsSnapshot() {
+        t.Fatalf("Expected snapshot after reconnect, got: %+v", snap)
+    }
+    order := types.SendOrderRequest{OrderType: "lmt", Symbol: "PF_BCHUSD", Side: "buy", Size: 1, LimitPrice: 500}
+    if _, err := exch.SendOrder(t.Context(), order); err != nil {
+        t.Fatalf("SendOrder failed: %v", err)
+    }
+    if ev := recv(t, feeds.OpenOrders); ev.Order == nil || ev.Order.LimitPrice != 500 {
+        t.Errorf("Wrong order event after reconnect: %+v", ev)
+    }
+}
+//}}} Test private feeds
@@ -0,0 +1,299 @@
+package krakenftr
+
+import (
+    "context"
+    "crypto/tls"
+    "errors"
+    "net/http"
+    "net/url"
+    "testing"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/api/kraken-ftr/krakenfake"
+)
+import (
+    "github.com/gorilla/websocket"
+)
+
+
+//{{{ Helpers
+// Feed client against fake, running until test ends
+func newFakeFeeds(t *testing.T) (*FeedClient, *krakenfake.Server) {
+    t.Helper()
+    exch, srv := newFakeExchange(t)
+    feeds := exch.NewFeedClient()
+    feeds.reconnect = RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan struct{})
+    go func() {
+        feeds.Run(ctx)
+        close(done)
+    }()
+    t.Cleanup(func() {
+        cancel()
+        <-done
+    })
+    return feeds, srv
+}
+
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+    t.Helper()
+    deadline := time.Now().Add(2 * time.Second)
+    for !cond() {
+        if time.Now().After(deadline) {
+            t.Fatalf("Timed out waiting for %s", what)
+        }
+        time.Sleep(5 * time.Millisecond)
+    }
+}
+
+
+func recv[T any](t *testing.T, ch <-chan T) T {
+    t.Helper()
+    select {
+    case v := <-ch:
+        return v
+    case <-time.After(2 * time.Second):
+        var zero T
+        t.Fatalf("Timed out waiting for %T", zero)
+        return zero
+    }
+}
+
+
+// First error of type E, others are skipped
+func recvErr[E error](t *testing.T, ch <-chan error) E {
+    t.Helper()
+    timeout := time.After(2 * time.Second)
+    for {
+        select {
+        case err := <-ch:
+            var target E
+            if errors.As(err, &target) {
+                return target
+            }
+        case <-timeout:
+            var zero E
+            t.Fatalf("Timed out waiting for %T", zero)
+            return zero
+        }
+    }
+}
+//}}} Helpers
+
+
+//{{{ Test feeds
+func TestFeedTickerHeartbeat(t *testing.T) {
+    feeds, srv := newFakeFeeds(t)
+    feeds.Subscribe(FeedTicker, "PF_BCHUSD")
+    feeds.Subscribe(FeedHeartbeat)
+    waitFor(t, "subscriptions", func() bool {
+        return srv.Subscribers("ticker", "PF_BCHUSD") == 1 && srv.Subscribers("heartbeat", "") == 1
+    })
+
+    srv.PublishTicker(types.TickerEvent{ProductId: "PF_BCHUSD", Bid: 549.5, Ask: 550.5, MarkPrice: 550})
+    ticker := recv(t, feeds.Tickers)
+    if ticker.ProductId != "PF_BCHUSD" || ticker.Bid != 549.5 || ticker.Ask != 550.5 || ticker.MarkPrice != 550 {
+        t.Errorf("Wrong ticker: %+v", ticker)
+    }
+    srv.PublishHeartbeat()
+    if hb := recv(t, feeds.Heartbeats); hb.Time == 0 {
+        t.Errorf("Heartbeat without time: %+v", hb)
+    }
+}
+
+
+func TestFeedBookGapResync(t *testing.T) {
+    feeds, srv := newFakeFeeds(t)
+    srv.SetBook("PF_BCHUSD", 10,
+        []types.BookLevel{{Price: 549, Qty: 2}, {Price: 548, Qty: 5}},
+        []types.BookLevel{{Price: 551, Qty: 1}})
+    feeds.Subscribe(FeedBook, "PF_BCHUSD")
+
+    snap := recv(t, feeds.Book).Snapshot
+    if snap == nil || snap.Seq != 10 || len(snap.Bids) != 2 || snap.Bids[0].Price != 549 {
+        t.Fatalf("Wrong snapshot: %+v", snap)
+    }
+    srv.PublishBookDelta(types.BookDelta{ProductId: "PF_BCHUSD", Side: "buy", Price: 549.5, Qty: 3})
+    delta := recv(t, feeds.Book).Delta
+    if delta == nil || delta.Seq != 11 || delta.Price != 549.5 {
+        t.Fatalf("Wrong delta: %+v", delta)
+    }
+
+    // 12..14 lost => gap reported, fresh snapshot with seq 15
+    srv.PublishBookDelta(types.BookDelta{ProductId: "PF_BCHUSD", Side: "sell", Price: 551, Qty: 0, Seq: 15})
+    gap := recvErr[*SeqGapError](t, feeds.Errors)
+    if gap.Expected != 12 || gap.Got != 15 {
+        t.Errorf("Wrong gap: %+v", gap)
+    }
+    resync := recv(t, feeds.Book).Snapshot
+    if resync == nil || resync.Seq != 15 || len(resync.Asks) != 0 || len(resync.Bids) != 3 {
+        t.Fatalf("Wrong resync snapshot: %+v", resync)
+    }
+    srv.PublishBookDelta(types.BookDelta{ProductId: "PF_BCHUSD", Side: "sell", Price: 552, Qty: 1})
+    if delta := recv(t, feeds.Book).Delta; delta == nil || delta.Seq != 16 {
+        t.Fatalf("Wrong delta after resync: %+v", delta)
+    }
+}
+
+
+// Local book fed from feed, corrupt book (crossed) is resynced from fresh snapshot
+func TestFeedOrderBookResync(t *testing.T) {
+    feeds, srv := newFakeFeeds(t)
+    srv.SetBook("PF_BCHUSD", 1,
+        []types.BookLevel{{Price: 549, Qty: 2}},
+        []types.BookLevel{{Price: 551, Qty: 1}})
+    book := types.NewOrderBook("PF_BCHUSD")
+    feeds.Subscribe(FeedBook, "PF_BCHUSD")
+    if err := book.Apply(recv(t, feeds.Book)); err != nil || !book.Synced() {
+        t.Fatalf("Snapshot not applied: %v", err)
+    }
+
+    // Bid through best ask without ask being removed first
+    srv.PublishBookDelta(types.BookDelta{ProductId: "PF_BCHUSD", Side: "buy", Price: 552, Qty: 1})
+    var corrupt *types.BookCorruptError
+    if err := book.Apply(recv(t, feeds.Book)); !errors.As(err, &corrupt) {
+        t.Fatalf("Expected *types.BookCorruptError, got %v", err)
+    }
+    srv.PublishBookDelta(types.BookDelta{ProductId: "PF_BCHUSD", Side: "sell", Price: 551, Qty: 0})
+    feeds.ResyncBook("PF_BCHUSD")
+    for !book.Synced() {
+        err := book.Apply(recv(t, feeds.Book))
+        if err != nil && !errors.Is(err, types.ErrBookNotSynced) {
+            t.Fatalf("Resync failed: %v", err)
+        }
+    }
+    bid, _ := book.BestBid()
+    if _, ok := book.BestAsk(); bid.Price != 552 || ok || book.Seq() != 3 {
+        t.Errorf("Wrong book after resync: bid %+v, seq %d", bid, book.Seq())
+    }
+}
+
+
+// Dropped connection: reconnect, subscribe again, trades already seen are not repeated
+func TestFeedReconnect(t *testing.T) {
+    feeds, srv := newFakeFeeds(t)
+    feeds.Subscribe(FeedTrade, "PF_BCHUSD")
+    waitFor(t, "subscription", func() bool { return srv.Subscribers("trade", "PF_BCHUSD") == 1 })
+    srv.PublishTrade(types.TradeEvent{ProductId: "PF_BCHUSD", Side: "buy", Type: "fill", Qty: 1, Price: 550})
+    srv.PublishTrade(types.TradeEvent{ProductId: "PF_BCHUSD", Side: "sell", Type: "fill", Qty: 2, Price: 549})
+    for _, expectSeq := range []int64{1, 2} {
+        if trade := recv(t, feeds.Trades); trade.Seq != expectSeq {
+            t.Fatalf("Wrong trade seq\nExpected:\t%d\nGot:\t\t%d", expectSeq, trade.Seq)
+        }
+    }
+
+    srv.DropFeedConnections()
+    recvErr[*FeedDisconnectError](t, feeds.Errors)
+    waitFor(t, "resubscription", func() bool { return srv.Subscribers("trade", "PF_BCHUSD") == 1 })
+
+    srv.PublishTrade(types.TradeEvent{ProductId: "PF_BCHUSD", Side: "buy", Type: "fill", Qty: 3, Price: 551})
+    if trade := recv(t, feeds.Trades); trade.Seq != 3 || trade.Qty != 3 {
+        t.Fatalf("Expected only new trade after reconnect, got: %+v", trade)
+    }
+}
+
+
+func TestFeedErrorEvent(t *testing.T) {
+    feeds, _ := newFakeFeeds(t)
+    feeds.Subscribe("nope", "PF_BCHUSD")
+    feedErr := recvErr[*FeedError](t, feeds.Errors)
+    if feedErr.Event != "error" {
+        t.Errorf("Wrong event: %+v", feedErr)
+    }
+}
+
+
+func TestFeedRunStops(t *testing.T) {
+    _, srv := newFakeExchange(t)
+    feeds := NewFeedClient("ws" + srv.URL[len("http"):] + "/ws/v1")
+    ctx, cancel := context.WithCancel(t.Context())
+    done := make(chan error)
+    go func() { done <- feeds.Run(ctx) }()
+    feeds.Subscribe(FeedTicker, "PF_BCHUSD")
+    waitFor(t, "subscription", func() bool { return srv.Subscribers("ticker", "PF_BCHUSD") == 1 })
+
+    cancel()
+    if err := <-done; !errors.Is(err, context.Canceled) {
+        t.Errorf("Expected context.Canceled, got %v", err)
+    }
+    if _, ok := <-feeds.Tickers; ok {
+        t.Errorf("Channels not closed after Run returned")
+    }
+    // Channels are closed already, second Run must not close them again
+    if err := feeds.Run(t.Context()); !errors.Is(err, ErrAlreadyRun) {
+        t.Errorf("Expected ErrAlreadyRun, got %v", err)
+    }
+}
+
+
+// Feed dials through Exchange's http.Client transport, not DefaultDialer
+func TestFeedDialerFromHTTPClient(t *testing.T) {
+    req, err := http.NewRequest("GET", "https://futures.kraken.com/ws/v1", nil)
+    if err != nil {
+        t.Fatalf("NewRequest failed: %v", err)
+    }
+    proxyOf := func(proxy func(*http.Request) (*url.URL, error)) string {
+        if proxy == nil {
+            return ""
+        }
+        u, err := proxy(req)
+        if err != nil || u == nil {
+            return ""
+        }
+        return u.String()
+    }
+    envProxy := proxyOf(http.ProxyFromEnvironment)
+    proxyURL, _ := url.Parse("http://proxy.example:3128")
+    custom := &http.Transport{
+        Proxy:              http.ProxyURL(proxyURL),
+        TLSClientConfig:    &tls.Config{ServerName: "feeds.example"},
+        TLSHandshakeTimeout: 3 * time.Second,
+    }
+
+    tests := []struct {
+        name                string
+        client              *http.Client
+        expectProxy         string
+        expectServerName    string
+        expectHandshake     time.Duration
+    }{
+        {"SuccNilClient",           nil,                                            envProxy,   "",             10 * time.Second},
+        {"SuccDefaultTransport",    &http.Client{},                                 envProxy,   "",             10 * time.Second},
+        {"SuccCustomTransport",     &http.Client{Transport: custom},                "http://proxy.example:3128", "feeds.example", 3 * time.Second},
+        {"SuccOtherRoundTripper",   &http.Client{Transport: roundTripFunc(nil)},    envProxy,   "",             websocket.DefaultDialer.HandshakeTimeout},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            dialer := dialerFor(tc.client)
+            if proxy := proxyOf(dialer.Proxy); proxy != tc.expectProxy {
+                t.Errorf("Wrong proxy\nExpected:\t%s\nGot:\t\t%s", tc.expectProxy, proxy)
+            }
+            serverName := ""
+            if dialer.TLSClientConfig != nil {
+                serverName = dialer.TLSClientConfig.ServerName
+            }
+            if serverName != tc.expectServerName {
+                t.Errorf("Wrong TLS server name\nExpected:\t%s\nGot:\t\t%s", tc.expectServerName, serverName)
+            }
+            if dialer.HandshakeTimeout != tc.expectHandshake {
+                t.Errorf("Wrong handshake timeout\nExpected:\t%v\nGot:\t\t%v", tc.expectHandshake, dialer.HandshakeTimeout)
+            }
+        })
+    }
+    // Copy, not shared with transport
+    if dialerFor(&http.Client{Transport: custom}).TLSClientConfig == custom.TLSClientConfig {
+        t.Errorf("TLS config shared with transport")
+    }
+}
+
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+    return f(r)
+}
+//}}} Test feeds
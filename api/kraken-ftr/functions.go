@@ -1,18 +1,25 @@
 package krakenftr
 
 import (
+    "context"
     "crypto/sha256"
     "crypto/sha512"
     "crypto/hmac"
     "strings"
     "time"
     "fmt"
+    "errors"
     "encoding/json"
     "encoding/base64"
     "net/http"
+    "net/url"
     "io"
+    "sort"
+    "strconv"
+    "sync/atomic"
 )
 import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/api"
     "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
 )
 import (
@@ -24,16 +31,119 @@ const (
     Reset = "\033[0m"
     Orange = "\033[38;5;208m"
 )
+const (
+    LiveURL = "https://futures.kraken.com"
+    DemoURL = "https://demo-futures.kraken.com"
+)
 type Exchange struct {
     baseURL     string
     publicKey   string
     privateKey  string
+    registry    atomic.Pointer[Registry]    // optional, see instruments.go
+    cliOrdIds   types.CliOrdIdGenerator     // fills empty cliOrdId
+    nonces      NonceSource
+    limiter     *RateLimiter                // nil => no client side limit
+    retry       RetryPolicy                 // see retry.go
+    sleep       func(context.Context, time.Duration) error  // backoff, swapped in tests
+    now         func() time.Time                            // swapped in tests
+    client      *http.Client
+}
+var _ api.Exchange = (*Exchange)(nil)
+
+
+// Optional settings for New, ex.: New(DemoURL, pub, priv, WithNonceSource(fileNonce))
+type Option func(*Exchange)
+
+
+// Share one source between Exchange-s that use same API key
+func WithNonceSource(nonces NonceSource) Option {
+    return func(exch *Exchange) {
+        exch.nonces = nonces
+    }
+}
+
+
+// Replace default (DefaultBudgets, blocking) limiter, nil disables it.
+// Share one limiter between Exchange-s that use same API key
+func WithRateLimiter(limiter *RateLimiter) Option {
+    return func(exch *Exchange) {
+        exch.limiter = limiter
+    }
+}
+
+
+// Timeouts, keep-alive, proxy, custom transport (tests), ... Share one client
+// between Exchange-s so they share connection pool
+func WithHTTPClient(client *http.Client) Option {
+    return func(exch *Exchange) {
+        exch.client = client
+    }
+}
+
+
+// Used when WithHTTPClient is not given, hung connection gives up after Timeout
+func DefaultHTTPClient() *http.Client {
+    transport := http.DefaultTransport.(*http.Transport).Clone()
+    transport.MaxIdleConnsPerHost = 16
+    transport.IdleConnTimeout = 90 * time.Second
+    return &http.Client{Timeout: 30 * time.Second, Transport: transport}
+}
+
+
+// baseURL: LiveURL, DemoURL or anything that speaks same API (ex.: krakenfake)
+// privateKey: base64 as given by Kraken
+func New(baseURL, publicKey, privateKey string, opts ...Option) *Exchange {
+    exch := &Exchange{
+        baseURL:    strings.TrimSuffix(baseURL, "/"),
+        publicKey:  publicKey,
+        privateKey: privateKey,
+        cliOrdIds:  types.DefaultCliOrdIdGenerator,
+        nonces:     NewMonotonicNonce(),
+        limiter:    NewRateLimiter(DefaultBudgets, LimitBlock),
+        retry:      DefaultRetryPolicy,
+        sleep:      sleepCtx,
+        now:        time.Now,
+    }
+    for _, opt := range opts {
+        opt(exch)
+    }
+    if exch.client == nil {
+        exch.client = DefaultHTTPClient()
+    }
+    return exch
+}
+
+
+// Strategy for orders sent without cliOrdId, set before Exchange is shared between goroutines
+func (exch *Exchange) SetCliOrdIdGenerator(generator types.CliOrdIdGenerator) {
+    exch.cliOrdIds = generator
+}
+
+
+// Budget left in pool, -1 when not limited
+func (exch *Exchange) RateLimitRemaining(pool Pool) float64 {
+    if exch.limiter == nil {
+        return -1
+    }
+    return exch.limiter.Remaining(pool)
+}
+
+
+// Blocks (or fails) until limiter lets request through
+func (exch *Exchange) waitLimit(ctx context.Context, endpoint, query, body string) error {
+    if exch.limiter == nil {
+        return nil
+    }
+    pool, cost := requestCost(endpoint, query, body)
+    return exch.limiter.Wait(ctx, endpoint, pool, cost)
 }
 
 
 // URL = baseURL + endpoint + (optional) pathParams + (optional) query
 //{{{ DRY
 func makeRequest(
+    ctx context.Context,
+    client *http.Client,
     method string,
     url string,
     body io.Reader,     // nil for GET, real body for POST
@@ -43,7 +153,7 @@ func makeRequest(
 ) (*http.Response, error) {
     fmt.Printf("\n%s %s: %s %s\n", Orange, method, url, Reset)
     // Create request
-    req, err := http.NewRequest(method, url, body)
+    req, err := http.NewRequestWithContext(ctx, method, url, body)
     if err != nil {
         return nil, fmt.Errorf("Failed to create request: %w", err)
     }
@@ -53,9 +163,113 @@ func makeRequest(
     req.Header.Add("Authent", signature)
     req.Header.Add("Nonce", nonce)
 
-    client := &http.Client{}
     return client.Do(req)
 }
+
+
+// Signs, sends and decodes into `out`, every failure comes back as one of errors.go types
+// endpoint is what gets signed, pathParams are only appended to URL
+// GET is retried on transient failure (fresh nonce each time), POST is sent once
+// Cancelled ctx comes back as TransportError (or plain ctx.Err() while waiting), errors.Is(err, context.Canceled) works
+func (exch *Exchange) doSigned(
+    ctx context.Context,
+    method string,
+    endpoint string,    // ex.: "/derivatives/api/v3/openpositions"
+    pathParams string,  // ex.: "/PF_BCHUSD"
+    query string,       // without `?`, signed for GET
+    body string,        // url encoded, signed for POST
+    out any,
+) error {
+    if method != "GET" {
+        return exch.doSignedOnce(ctx, method, endpoint, pathParams, query, body, out)
+    }
+    return exch.withRetry(ctx, func() error {
+        return exch.doSignedOnce(ctx, method, endpoint, pathParams, query, body, out)
+    })
+}
+
+
+func (exch *Exchange) doSignedOnce(ctx context.Context, method, endpoint, pathParams, query, body string, out any) error {
+    // Wait before taking nonce, otherwise requests sent meanwhile would overtake it and get it rejected
+    if err := exch.waitLimit(ctx, endpoint, query, body); err != nil {
+        return err
+    }
+    nonceValue, err := exch.nonces.NextNonce()
+    if err != nil {
+        return &NonceError{Endpoint: endpoint, Code: "localNonceSource", Err: err}
+    }
+    nonce := strconv.FormatInt(nonceValue, 10)
+    url := exch.baseURL + endpoint + pathParams
+    if query != "" {
+        url += "?" + query
+    }
+
+    // Query is considerd data for signature but withoug `?`
+    signature, err := exch.signRequestFn(endpoint, query+body, nonce)
+    if err != nil {
+        return &AuthError{Endpoint: endpoint, Err: err}
+    }
+
+    var bodyReader io.Reader
+    if method == "POST" {
+        // io.Reader for HTTP POST
+        bodyReader = strings.NewReader(body)
+    }
+    resp, err := makeRequest(ctx, exch.client, method, url, bodyReader, exch.publicKey, signature, nonce)
+    if err != nil {
+        return &TransportError{Method: method, URL: url, Err: err}
+    }
+    defer resp.Body.Close()
+
+    return decodeResponse(endpoint, resp, out)
+}
+
+
+// Unsigned GET (charts, ...), retried on transient failure
+func (exch *Exchange) doPublic(ctx context.Context, endpoint, query string, out any) error {
+    return exch.withRetry(ctx, func() error {
+        return exch.doPublicOnce(ctx, endpoint, query, out)
+    })
+}
+
+
+func (exch *Exchange) doPublicOnce(ctx context.Context, endpoint, query string, out any) error {
+    if err := exch.waitLimit(ctx, endpoint, query, ""); err != nil {
+        return err
+    }
+    url := exch.baseURL + endpoint
+    if query != "" {
+        url += "?" + query
+    }
+    fmt.Printf("\n%s GET: %s %s\n", Orange, url, Reset)
+    req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+    if err != nil {
+        return &TransportError{Method: "GET", URL: url, Err: err}
+    }
+    req.Header.Add("Accept", "application/json")
+    resp, err := exch.client.Do(req)
+    if err != nil {
+        return &TransportError{Method: "GET", URL: url, Err: err}
+    }
+    defer resp.Body.Close()
+
+    return decodeResponse(endpoint, resp, out)
+}
+
+
+func decodeResponse(endpoint string, resp *http.Response, out any) error {
+    bodyBytes, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return &TransportError{Method: resp.Request.Method, URL: resp.Request.URL.String(), Err: err}
+    }
+    if err := checkResponse(endpoint, resp.StatusCode, bodyBytes); err != nil {
+        return err
+    }
+    if err := json.Unmarshal(bodyBytes, out); err != nil {
+        return &DecodeError{Endpoint: endpoint, Err: err}
+    }
+    return nil
+}
 //}}} DRY
 
 
@@ -92,64 +306,131 @@ func sincePeriod(days int) int64 {
 }
 
 
+// Last sinceDays as one gap-free series (all pages), sinceDays 0 => single
+// page of Kraken's default range, more_candles left as Kraken sent it
 func (exch *Exchange) GetOHLC(
+    ctx context.Context,
     tickType string,    // "spot", "mark", "trade"
     symbol string,      // "PF_BCHUSD", ...
     resolution string,  // "1m", "5m", "15m", "30m", "1h", "4h", "12h", "1d", "1w"
     sinceDays int,      // 128, 32, ...
     ) (*types.CandleResponseWithMeta, error) {
-    pathParams := fmt.Sprintf("/%s/%s/%s", tickType, symbol, resolution)
-    url := exch.baseURL + "/api/charts/v1" + pathParams
-    query := ""
-    if sinceDays > 0 {
-        // Convert last X days to unix timestamp
-        since := sincePeriod(sinceDays)
-        query += fmt.Sprintf("?from=%d", since)
+    if err := errors.Join(types.ValidateTickType(tickType), types.ValidateResolution(resolution)); err != nil {
+        return nil, err
+    }
+    meta := types.CandleMeta{
+        TickType:   tickType,
+        Symbol:     symbol,
+        Resolution: resolution,
+        SinceDays:  sinceDays,
     }
 
-    fmt.Printf("\n%s GET: %s %s\n", Orange, (url+query), Reset)
-    resp, err := http.Get(url+query)
+    if sinceDays <= 0 {
+        result, err := exch.getCandlePage(ctx, tickType, symbol, resolution, "")
+        if err != nil {
+            return nil, err
+        }
+        return &types.CandleResponseWithMeta{Meta: meta, Response: *result}, nil
+    }
+
+    candles, err := exch.GetOHLCRange(ctx, types.CandleQuery{
+        TickType:   tickType,
+        Symbol:     symbol,
+        Resolution: resolution,
+        From:       time.Unix(sincePeriod(sinceDays), 0),
+    })
     if err != nil {
         return nil, err
     }
-    defer resp.Body.Close()
+    return &types.CandleResponseWithMeta{Meta: meta, Response: types.CandleResponse{Candles: candles}}, nil
+}
+
 
+func (exch *Exchange) getCandlePage(ctx context.Context, tickType, symbol, resolution, query string) (*types.CandleResponse, error) {
+    pathParams := fmt.Sprintf("/%s/%s/%s", tickType, symbol, resolution)
     var result types.CandleResponse
-    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+    if err := exch.doPublic(ctx, "/api/charts/v1"+pathParams, query, &result); err != nil {
         return nil, err
     }
+    return &result, nil
+}
 
-    candleResponseWithMeta := types.CandleResponseWithMeta{
-        Meta: types.CandleMeta{
-            TickType:   tickType,
-            Symbol:     symbol,
-            Resolution: resolution,
-            SinceDays:  sinceDays,
-        },
-        Response: result,
-    }
 
-    return &candleResponseWithMeta, nil
+// Candles with open time in [From, To], gaps filled (see types.FillCandleGaps)
+func (exch *Exchange) GetOHLCRange(ctx context.Context, q types.CandleQuery) ([]types.Candle, error) {
+    candles, err := exch.FetchCandles(ctx, q)
+    if err != nil {
+        return nil, err
+    }
+    return types.FillCandleGaps(candles, types.CandleResolutions[q.Resolution]), nil
 }
-//}}} FetchOHLC
 
 
-//{{{ Get open positions
-func (exch *Exchange) GetOpenPositions() (*types.OpenPositionResponse, error) {
-    nonce := fmt.Sprintf("%d", time.Now().UnixMilli()) // ms timestamp
-    endpoint :=  "/derivatives/api/v3/openpositions"
-    url := exch.baseURL + endpoint
-
-    signature, err := exch.signRequestFn(endpoint, "", nonce)
+// Candles with open time in [From, To] as Kraken has them, follows
+// more_candles until range is covered; oldest first, no duplicates,
+// periods without candle stay missing (what gets stored, see jobs)
+func (exch *Exchange) FetchCandles(ctx context.Context, q types.CandleQuery) ([]types.Candle, error) {
+    if err := q.Validate(); err != nil {
+        return nil, err
+    }
+    to := q.To
+    if to.IsZero() {
+        to = time.Now()
+    }
+    step := types.CandleResolutions[q.Resolution]
+    fromMs, toMs := q.From.UnixMilli(), to.UnixMilli()
+
+    candles := []types.Candle{}
+    from := q.From.Unix()
+    for from <= to.Unix() {
+        page, err := exch.getCandlePage(ctx, q.TickType, q.Symbol, q.Resolution, fmt.Sprintf("from=%d&to=%d", from, to.Unix()))
+        if err != nil {
+            return nil, err
+        }
+        candles = append(candles, page.Candles...)
+        if !page.MoreCandles || len(page.Candles) == 0 {
+            break
+        }
+        // Next page starts one candle after newest one, stuck cursor ends paging
+        newest := page.Candles[0].Time
+        for _, c := range page.Candles {
+            newest = max(newest, c.Time)
+        }
+        next := (newest + step.Milliseconds()) / 1000
+        if next <= from {
+            break
+        }
+        from = next
+    }
 
-    resp, err := makeRequest("GET", url, nil, exch.publicKey, signature, nonce)
-    if err != nil {
-        panic(err)
+    inRange := candles[:0]
+    for _, c := range candles {
+        if c.Time >= fromMs && c.Time <= toMs {
+            inRange = append(inRange, c)
+        }
     }
-    defer resp.Body.Close()
+    // Zero step => sorted and deduplicated, nothing filled
+    return types.FillCandleGaps(inRange, 0), nil
+}
+//}}} Get OHLC
+
 
+//{{{ Get open positions
+func (exch *Exchange) GetOpenPositions(ctx context.Context) (*types.OpenPositionResponse, error) {
     var result types.OpenPositionResponse
-    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+    if err := exch.doSigned(ctx, "GET", "/derivatives/api/v3/openpositions", "", "", "", &result); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
+//}}}
+
+
+//{{{ Get accounts
+// Cash, single-collateral margin and flex (multi-collateral) accounts
+func (exch *Exchange) GetAccounts(ctx context.Context) (*types.AccountsResponse, error) {
+    var result types.AccountsResponse
+    if err := exch.doSigned(ctx, "GET", "/derivatives/api/v3/accounts", "", "", "", &result); err != nil {
         return nil, err
     }
     return &result, nil
@@ -158,21 +439,27 @@ func (exch *Exchange) GetOpenPositions() (*types.OpenPositionResponse, error) {
 
 
 //{{{ Get active/open orders
-func (exch *Exchange) GetOpenOrders() (*types.OpenOrdersResponse, error){
-    nonce := fmt.Sprintf("%d", time.Now().UnixMilli()) // ms timestamp
-    endpoint :=  "/derivatives/api/v3/openorders"
-    url := exch.baseURL + endpoint
+func (exch *Exchange) GetOpenOrders(ctx context.Context) (*types.OpenOrdersResponse, error){
+    var result types.OpenOrdersResponse
+    if err := exch.doSigned(ctx, "GET", "/derivatives/api/v3/openorders", "", "", "", &result); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
 
-    signature, err := exch.signRequestFn(endpoint, "", nonce)
 
-    resp, err := makeRequest("GET", url, nil, exch.publicKey, signature, nonce)
-    if err != nil {
-        panic(err)
+// Open orders and orders closed in last few seconds, by order id and/or cliOrdId
+func (exch *Exchange) GetOrderStatus(ctx context.Context, orderIds, cliOrdIds []string) (*types.OrderStatusResponse, error) {
+    v := url.Values{}
+    for _, id := range orderIds {
+        v.Add("orderIds", id)
+    }
+    for _, id := range cliOrdIds {
+        v.Add("cliOrdIds", id)
     }
-    defer resp.Body.Close()
 
-    var result types.OpenOrdersResponse
-    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+    var result types.OrderStatusResponse
+    if err := exch.doSigned(ctx, "POST", "/derivatives/api/v3/orders/status", "", "", v.Encode(), &result); err != nil {
         return nil, err
     }
     return &result, nil
@@ -180,57 +467,94 @@ func (exch *Exchange) GetOpenOrders() (*types.OpenOrdersResponse, error){
 //}}} Get active/open orders
 
 
-//{{{ Get order fills aka own histroy WIP/AKA NOT DONE/AKA NEED MORE WORK
+//{{{ Get order fills aka own histroy
+// Kraken returns at most 100 fills per call, newest first
+const fillsPageSize = 100
+// Kraken style timestamps, ex.: "2025-09-23T16:55:10.557Z"
+const timeLayout = "2006-01-02T15:04:05.000Z"
+
+
+// Single page of fills
 func (exch *Exchange) GetOrderFills(
-    lastFillTime int,   //timestamp in unix ms time, cursor for pagination, not filter
+    ctx context.Context,
+    lastFillTime time.Time, // cursor for pagination, not filter; zero = latest 100 fills
     ) (*types.FillsResponse, error) {
-    nonce := fmt.Sprintf("%d", time.Now().UnixMilli()) // ms timestamp
-    endpoint :=  "/derivatives/api/v3/fills"
-    url := exch.baseURL + endpoint
     query := ""
-    if lastFillTime > 0 {
-        // Convert last X days to unix miliseconds
-        query += fmt.Sprintf("lastFillTime=%d", lastFillTime) // well we don't know if its sec or ms and can't test it until there is 100+ orders
-    }
-
-    // Query is considerd data for signature but withoug `?`
-    signature, err := exch.signRequestFn(endpoint, query, nonce)
-
-    resp, err := makeRequest("GET", url+"?"+query, nil, exch.publicKey, signature, nonce)
-    if err != nil {
-        panic(err)
+    if !lastFillTime.IsZero() {
+        // ISO 8601 (not unix sec/ms), returns fills before that time
+        query += "lastFillTime=" + url.QueryEscape(lastFillTime.UTC().Format(timeLayout))
     }
-    defer resp.Body.Close()
 
     var result types.FillsResponse
-    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+    if err := exch.doSigned(ctx, "GET", "/derivatives/api/v3/fills", "", query, "", &result); err != nil {
         return nil, err
     }
     return &result, nil
 }
-// TODO: GetOrderFills recursive up until given timestamp/date
-// Get order fills as some abstract FN that gets all from last X days
 
-//}}} Get order fills aka own histroy
 
+// All fills at or after `since`, follows lastFillTime cursor page by page,
+// de-duplicated by fill_id and returned oldest first
+func (exch *Exchange) FetchFillsSince(ctx context.Context, since time.Time) ([]types.Fill, error) {
+    endpoint := "/derivatives/api/v3/fills"
+    seen := map[string]bool{}
+    fills := []types.Fill{}
+    fillTimes := map[string]time.Time{}
+    cursor := time.Time{}
 
-//{{{ Get ticker
-func (exch *Exchange) GetTicker(symbol string) (*types.TickerResponse, error) {
-    nonce := fmt.Sprintf("%d", time.Now().UnixMilli()) // ms timestamp
-    endpoint :=  "/derivatives/api/v3/tickers"
-    pathParams := fmt.Sprintf("/%s", symbol)
-    url := exch.baseURL + endpoint + pathParams
+    for {
+        page, err := exch.GetOrderFills(ctx, cursor)
+        if err != nil {
+            return nil, err
+        }
 
-    signature, err := exch.signRequestFn(endpoint, "", nonce)
+        added := 0
+        reachedSince := false
+        oldest := cursor
+        for _, fill := range page.Fills {
+            fillTime, err := time.Parse(time.RFC3339Nano, fill.FillTime)
+            if err != nil {
+                return nil, &DecodeError{Endpoint: endpoint, Err: fmt.Errorf("fillTime of %s: %w", fill.FillId, err)}
+            }
+            if oldest.IsZero() || fillTime.Before(oldest) {
+                oldest = fillTime
+            }
+            if fillTime.Before(since) {
+                reachedSince = true
+                continue
+            }
+            if seen[fill.FillId] {
+                continue
+            }
+            seen[fill.FillId] = true
+            fillTimes[fill.FillId] = fillTime
+            fills = append(fills, fill)
+            added++
+        }
 
-    resp, err := makeRequest("GET", url, nil, exch.publicKey, signature, nonce)
-    if err != nil {
-        panic(err)
+        // Last page, went past `since` or page had nothing new (cursor stuck)
+        if reachedSince || len(page.Fills) < fillsPageSize || added == 0 {
+            break
+        }
+        // lastFillTime is exclusive, +1ms asks for oldest millisecond again so fills
+        // sharing it across page boundary are not lost; already seen ones are skipped
+        cursor = oldest.Add(time.Millisecond)
     }
-    defer resp.Body.Close()
 
+    sort.SliceStable(fills, func(i, j int) bool {
+        return fillTimes[fills[i].FillId].Before(fillTimes[fills[j].FillId])
+    })
+    return fills, nil
+}
+
+//}}} Get order fills aka own histroy
+
+
+//{{{ Get ticker
+// Public, no signature needed; see market.go for all tickers at once
+func (exch *Exchange) GetTicker(ctx context.Context, symbol string) (*types.TickerResponse, error) {
     var result types.TickerResponse
-    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+    if err := exch.doPublic(ctx, "/derivatives/api/v3/tickers/"+url.PathEscape(symbol), "", &result); err != nil {
         return nil, err
     }
     return &result, nil
@@ -238,38 +562,80 @@ func (exch *Exchange) GetTicker(symbol string) (*types.TickerResponse, error) {
 //}}} Get ticker
 
 
+//{{{ Pre-trade checks
+// Validate => round to contract spec (if registry loaded) => cliOrdId if missing => stop side vs mark price
+// Works on copy, callers slice is left as is
+func (exch *Exchange) prepareOrders(ctx context.Context, orderReqList []types.SendOrderRequest) ([]types.SendOrderRequest, error) {
+    prepared := make([]types.SendOrderRequest, len(orderReqList))
+    registry := exch.Registry()
+    for i, order := range orderReqList {
+        if err := order.Validate(); err != nil {
+            return nil, fmt.Errorf("order %d (%s): %w", i, order.CliOrdId, err)
+        }
+        if registry != nil {
+            if err := registry.Normalize(&order); err != nil {
+                return nil, fmt.Errorf("order %d (%s): %w", i, order.CliOrdId, err)
+            }
+        }
+        // Every order gets cliOrdId so it can be found again (retries, reconciliation)
+        if order.CliOrdId == "" && exch.cliOrdIds != nil {
+            order.CliOrdId = exch.cliOrdIds.NextCliOrdId(order)
+            // Validate ran before id existed
+            if err := types.ValidateCliOrdId(order.CliOrdId); err != nil {
+                return nil, fmt.Errorf("order %d (generated %s): %w", i, order.CliOrdId, err)
+            }
+        }
+        prepared[i] = order
+    }
+
+    // Only trigger orders need market price, one ticker call per symbol;
+    // stop is compared against price its triggerSignal follows
+    tickers := map[string]types.Ticker{}
+    for i, order := range prepared {
+        if !types.IsTriggerOrder(order.OrderType) {
+            continue
+        }
+        ticker, ok := tickers[order.Symbol]
+        if !ok {
+            result, err := exch.GetTicker(ctx, order.Symbol)
+            if err != nil {
+                return nil, fmt.Errorf("order %d (%s): market price for stop check: %w", i, order.CliOrdId, err)
+            }
+            ticker = result.Ticker
+            tickers[order.Symbol] = ticker
+        }
+        signal := "mark"
+        if order.TriggerSignal != nil {
+            signal = *order.TriggerSignal
+        }
+        if err := order.ValidateStopSide(ticker.SignalPrice(signal)); err != nil {
+            return nil, fmt.Errorf("order %d (%s): %w", i, order.CliOrdId, err)
+        }
+    }
+    return prepared, nil
+}
+//}}} Pre-trade checks
+
+
 //{{{ Send order
 // body data is expected to be url encoded
 // ex.: "symbol=PF_BCHUSD&orderType=post&side=buy&size=0.1&limitPrice=550&cliOrdId=test123"
 // cliOrdId must be unique it saves it server side each order has it
-func (exch *Exchange) SendOrder(orderReq types.SendOrderRequest) (*types.SendOrderResponse, error) {
-    nonce := fmt.Sprintf("%d", time.Now().UnixMilli()) // ms timestamp
-    endpoint :=  "/derivatives/api/v3/sendorder"
-    url := exch.baseURL + endpoint
-
-    // Encode the order struct as URL-encoded form data (dynamic, works with optional fields)
-    v, err := query.Values(orderReq)    // uses `url:xxx`
+func (exch *Exchange) SendOrder(ctx context.Context, orderReq types.SendOrderRequest) (*types.SendOrderResponse, error) {
+    prepared, err := exch.prepareOrders(ctx, []types.SendOrderRequest{orderReq})
     if err != nil {
         return nil, err
     }
-    // string for signing
-    bodyString := v.Encode()
-    // io.Reader for HTTP POST
-    bodyReader := strings.NewReader(bodyString)
-
-    signature, err := exch.signRequestFn(endpoint, bodyString, nonce)
+    orderReq = prepared[0]
 
-    resp, err := makeRequest("POST", url, bodyReader, exch.publicKey, signature, nonce)
+    // Encode the order struct as URL-encoded form data (dynamic, works with optional fields)
+    v, err := query.Values(orderReq)    // uses `url:xxx`
     if err != nil {
-        panic(err)
-    }
-    defer resp.Body.Close()
-
-    var result types.SendOrderResponse
-    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
         return nil, err
     }
-    return &result, nil
+
+    // Safe to retry, cliOrdId tells whether failed attempt actually landed
+    return exch.sendOrderIdempotent(ctx, orderReq, v.Encode())
 }
 //}}} Send order
 
@@ -277,95 +643,155 @@ func (exch *Exchange) SendOrder(orderReq types.SendOrderRequest) (*types.SendOrd
 //{{{ Batch send order(s)
 // "json":"batchOrder:{ {order1},{order2}, ... }, => encoded as json string (then later url encoded)
 //{{{ helper fn
-func structToMapBSO(order types.SendOrderRequest, i int) map[string]any {
+func structToMapBSO(order types.SendOrderRequest, i int) (map[string]any, error) {
     // convert struct to JSON
-    data, _ := json.Marshal(order)
+    data, err := json.Marshal(order)
+    if err != nil {
+        return nil, err
+    }
     // convert JSON into map
     var m map[string]any
-    json.Unmarshal(data, &m)
+    if err := json.Unmarshal(data, &m); err != nil {
+        return nil, err
+    }
     // add extra fields
     m["order"] = "send"
-    m["order_tag"] = fmt.Sprintf("%d", i)
+    m["order_tag"] = strconv.Itoa(i)
     // return
-    return m
+    return m, nil
+}
+
+
+// BatchOrderResponse in input order, as if it was one call
+func batchResponse(serverTime string, results []types.BatchResult) *types.BatchOrderResponse {
+    resp := &types.BatchOrderResponse{Result: "success", ServerTime: serverTime}
+    for _, r := range results {
+        resp.BatchStatus = append(resp.BatchStatus, types.BatchStatus{Status: r.Status, OrderId: r.OrderId, OrderTag: r.OrderTag, CliOrdId: r.CliOrdId, Err: r.Err})
+    }
+    return resp
 }
 //}}} helperr fn
 
 
-func (exch *Exchange) BatchSendOrders(orderReqList []types.SendOrderRequest) (*types.BatchOrderResponse, error) {
-    nonce := fmt.Sprintf("%d", time.Now().UnixMilli()) // ms timestamp
-    endpoint :=  "/derivatives/api/v3/batchorder"
-    url := exch.baseURL + endpoint
+// Never retried, on transient failure check GetOpenOrders before sending again
+// More than MaxBatchSize orders are split, see ExecuteBatch for mixed batches;
+// when a later call fails response still comes back with error: earlier
+// chunks are live, orders of failed and unsent chunks have Err set
+func (exch *Exchange) BatchSendOrders(ctx context.Context, orderReqList []types.SendOrderRequest) (*types.BatchOrderResponse, error) {
+    orderReqList, err := exch.prepareOrders(ctx, orderReqList)
+    if err != nil {
+        return nil, err
+    }
 
-    var batchOrder []map[string]any
+    instructions := make([]map[string]any, len(orderReqList))
+    results := make([]types.BatchResult, len(orderReqList))
+    pending := make([]int, len(orderReqList))
     for i, order := range orderReqList {
-        orderMap := structToMapBSO(order, i)
-        batchOrder = append(batchOrder, orderMap)
+        if instructions[i], err = structToMapBSO(order, i); err != nil {
+            return nil, fmt.Errorf("order %d (%s): %w", i, order.CliOrdId, err)
+        }
+        results[i] = types.BatchResult{Index: i, Order: "send", OrderTag: strconv.Itoa(i), CliOrdId: order.CliOrdId}
+        pending[i] = i
     }
 
-    batchOrderJSON := map[string]any {
-        "batchOrder": batchOrder,
-        }
-    data, _ := json.Marshal(batchOrderJSON)
+    serverTime, err := exch.runBatch(ctx, pending, instructions, results)
+    return batchResponse(serverTime, results), err
+}
+//}}} Batch send order(s)
+
+
+//{{{ Batch cancel order(s)
+// Split and partial on error same as BatchSendOrders
+func (exch *Exchange) BatchCancelOrders(ctx context.Context, orderIDs []string) (*types.BatchOrderResponse, error) {
+    instructions := make([]map[string]any, len(orderIDs))
+    results := make([]types.BatchResult, len(orderIDs))
+    pending := make([]int, len(orderIDs))
+    for i, orderID := range orderIDs {
+        instructions[i] = cancelInstruction(orderID, "")
+        results[i] = types.BatchResult{Index: i, Order: "cancel", OrderTag: strconv.Itoa(i), OrderId: orderID}
+        pending[i] = i
+    }
+
+    serverTime, err := exch.runBatch(ctx, pending, instructions, results)
+    return batchResponse(serverTime, results), err
+}
+
 
-    bodyString := fmt.Sprintf("json=%s", data)
-    bodyReader := strings.NewReader(bodyString)
+//}}} Batch cancel order(s)
 
-    signature, err := exch.signRequestFn(endpoint, bodyString, nonce)
 
-    resp, err := makeRequest("POST", url, bodyReader, exch.publicKey, signature, nonce)
+//{{{ Edit order
+// Price/size/stop of resting order, by orderId or cliOrdId; editStatus.status
+// other than `edited` is not an error (same as sendStatus)
+func (exch *Exchange) EditOrder(ctx context.Context, editReq types.EditOrderRequest) (*types.EditOrderResponse, error) {
+    if err := editReq.Validate(); err != nil {
+        return nil, err
+    }
+    v, err := query.Values(editReq)
     if err != nil {
-        panic(err)
+        return nil, err
     }
-    defer resp.Body.Close()
 
-    var result types.BatchOrderResponse
-    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+    var result types.EditOrderResponse
+    if err := exch.doSigned(ctx, "POST", "/derivatives/api/v3/editorder", "", "", v.Encode(), &result); err != nil {
         return nil, err
     }
     return &result, nil
 }
-//}}} Batch send order(s)
+//}}} Edit order
 
 
-//{{{ Batch cancel order(s)
-func (exch *Exchange) BatchCancelOrders(orderIDs []string) (*types.BatchOrderResponse, error) {
-    nonce := fmt.Sprintf("%d", time.Now().UnixMilli()) // ms timestamp
-    endpoint :=  "/derivatives/api/v3/batchorder"
-    url := exch.baseURL + endpoint
-
-    var batchOrder []map[string]string
-    for _, orderID := range(orderIDs) {
-        tmp := map[string]string{}
-        tmp["order"] = "cancel"
-        tmp["order_id"] = orderID
-        batchOrder = append(batchOrder, tmp)
+//{{{ Cancel order(s)
+// One of orderId/cliOrdId, other one empty
+func (exch *Exchange) CancelOrder(ctx context.Context, orderId, cliOrdId string) (*types.CancelOrderResponse, error) {
+    if (orderId == "") == (cliOrdId == "") {
+        return nil, &types.ValidationError{Field: "orderId", Reason: "exactly one of orderId, cliOrdId required"}
+    }
+    v := url.Values{}
+    if orderId != "" {
+        v.Set("order_id", orderId)
+    } else {
+        v.Set("cliOrdId", cliOrdId)
     }
 
-    batchOrderJSON := map[string]any {
-        "batchOrder": batchOrder,
-        }
-    data, _ := json.Marshal(batchOrderJSON)
-
-    bodyString := fmt.Sprintf("json=%s", data)
-    bodyReader := strings.NewReader(bodyString)
+    var result types.CancelOrderResponse
+    if err := exch.doSigned(ctx, "POST", "/derivatives/api/v3/cancelorder", "", "", v.Encode(), &result); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
 
-    signature, err := exch.signRequestFn(endpoint, bodyString, nonce)
 
-    resp, err := makeRequest("POST", url, bodyReader, exch.publicKey, signature, nonce)
-    if err != nil {
-        panic(err)
+// Every open order, only `symbol` ones when not empty
+func (exch *Exchange) CancelAllOrders(ctx context.Context, symbol string) (*types.CancelAllOrdersResponse, error) {
+    v := url.Values{}
+    if symbol != "" {
+        v.Set("symbol", symbol)
     }
-    defer resp.Body.Close()
 
-    var result types.BatchOrderResponse
-    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+    var result types.CancelAllOrdersResponse
+    if err := exch.doSigned(ctx, "POST", "/derivatives/api/v3/cancelallorders", "", "", v.Encode(), &result); err != nil {
         return nil, err
     }
     return &result, nil
 }
 
 
-//}}} Batch cancel order(s)
+// Dead man switch: all orders get cancelled `timeout` from now unless called
+// again before, 0 disarms. Whole seconds, see DeadManSwitch for heartbeat
+func (exch *Exchange) CancelAllOrdersAfter(ctx context.Context, timeout time.Duration) (*types.CancelAllOrdersAfterResponse, error) {
+    if timeout < 0 || (timeout > 0 && timeout < time.Second) {
+        return nil, &types.ValidationError{Field: "timeout", Reason: fmt.Sprintf("0 (disarm) or at least 1s, got %v", timeout)}
+    }
+    v := url.Values{}
+    v.Set("timeout", strconv.FormatInt(int64(timeout.Round(time.Second)/time.Second), 10))
+
+    var result types.CancelAllOrdersAfterResponse
+    if err := exch.doSigned(ctx, "POST", "/derivatives/api/v3/cancelallordersafter", "", "", v.Encode(), &result); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
+//}}} Cancel order(s)
 
 
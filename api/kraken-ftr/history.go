@@ -0,0 +1,211 @@
+package krakenftr
+
+import (
+    "context"
+    "net/url"
+    "strconv"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+// History API (own pool, see limiter.go): order, trigger and execution
+// history page by continuationToken, account log pages by entry id.
+// Fetch* follow every page oldest first, FetchAccountEvents merges all of
+// them into one chronological stream.
+
+
+// Page sizes used by Fetch*
+const (
+    historyPageSize     = 500
+    accountLogPageSize  = 500
+)
+
+
+//{{{ Orders, triggers, executions
+func historyQueryValues(q types.HistoryQuery, continuationToken string) string {
+    v := url.Values{}
+    if !q.Since.IsZero() {
+        v.Set("since", strconv.FormatInt(q.Since.UnixMilli(), 10))
+    }
+    if !q.Before.IsZero() {
+        v.Set("before", strconv.FormatInt(q.Before.UnixMilli(), 10))
+    }
+    if q.Sort != "" {
+        v.Set("sort", q.Sort)
+    }
+    if q.Count > 0 {
+        v.Set("count", strconv.Itoa(q.Count))
+    }
+    if q.Tradeable != "" {
+        v.Set("tradeable", q.Tradeable)
+    }
+    if continuationToken != "" {
+        v.Set("continuation_token", continuationToken)
+    }
+    return v.Encode()
+}
+
+
+func (exch *Exchange) getHistory(ctx context.Context, kind string, q types.HistoryQuery, continuationToken string) (*types.HistoryResponse, error) {
+    var result types.HistoryResponse
+    if err := exch.doSigned(ctx, "GET", "/api/history/v2/"+kind, "", historyQueryValues(q, continuationToken), "", &result); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
+
+
+// One page, continuationToken from previous page ("" for first)
+func (exch *Exchange) GetOrderHistory(ctx context.Context, q types.HistoryQuery, continuationToken string) (*types.HistoryResponse, error) {
+    return exch.getHistory(ctx, "orders", q, continuationToken)
+}
+
+
+func (exch *Exchange) GetTriggerHistory(ctx context.Context, q types.HistoryQuery, continuationToken string) (*types.HistoryResponse, error) {
+    return exch.getHistory(ctx, "triggers", q, continuationToken)
+}
+
+
+func (exch *Exchange) GetExecutionHistory(ctx context.Context, q types.HistoryQuery, continuationToken string) (*types.HistoryResponse, error) {
+    return exch.getHistory(ctx, "executions", q, continuationToken)
+}
+
+
+// Every page in [since, before), oldest first; zero times are open ends
+func (exch *Exchange) fetchHistory(ctx context.Context, kind string, since, before time.Time) ([]types.HistoryElement, error) {
+    q := types.HistoryQuery{Since: since, Before: before, Sort: "asc", Count: historyPageSize}
+    elements := []types.HistoryElement{}
+    token := ""
+    for {
+        page, err := exch.getHistory(ctx, kind, q, token)
+        if err != nil {
+            return nil, err
+        }
+        elements = append(elements, page.Elements...)
+        // Same token twice would loop forever
+        if page.ContinuationToken == "" || page.ContinuationToken == token || len(page.Elements) == 0 {
+            return elements, nil
+        }
+        token = page.ContinuationToken
+    }
+}
+
+
+func (exch *Exchange) FetchOrderHistory(ctx context.Context, since, before time.Time) ([]types.HistoryElement, error) {
+    return exch.fetchHistory(ctx, "orders", since, before)
+}
+
+
+func (exch *Exchange) FetchTriggerHistory(ctx context.Context, since, before time.Time) ([]types.HistoryElement, error) {
+    return exch.fetchHistory(ctx, "triggers", since, before)
+}
+
+
+func (exch *Exchange) FetchExecutionHistory(ctx context.Context, since, before time.Time) ([]types.HistoryElement, error) {
+    return exch.fetchHistory(ctx, "executions", since, before)
+}
+//}}} Orders, triggers, executions
+
+
+//{{{ Account log
+func accountLogQueryValues(q types.AccountLogQuery) string {
+    v := url.Values{}
+    if !q.Since.IsZero() {
+        v.Set("since", strconv.FormatInt(q.Since.UnixMilli(), 10))
+    }
+    if !q.Before.IsZero() {
+        v.Set("before", strconv.FormatInt(q.Before.UnixMilli(), 10))
+    }
+    if q.From > 0 {
+        v.Set("from", strconv.FormatInt(q.From, 10))
+    }
+    if q.To > 0 {
+        v.Set("to", strconv.FormatInt(q.To, 10))
+    }
+    if q.Sort != "" {
+        v.Set("sort", q.Sort)
+    }
+    if q.Count > 0 {
+        v.Set("count", strconv.Itoa(q.Count))
+    }
+    for _, info := range q.Info {
+        v.Add("info", info)
+    }
+    return v.Encode()
+}
+
+
+// One page, Kraken default is newest first
+func (exch *Exchange) GetAccountLog(ctx context.Context, q types.AccountLogQuery) (*types.AccountLogResponse, error) {
+    var result types.AccountLogResponse
+    if err := exch.doSigned(ctx, "GET", "/api/history/v3/account-log", "", accountLogQueryValues(q), "", &result); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
+
+
+// Every entry in [since, before], oldest first; info filters kinds (none => all)
+func (exch *Exchange) FetchAccountLog(ctx context.Context, since, before time.Time, info ...string) ([]types.AccountLogEntry, error) {
+    q := types.AccountLogQuery{Since: since, Before: before, Sort: "asc", Count: accountLogPageSize, Info: info}
+    logs := []types.AccountLogEntry{}
+    for {
+        page, err := exch.GetAccountLog(ctx, q)
+        if err != nil {
+            return nil, err
+        }
+        logs = append(logs, page.Logs...)
+        if len(page.Logs) < accountLogPageSize {
+            return logs, nil
+        }
+        // Ids only grow, next page starts after last one
+        last := page.Logs[len(page.Logs)-1].Id
+        if last < q.From {
+            return logs, nil
+        }
+        q.From = last + 1
+    }
+}
+//}}} Account log
+
+
+//{{{ Unified stream
+// Orders, triggers, executions and account log in [since, before), oldest
+// first; same timestamp keeps that order (order event before its execution
+// before its balance change)
+func (exch *Exchange) FetchAccountEvents(ctx context.Context, since, before time.Time) ([]types.AccountEvent, error) {
+    streams := [][]types.AccountEvent{}
+    for _, h := range []struct{ kind, source string }{{"orders", "order"}, {"triggers", "trigger"}, {"executions", "execution"}} {
+        elements, err := exch.fetchHistory(ctx, h.kind, since, before)
+        if err != nil {
+            return nil, err
+        }
+        events := make([]types.AccountEvent, len(elements))
+        for i, el := range elements {
+            events[i] = el.AccountEvent(h.source)
+        }
+        streams = append(streams, events)
+    }
+
+    // Account log `before` is inclusive, trim to match history
+    logBefore := before
+    if !before.IsZero() {
+        logBefore = before.Add(-time.Millisecond)
+    }
+    logs, err := exch.FetchAccountLog(ctx, since, logBefore)
+    if err != nil {
+        return nil, err
+    }
+    events := make([]types.AccountEvent, 0, len(logs))
+    for _, entry := range logs {
+        ev, err := entry.AccountEvent()
+        if err != nil {
+            return nil, &DecodeError{Endpoint: "/api/history/v3/account-log", Err: err}
+        }
+        events = append(events, ev)
+    }
+    streams = append(streams, events)
+    return types.MergeAccountEvents(streams...), nil
+}
+//}}} Unified stream
@@ -0,0 +1,185 @@
+package krakenftr
+
+import (
+    "testing"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+
+
+//{{{ Test order history
+// Lifecycle lands in order history, continuationToken walks every page
+func TestOrderHistoryPages(t *testing.T) {
+    floatPtr := func(f float64) *float64 { return &f }
+    exch, srv := newFakeExchange(t)
+    for i := range 3 {
+        if _, err := exch.SendOrder(t.Context(), types.SendOrderRequest{OrderType: "post", Symbol: "PF_BCHUSD", Side: "buy", Size: 1, LimitPrice: 500 + float64(i), CliOrdId: "hist-" + string(rune('a'+i))}); err != nil {
+            t.Fatalf("SendOrder failed: %v", err)
+        }
+    }
+    if _, err := exch.EditOrder(t.Context(), types.EditOrderRequest{CliOrdId: "hist-a", LimitPrice: floatPtr(495)}); err != nil {
+        t.Fatalf("EditOrder failed: %v", err)
+    }
+    if _, err := exch.CancelOrder(t.Context(), "", "hist-b"); err != nil {
+        t.Fatalf("CancelOrder failed: %v", err)
+    }
+
+    var got []types.HistoryElement
+    token, pages := "", 0
+    for {
+        page, err := exch.GetOrderHistory(t.Context(), types.HistoryQuery{Sort: "asc", Count: 2}, token)
+        if err != nil {
+            t.Fatalf("GetOrderHistory failed: %v", err)
+        }
+        got = append(got, page.Elements...)
+        pages++
+        if page.ContinuationToken == "" {
+            break
+        }
+        token = page.ContinuationToken
+    }
+    if pages != 3 {
+        t.Errorf("Wrong number of pages\nExpected:\t3\nGot:\t\t%d", pages)
+    }
+
+    expected := []struct {
+        eventType   string
+        clientId    string
+        limitPrice  float64
+        reason      string
+    }{
+        {"OrderPlaced",     "hist-a",   500,    "new_user_order"},
+        {"OrderPlaced",     "hist-b",   501,    "new_user_order"},
+        {"OrderPlaced",     "hist-c",   502,    "new_user_order"},
+        {"OrderUpdated",    "hist-a",   495,    "edited_by_user"},
+        {"OrderCancelled",  "hist-b",   501,    "cancelled_by_user"},
+    }
+    if len(got) != len(expected) {
+        t.Fatalf("Wrong number of elements\nExpected:\t%d\nGot:\t\t%d", len(expected), len(got))
+    }
+    // Iterate
+    for i, exp := range expected {
+        ev := got[i].Event
+        if ev.Type != exp.eventType || ev.Order == nil || ev.Order.ClientId != exp.clientId || ev.Order.LimitPrice != exp.limitPrice || ev.Reason != exp.reason {
+            t.Errorf("Wrong element %d\nExpected:\t%+v\nGot:\t\t%+v %+v", i, exp, ev, ev.Order)
+        }
+    }
+    if old := got[3].Event.OldOrder; old == nil || old.LimitPrice != 500 {
+        t.Errorf("Wrong old order for OrderUpdated: %+v", old)
+    }
+
+    all, err := exch.FetchOrderHistory(t.Context(), time.Time{}, time.Time{})
+    if err != nil {
+        t.Fatalf("FetchOrderHistory failed: %v", err)
+    }
+    if len(all) != len(expected) || all[0].Uid != got[0].Uid {
+        t.Errorf("FetchOrderHistory differs from paged result: %d elements", len(all))
+    }
+    if triggers := srv.History("triggers"); len(triggers) != 0 {
+        t.Errorf("Plain orders recorded as triggers: %+v", triggers)
+    }
+}
+//}}} Test order history
+
+
+//{{{ Test account log
+// More entries than one page, info filter is passed through
+func TestFetchAccountLog(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+    n := accountLogPageSize*2 + 10
+    entries := make([]types.AccountLogEntry, n)
+    for i := range entries {
+        info := "funding rate change"
+        if i%10 == 0 {
+            info = "transfer"
+        }
+        entries[i] = types.AccountLogEntry{Date: start.Add(time.Duration(i) * time.Minute).Format(time.RFC3339), Asset: "usd", Info: info}
+    }
+    srv.AddAccountLog(entries...)
+
+    tests := []struct {
+        name        string
+        since       time.Time
+        info        []string
+        expected    int
+    }{
+        {"All",         time.Time{},                    nil,                    n},
+        {"Since",       start.Add(10 * time.Minute),    nil,                    n - 10},
+        {"Transfers",   time.Time{},                    []string{"transfer"},   n / 10},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            logs, err := exch.FetchAccountLog(t.Context(), tc.since, time.Time{}, tc.info...)
+            if err != nil {
+                t.Fatalf("FetchAccountLog failed: %v", err)
+            }
+            if len(logs) != tc.expected {
+                t.Fatalf("Wrong number of entries\nExpected:\t%d\nGot:\t\t%d", tc.expected, len(logs))
+            }
+            for i := 1; i < len(logs); i++ {
+                if logs[i].Id <= logs[i-1].Id {
+                    t.Fatalf("Entries not oldest first at %d: %d after %d", i, logs[i].Id, logs[i-1].Id)
+                }
+            }
+        })
+    }
+}
+//}}} Test account log
+
+
+//{{{ Test account events
+// Orders, executions and account log merged by time
+func TestFetchAccountEvents(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    t0 := time.Date(2025, 9, 23, 12, 0, 0, 0, time.UTC)
+    clock := t0
+    srv.SetClock(func() time.Time { return clock })
+    srv.AddAccountLog(types.AccountLogEntry{Date: t0.Add(-time.Hour).Format(time.RFC3339), Asset: "usd", Info: "transfer", NewBalance: 1000})
+
+    if _, err := exch.SendOrder(t.Context(), types.SendOrderRequest{OrderType: "post", Symbol: "PF_BCHUSD", Side: "buy", Size: 1, LimitPrice: 500}); err != nil {
+        t.Fatalf("SendOrder failed: %v", err)
+    }
+    clock = t0.Add(time.Minute)
+    if _, err := exch.SendOrder(t.Context(), types.SendOrderRequest{OrderType: "mkt", Symbol: "PF_BCHUSD", Side: "sell", Size: 2}); err != nil {
+        t.Fatalf("SendOrder failed: %v", err)
+    }
+    contract, rate := "PF_BCHUSD", 0.0001
+    srv.AddAccountLog(types.AccountLogEntry{Date: t0.Add(2 * time.Minute).Format(time.RFC3339), Asset: "usd", Info: "funding rate change", Contract: &contract, FundingRate: &rate})
+
+    events, err := exch.FetchAccountEvents(t.Context(), t0.Add(-2*time.Hour), t0.Add(time.Hour))
+    if err != nil {
+        t.Fatalf("FetchAccountEvents failed: %v", err)
+    }
+    expected := []struct {
+        source      string
+        eventType   string
+        symbol      string
+    }{
+        {"account_log", "transfer",             ""},
+        {"order",       "OrderPlaced",          "PF_BCHUSD"},
+        {"execution",   "Execution",            "PF_BCHUSD"},
+        {"account_log", "futures trade",        "PF_BCHUSD"},
+        {"account_log", "funding rate change",  "PF_BCHUSD"},
+    }
+    if len(events) != len(expected) {
+        t.Fatalf("Wrong number of events\nExpected:\t%d\nGot:\t\t%d\n%+v", len(expected), len(events), events)
+    }
+    // Iterate
+    for i, exp := range expected {
+        ev := events[i]
+        if ev.Source != exp.source || ev.Type != exp.eventType || ev.Symbol != exp.symbol {
+            t.Errorf("Wrong event %d\nExpected:\t%+v\nGot:\t\t%s %s %s", i, exp, ev.Source, ev.Type, ev.Symbol)
+        }
+    }
+    if exec := events[2].Execution; exec == nil || exec.Order() == nil || exec.Order().Direction != "Sell" || exec.Quantity != 2 {
+        t.Errorf("Wrong execution: %+v", exec)
+    }
+    if log := events[3].Log; log == nil || log.TradePrice == nil || *log.TradePrice != 550 {
+        t.Errorf("Wrong futures trade entry: %+v", log)
+    }
+}
+//}}} Test account events
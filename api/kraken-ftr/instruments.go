@@ -0,0 +1,245 @@
+package krakenftr
+
+import (
+    "context"
+    _ "embed"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+// Contract specs by symbol, used to validate/round orders before they are sent.
+// Loaded from /instruments or from JSON snapshot when offline.
+
+
+// Offline fallback, same format as /derivatives/api/v3/instruments response
+//go:embed instruments.json
+var instrumentsSnapshot []byte
+
+
+var (
+    ErrUnknownInstrument    = errors.New("unknown instrument")
+    ErrNotTradeable         = errors.New("instrument not tradeable")
+    ErrSizeTooSmall         = errors.New("size below minimum order size")
+    ErrNotMultiCollateral   = errors.New("instrument is not multi-collateral (flexible_futures)")
+    ErrLeverageTooHigh      = errors.New("leverage above instrument maximum")
+)
+
+
+//{{{ Get instruments
+// Public endpoint, no signature
+func (exch *Exchange) GetInstruments(ctx context.Context) (*types.InstrumentsResponse, error) {
+    var result types.InstrumentsResponse
+    if err := exch.doPublic(ctx, "/derivatives/api/v3/instruments", "", &result); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
+
+
+// Fetches instruments and makes Exchange use them for SendOrder/BatchSendOrders
+func (exch *Exchange) LoadInstruments(ctx context.Context) (*Registry, error) {
+    result, err := exch.GetInstruments(ctx)
+    if err != nil {
+        return nil, err
+    }
+    registry := NewRegistry(result.Instruments)
+    exch.SetRegistry(registry)
+    return registry, nil
+}
+
+
+// nil turns order normalization off
+func (exch *Exchange) SetRegistry(registry *Registry) {
+    exch.registry.Store(registry)
+}
+
+
+func (exch *Exchange) Registry() *Registry {
+    return exch.registry.Load()
+}
+//}}} Get instruments
+
+
+//{{{ Registry
+type Registry struct {
+    mu          sync.RWMutex
+    instruments map[string]types.Instrument
+}
+
+
+func NewRegistry(instruments []types.Instrument) *Registry {
+    r := &Registry{instruments: map[string]types.Instrument{}}
+    r.Update(instruments)
+    return r
+}
+
+
+// Embedded snapshot, might be outdated so prefer LoadInstruments when online
+func SnapshotRegistry() (*Registry, error) {
+    return registryFromJSON(instrumentsSnapshot)
+}
+
+
+// Snapshot saved from /instruments response (ex.: curl > instruments.json)
+func LoadRegistryFile(path string) (*Registry, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    return registryFromJSON(data)
+}
+
+
+func registryFromJSON(data []byte) (*Registry, error) {
+    var result types.InstrumentsResponse
+    if err := json.Unmarshal(data, &result); err != nil {
+        return nil, fmt.Errorf("Failed to decode instruments: %w", err)
+    }
+    return NewRegistry(result.Instruments), nil
+}
+
+
+// Adds or replaces instruments (by symbol)
+func (r *Registry) Update(instruments []types.Instrument) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    for _, inst := range instruments {
+        r.instruments[strings.ToUpper(inst.Symbol)] = inst
+    }
+}
+
+
+func (r *Registry) Lookup(symbol string) (types.Instrument, bool) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    inst, ok := r.instruments[strings.ToUpper(symbol)]
+    return inst, ok
+}
+
+
+func (r *Registry) Symbols() []string {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    symbols := make([]string, 0, len(r.instruments))
+    for symbol := range r.instruments {
+        symbols = append(symbols, symbol)
+    }
+    sort.Strings(symbols)
+    return symbols
+}
+
+
+// Base/quote from contract spec, ex.: "PF_XRPUSD" => "XRP", "USD"
+func (r *Registry) SplitSymbol(symbol string) (string, string, error) {
+    inst, ok := r.Lookup(symbol)
+    if !ok {
+        return "", "", fmt.Errorf("%s: %w", symbol, ErrUnknownInstrument)
+    }
+    return inst.Base, inst.Quote, nil
+}
+
+
+// Leverage and PnL preferences exist only for flex instruments
+func (r *Registry) CheckMultiCollateral(symbol string) (types.Instrument, error) {
+    inst, ok := r.Lookup(symbol)
+    if !ok {
+        return inst, fmt.Errorf("%s: %w", symbol, ErrUnknownInstrument)
+    }
+    if inst.Type != "flexible_futures" {
+        return inst, fmt.Errorf("%s: %w", symbol, ErrNotMultiCollateral)
+    }
+    return inst, nil
+}
+
+
+// Leverage preference has to fit first margin level
+func (r *Registry) CheckLeverage(symbol string, maxLeverage float64) error {
+    inst, err := r.CheckMultiCollateral(symbol)
+    if err != nil {
+        return err
+    }
+    if limit := inst.MaxLeverage(); limit > 0 && maxLeverage > limit {
+        return fmt.Errorf("%s: leverage %v (max %v): %w", symbol, maxLeverage, limit, ErrLeverageTooHigh)
+    }
+    return nil
+}
+//}}} Registry
+
+
+//{{{ Rounding
+// Decimals needed to print step, 0.01 => 2, 0.5 => 1, 10 => 0
+func stepDecimals(step float64) int {
+    str := strconv.FormatFloat(step, 'f', -1, 64)
+    if i := strings.IndexByte(str, '.'); i >= 0 {
+        return len(str) - i - 1
+    }
+    return 0
+}
+
+
+// Rounds to step and strips float noise (0.30000000000000004 => 0.3)
+func roundToStep(value, step float64, round func(float64) float64) float64 {
+    if step <= 0 {
+        return value
+    }
+    // Small epsilon so 0.29999999 / 0.01 doesn't floor to 28
+    rounded := round(value/step + 1e-9) * step
+    clean, _ := strconv.ParseFloat(strconv.FormatFloat(rounded, 'f', stepDecimals(step), 64), 64)
+    return clean
+}
+
+
+// Nearest tick
+func (r *Registry) RoundPrice(symbol string, price float64) (float64, error) {
+    inst, ok := r.Lookup(symbol)
+    if !ok {
+        return 0, fmt.Errorf("%s: %w", symbol, ErrUnknownInstrument)
+    }
+    return roundToStep(price, inst.TickSize, math.Round), nil
+}
+
+
+// Down to size precision, never rounds up so order is never bigger than asked
+func (r *Registry) RoundSize(symbol string, size float64) (float64, error) {
+    inst, ok := r.Lookup(symbol)
+    if !ok {
+        return 0, fmt.Errorf("%s: %w", symbol, ErrUnknownInstrument)
+    }
+    return roundToStep(size, inst.MinOrderSize(), math.Floor), nil
+}
+
+
+// Checks symbol is known and tradeable, rounds prices to tick and size to precision (in place)
+func (r *Registry) Normalize(orderReq *types.SendOrderRequest) error {
+    inst, ok := r.Lookup(orderReq.Symbol)
+    if !ok {
+        return fmt.Errorf("%s: %w", orderReq.Symbol, ErrUnknownInstrument)
+    }
+    if !inst.Tradeable {
+        return fmt.Errorf("%s: %w", orderReq.Symbol, ErrNotTradeable)
+    }
+
+    size := roundToStep(orderReq.Size, inst.MinOrderSize(), math.Floor)
+    if size < inst.MinOrderSize() {
+        return fmt.Errorf("%s: size %v (min %v): %w", orderReq.Symbol, orderReq.Size, inst.MinOrderSize(), ErrSizeTooSmall)
+    }
+    orderReq.Size = size
+    if orderReq.LimitPrice != 0 {
+        orderReq.LimitPrice = roundToStep(orderReq.LimitPrice, inst.TickSize, math.Round)
+    }
+    if orderReq.StopPrice != nil {
+        stopPrice := roundToStep(*orderReq.StopPrice, inst.TickSize, math.Round)
+        orderReq.StopPrice = &stopPrice
+    }
+    return nil
+}
+//}}} Rounding
@@ -0,0 +1,147 @@
+package krakenftr
+
+import (
+    "errors"
+    "testing"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+
+
+//{{{ Test snapshot
+func TestSnapshotRegistry(t *testing.T) {
+    registry, err := SnapshotRegistry()
+    if err != nil {
+        t.Fatalf("SnapshotRegistry failed: %v", err)
+    }
+    inst, ok := registry.Lookup("pf_bchusd")
+    if !ok {
+        t.Fatalf("PF_BCHUSD not in snapshot, got: %v", registry.Symbols())
+    }
+    if inst.Base != "BCH" || inst.Quote != "USD" {
+        t.Errorf("Wrong base/quote: %s/%s", inst.Base, inst.Quote)
+    }
+    if inst.MinOrderSize() != 0.01 || inst.MaxLeverage() != 50 {
+        t.Errorf("Wrong min size/max leverage: %v/%v", inst.MinOrderSize(), inst.MaxLeverage())
+    }
+}
+//}}} Test snapshot
+
+
+//{{{ Test Normalize
+func TestNormalize(t *testing.T) {
+    registry, err := SnapshotRegistry()
+    if err != nil {
+        t.Fatalf("SnapshotRegistry failed: %v", err)
+    }
+    floatPtr := func(f float64) *float64 { return &f }
+
+    tests := []struct {
+        name            string
+        req             types.SendOrderRequest
+        expectSize      float64
+        expectPrice     float64
+        expectStop      float64
+        expectErr       error
+    }{
+        {
+            name:           "SuccRoundBCH",
+            req:            types.SendOrderRequest{Symbol: "PF_BCHUSD", Size: 0.257, LimitPrice: 524.126},
+            expectSize:     0.25,
+            expectPrice:    524.13,
+        }, {
+            name:           "SuccRoundXBTHalfTick",
+            req:            types.SendOrderRequest{Symbol: "PF_XBTUSD", Size: 0.00019, LimitPrice: 61234.6, StopPrice: floatPtr(61000.4)},
+            expectSize:     0.0001,
+            expectPrice:    61235,
+            expectStop:     61000,
+        }, {
+            name:           "SuccFloatNoise",
+            req:            types.SendOrderRequest{Symbol: "PF_ETHUSD", Size: 0.3, LimitPrice: 0.1 + 0.2},
+            expectSize:     0.3,
+            expectPrice:    0.3,
+        }, {
+            name:           "FailSizeTooSmall",
+            req:            types.SendOrderRequest{Symbol: "PF_XRPUSD", Size: 0.5, LimitPrice: 2.5},
+            expectErr:      ErrSizeTooSmall,
+        }, {
+            name:           "FailUnknown",
+            req:            types.SendOrderRequest{Symbol: "PF_NOPEUSD", Size: 1, LimitPrice: 1},
+            expectErr:      ErrUnknownInstrument,
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            req := tc.req
+            err := registry.Normalize(&req)
+            if !errors.Is(err, tc.expectErr) {
+                t.Fatalf("Wrong error\nExpected:\t%v\nGot:\t\t%v", tc.expectErr, err)
+            }
+            if err != nil {
+                return
+            }
+            if req.Size != tc.expectSize || req.LimitPrice != tc.expectPrice {
+                t.Errorf("Wrong size/price\nExpected:\t%v/%v\nGot:\t\t%v/%v", tc.expectSize, tc.expectPrice, req.Size, req.LimitPrice)
+            }
+            if req.StopPrice != nil && *req.StopPrice != tc.expectStop {
+                t.Errorf("Wrong stop price\nExpected:\t%v\nGot:\t\t%v", tc.expectStop, *req.StopPrice)
+            }
+        })
+    }
+}
+//}}} Test Normalize
+
+
+//{{{ Test LoadInstruments + SendOrder
+func TestFakeLoadInstruments(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    snapshot, _ := SnapshotRegistry()
+    inst, _ := snapshot.Lookup("PF_BCHUSD")
+    srv.SetInstruments(inst)
+
+    orderReq := types.SendOrderRequest{
+        OrderType:  "post",
+        Symbol:     "PF_BCHUSD",
+        Side:       "buy",
+        Size:       0.25,
+        LimitPrice: 524.123,
+        CliOrdId:   "normalize-test",
+    }
+
+    // Without registry fake rejects price off tick
+    sent, err := exch.SendOrder(t.Context(), orderReq)
+    if err != nil {
+        t.Fatalf("SendOrder failed: %v", err)
+    }
+    if sent.SendStatus.Status != "invalidPrice" {
+        t.Errorf("Wrong status\nExpected:\tinvalidPrice\nGot:\t\t%s", sent.SendStatus.Status)
+    }
+
+    registry, err := exch.LoadInstruments(t.Context())
+    if err != nil {
+        t.Fatalf("LoadInstruments failed: %v", err)
+    }
+    if symbols := registry.Symbols(); len(symbols) != 1 || symbols[0] != "PF_BCHUSD" {
+        t.Errorf("Wrong symbols: %v", symbols)
+    }
+
+    sent, err = exch.SendOrder(t.Context(), orderReq)
+    if err != nil {
+        t.Fatalf("SendOrder failed: %v", err)
+    }
+    if sent.SendStatus.Status != "placed" {
+        t.Fatalf("Order not placed: %+v", sent.SendStatus)
+    }
+    if open := srv.OpenOrders(); len(open) != 1 || open[0].LimitPrice != 524.12 {
+        t.Errorf("Price not rounded: %+v", open)
+    }
+
+    // Unknown symbol never leaves the client
+    orderReq.Symbol = "PF_XRPUSD"
+    if _, err := exch.SendOrder(t.Context(), orderReq); !errors.Is(err, ErrUnknownInstrument) {
+        t.Errorf("Expected ErrUnknownInstrument, got: %v", err)
+    }
+}
+//}}} Test LoadInstruments + SendOrder
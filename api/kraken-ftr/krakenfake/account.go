@@ -0,0 +1,135 @@
+package krakenfake
+
+import (
+    "net/http"
+    "sort"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+
+
+// Kraken returns at most this many fills per call
+const fillsPageSize = 100
+
+
+//{{{ Seed/inspect
+// Adds historical fill(s) as if they were executed earlier, does not touch positions
+func (s *Server) AddFills(fills ...types.Fill) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.fills = append(s.fills, fills...)
+    sort.SliceStable(s.fills, func(i, j int) bool { return s.fills[i].FillTime < s.fills[j].FillTime })
+}
+
+
+// Snapshot of open orders, oldest first
+func (s *Server) OpenOrders() []types.OpenOrder {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    orders := make([]types.OpenOrder, 0, len(s.orders))
+    for _, o := range s.orders {
+        orders = append(orders, *o)
+    }
+    return orders
+}
+
+
+// Snapshot of all fills, oldest first
+func (s *Server) Fills() []types.Fill {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]types.Fill(nil), s.fills...)
+}
+
+
+// Replaces what /accounts returns
+func (s *Server) SetAccounts(accounts types.Accounts) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.accounts = accounts
+}
+
+
+// Snapshot of open positions sorted by symbol
+func (s *Server) OpenPositions() []types.OpenPosition {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.openPositionsLocked()
+}
+
+
+func (s *Server) openPositionsLocked() []types.OpenPosition {
+    positions := make([]types.OpenPosition, 0, len(s.positions))
+    for _, p := range s.positions {
+        pos := *p
+        if leverage, ok := s.leverage[pos.Symbol]; ok {
+            pos.MaxFixedLeverage = &leverage
+        }
+        if currency, ok := s.pnlCurrency[pos.Symbol]; ok {
+            pos.PnlCurrency = &currency
+        }
+        positions = append(positions, pos)
+    }
+    sort.Slice(positions, func(i, j int) bool { return positions[i].Symbol < positions[j].Symbol })
+    return positions
+}
+//}}} Seed/inspect
+
+
+//{{{ Handlers
+func (s *Server) handleOpenPositions(w http.ResponseWriter, r *http.Request, body string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    writeJSON(w, http.StatusOK, map[string]any{
+        "result":           "success",
+        "serverTime":       s.nowLocked().Format(TimeLayout),
+        "openPositions":    s.openPositionsLocked(),
+    })
+}
+
+
+func (s *Server) handleAccounts(w http.ResponseWriter, r *http.Request, body string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    writeJSON(w, http.StatusOK, map[string]any{
+        "result":       "success",
+        "serverTime":   s.nowLocked().Format(TimeLayout),
+        "accounts":     s.accounts,
+    })
+}
+
+
+// Newest first, `lastFillTime` (ISO 8601) returns page of fills before that time
+func (s *Server) handleFills(w http.ResponseWriter, r *http.Request, body string) {
+    var cursor time.Time
+    if v := r.URL.Query().Get("lastFillTime"); v != "" {
+        t, err := time.Parse(time.RFC3339Nano, v)
+        if err != nil {
+            writeError(w, http.StatusOK, "invalidArgument", s.serverTime())
+            return
+        }
+        cursor = t
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    page := []types.Fill{}
+    for i := len(s.fills) - 1; i >= 0 && len(page) < fillsPageSize; i-- {
+        fill := s.fills[i]
+        if !cursor.IsZero() {
+            fillTime, err := time.Parse(time.RFC3339Nano, fill.FillTime)
+            if err != nil || !fillTime.Before(cursor) {
+                continue
+            }
+        }
+        page = append(page, fill)
+    }
+    writeJSON(w, http.StatusOK, map[string]any{
+        "result":       "success",
+        "serverTime":   s.nowLocked().Format(TimeLayout),
+        "fills":        page,
+    })
+}
+//}}} Handlers
@@ -0,0 +1,347 @@
+package krakenfake
+
+import (
+    "encoding/json"
+    "net/http"
+    "sort"
+    "sync"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+import (
+    "github.com/gorilla/websocket"
+)
+// WebSocket stand-in at /ws/v1: subscribe/unsubscribe, book_snapshot and
+// trade_snapshot on subscribe, Publish* pushes to subscribed connections.
+// Private feeds (challenge, snapshots, pushes) are in feeds_private.go.
+// Seq numbers are stamped here (per product), tests can force gaps.
+
+
+var upgrader = websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+
+
+type feedConn struct {
+    conn    *websocket.Conn
+    writeMu sync.Mutex
+    subs    map[string]bool     // feed + "|" + product id
+    challenge string            // last one handed out, private feeds need it signed
+}
+
+
+func (fc *feedConn) writeJSON(v any) error {
+    fc.writeMu.Lock()
+    defer fc.writeMu.Unlock()
+    return fc.conn.WriteJSON(v)
+}
+
+
+//{{{ Handler
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+    conn, err := upgrader.Upgrade(w, r, nil)
+    if err != nil {
+        return
+    }
+    fc := &feedConn{conn: conn, subs: map[string]bool{}}
+    s.mu.Lock()
+    s.feedConns[fc] = true
+    s.mu.Unlock()
+    defer func() {
+        s.mu.Lock()
+        delete(s.feedConns, fc)
+        s.mu.Unlock()
+        conn.Close()
+    }()
+
+    fc.writeJSON(map[string]any{"event": "info", "version": 1})
+    for {
+        var req struct {
+            Event               string      `json:"event"`
+            Feed                string      `json:"feed"`
+            ProductIds          []string    `json:"product_ids"`
+            ApiKey              string      `json:"api_key"`
+            OriginalChallenge   string      `json:"original_challenge"`
+            SignedChallenge     string      `json:"signed_challenge"`
+        }
+        if err := conn.ReadJSON(&req); err != nil {
+            return
+        }
+        if req.Event == "challenge" {
+            if req.ApiKey != s.PublicKey {
+                fc.writeJSON(map[string]any{"event": "error", "message": "Invalid API key"})
+                continue
+            }
+            s.mu.Lock()
+            fc.challenge = s.newIdLocked()
+            s.mu.Unlock()
+            fc.writeJSON(map[string]any{"event": "challenge", "message": fc.challenge})
+            continue
+        }
+        if (!validFeeds[req.Feed] && !privateFeeds[req.Feed]) || (req.Event != "subscribe" && req.Event != "unsubscribe") {
+            fc.writeJSON(map[string]any{"event": "error", "message": "Invalid feed"})
+            continue
+        }
+        if privateFeeds[req.Feed] && !s.feedAuthorized(fc, req.ApiKey, req.OriginalChallenge, req.SignedChallenge) {
+            fc.writeJSON(map[string]any{"event": "error", "message": "Invalid challenge"})
+            continue
+        }
+
+        // Holding writeMu keeps publish from slipping a delta in before snapshot
+        fc.writeMu.Lock()
+        products := req.ProductIds
+        if len(products) == 0 {
+            products = []string{""}
+        }
+        s.mu.Lock()
+        for _, id := range products {
+            if req.Event == "subscribe" {
+                fc.subs[req.Feed+"|"+id] = true
+            } else {
+                delete(fc.subs, req.Feed+"|"+id)
+            }
+        }
+        var snapshots []any
+        if req.Event == "subscribe" {
+            for _, id := range products {
+                if snap := s.snapshotLocked(req.Feed, id); snap != nil {
+                    snapshots = append(snapshots, snap)
+                }
+            }
+        }
+        s.mu.Unlock()
+
+        ack := map[string]any{"event": req.Event + "d", "feed": req.Feed}
+        if len(req.ProductIds) > 0 {
+            ack["product_ids"] = req.ProductIds
+        }
+        conn.WriteJSON(ack)
+        for _, snap := range snapshots {
+            conn.WriteJSON(snap)
+        }
+        fc.writeMu.Unlock()
+    }
+}
+
+
+var validFeeds = map[string]bool{"ticker": true, "book": true, "trade": true, "heartbeat": true}
+
+
+// nil for feeds without snapshot, caller holds s.mu
+func (s *Server) snapshotLocked(feed, productId string) any {
+    if privateFeeds[feed] {
+        return s.privateSnapshotLocked(feed)
+    }
+    if productId == "" {
+        return nil
+    }
+    switch feed {
+    case "book":
+        book := s.bookLocked(productId)
+        return types.BookSnapshot{
+            Feed:       "book_snapshot",
+            ProductId:  productId,
+            Timestamp:  s.nowLocked().UnixMilli(),
+            Seq:        s.feedSeq["book|"+productId],
+            Bids:       levels(book.bids, true),
+            Asks:       levels(book.asks, false),
+        }
+    case "trade":
+        return types.TradeSnapshot{Feed: "trade_snapshot", ProductId: productId, Trades: append([]types.TradeEvent{}, s.trades[productId]...)}
+    }
+    return nil
+}
+//}}} Handler
+
+
+//{{{ Book
+type fakeBook struct {
+    bids    map[float64]float64
+    asks    map[float64]float64
+}
+
+
+// Caller holds s.mu
+func (s *Server) bookLocked(productId string) *fakeBook {
+    book, ok := s.books[productId]
+    if !ok {
+        book = &fakeBook{bids: map[float64]float64{}, asks: map[float64]float64{}}
+        s.books[productId] = book
+    }
+    return book
+}
+
+
+func levels(side map[float64]float64, desc bool) []types.BookLevel {
+    out := make([]types.BookLevel, 0, len(side))
+    for price, qty := range side {
+        out = append(out, types.BookLevel{Price: price, Qty: qty})
+    }
+    sort.Slice(out, func(i, j int) bool {
+        if desc {
+            return out[i].Price > out[j].Price
+        }
+        return out[i].Price < out[j].Price
+    })
+    return out
+}
+
+
+// Replaces book, seq of next snapshot is `seq`
+func (s *Server) SetBook(productId string, seq int64, bids, asks []types.BookLevel) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    book := &fakeBook{bids: map[float64]float64{}, asks: map[float64]float64{}}
+    for _, l := range bids {
+        book.bids[l.Price] = l.Qty
+    }
+    for _, l := range asks {
+        book.asks[l.Price] = l.Qty
+    }
+    s.books[productId] = book
+    s.feedSeq["book|"+productId] = seq
+}
+
+
+// Applies delta to book and pushes it; Seq 0 => previous + 1, anything else
+// is used as is (ex.: skip some to simulate lost messages)
+func (s *Server) PublishBookDelta(delta types.BookDelta) {
+    s.mu.Lock()
+    key := "book|" + delta.ProductId
+    if delta.Seq == 0 {
+        delta.Seq = s.feedSeq[key] + 1
+    }
+    s.feedSeq[key] = delta.Seq
+    delta.Feed = "book"
+    if delta.Timestamp == 0 {
+        delta.Timestamp = s.nowLocked().UnixMilli()
+    }
+    book := s.bookLocked(delta.ProductId)
+    side := book.asks
+    if delta.Side == "buy" {
+        side = book.bids
+    }
+    if delta.Qty == 0 {
+        delete(side, delta.Price)
+    } else {
+        side[delta.Price] = delta.Qty
+    }
+    s.mu.Unlock()
+    s.publish("book", delta.ProductId, delta)
+}
+//}}} Book
+
+
+//{{{ Ticker, trade, heartbeat
+func (s *Server) PublishTicker(ticker types.TickerEvent) {
+    ticker.Feed = "ticker"
+    if ticker.Time == 0 {
+        ticker.Time = s.nowMilli()
+    }
+    s.publish("ticker", ticker.ProductId, ticker)
+}
+
+
+// Seq 0 => previous + 1, kept for trade_snapshot (last 100)
+func (s *Server) PublishTrade(trade types.TradeEvent) {
+    s.mu.Lock()
+    key := "trade|" + trade.ProductId
+    if trade.Seq == 0 {
+        trade.Seq = s.feedSeq[key] + 1
+    }
+    s.feedSeq[key] = trade.Seq
+    trade.Feed = "trade"
+    if trade.Uid == "" {
+        trade.Uid = s.newIdLocked()
+    }
+    if trade.Time == 0 {
+        trade.Time = s.nowLocked().UnixMilli()
+    }
+    // Snapshot is newest first like Kraken
+    s.trades[trade.ProductId] = append([]types.TradeEvent{trade}, s.trades[trade.ProductId]...)
+    if len(s.trades[trade.ProductId]) > 100 {
+        s.trades[trade.ProductId] = s.trades[trade.ProductId][:100]
+    }
+    // Same trade on /history
+    s.addPublicTradesLocked(trade.ProductId, types.PublicTrade{
+        Time:       time.UnixMilli(trade.Time).UTC().Format(TimeLayout),
+        TradeId:    trade.Seq,
+        Price:      trade.Price,
+        Size:       trade.Qty,
+        Side:       trade.Side,
+        Type:       trade.Type,
+        UID:        trade.Uid,
+    })
+    s.mu.Unlock()
+    s.publish("trade", trade.ProductId, trade)
+}
+
+
+func (s *Server) PublishHeartbeat() {
+    s.publish("heartbeat", "", types.Heartbeat{Feed: "heartbeat", Time: s.nowMilli()})
+}
+
+
+func (s *Server) nowMilli() int64 {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.nowLocked().UnixMilli()
+}
+//}}} Ticker, trade, heartbeat
+
+
+//{{{ Connections
+func (s *Server) publish(feed, productId string, msg any) {
+    data, err := json.Marshal(msg)
+    if err != nil {
+        return
+    }
+    s.mu.Lock()
+    var targets []*feedConn
+    for fc := range s.feedConns {
+        if fc.subs[feed+"|"+productId] {
+            targets = append(targets, fc)
+        }
+    }
+    s.mu.Unlock()
+    for _, fc := range targets {
+        fc.writeJSON(json.RawMessage(data))
+    }
+}
+
+
+// Number of connections subscribed to feed/product ("" for heartbeat),
+// tests wait on this before publishing
+func (s *Server) Subscribers(feed, productId string) int {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    n := 0
+    for fc := range s.feedConns {
+        if fc.subs[feed+"|"+productId] {
+            n++
+        }
+    }
+    return n
+}
+
+
+// Closes every WebSocket connection, clients are expected to reconnect
+func (s *Server) DropFeedConnections() {
+    s.mu.Lock()
+    conns := make([]*feedConn, 0, len(s.feedConns))
+    for fc := range s.feedConns {
+        conns = append(conns, fc)
+    }
+    s.mu.Unlock()
+    for _, fc := range conns {
+        fc.conn.Close()
+    }
+}
+
+
+// httptest does not track hijacked connections, close them too
+func (s *Server) Close() {
+    s.DropFeedConnections()
+    s.Server.Close()
+}
+//}}} Connections
@@ -0,0 +1,221 @@
+package krakenfake
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "crypto/sha512"
+    "encoding/base64"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+// Private feeds: challenge is checked like Kraken does, then REST activity
+// (orders placed/cancelled/filled, positions) is pushed to subscribers.
+// Changes are queued while s.mu is held and flushed after, in order.
+
+
+var privateFeeds = map[string]bool{"open_orders": true, "fills": true, "open_positions": true, "balances": true}
+
+
+type queuedMsg struct {
+    feed    string
+    msg     any
+}
+
+
+// Same algorithm as krakenftr.signChallenge, written again on purpose
+func (s *Server) expectedChallengeSignature(challenge string) (string, error) {
+    digest := sha256.Sum256([]byte(challenge))
+    key, err := base64.StdEncoding.DecodeString(s.PrivateKey)
+    if err != nil {
+        return "", err
+    }
+    hmacHash := hmac.New(sha512.New, key)
+    hmacHash.Write(digest[:])
+    return base64.StdEncoding.EncodeToString(hmacHash.Sum(nil)), nil
+}
+
+
+// Challenge handed out on this connection must come back signed
+func (s *Server) feedAuthorized(fc *feedConn, apiKey, original, signed string) bool {
+    if apiKey != s.PublicKey || original == "" || original != fc.challenge {
+        return false
+    }
+    expected, err := s.expectedChallengeSignature(original)
+    return err == nil && hmac.Equal([]byte(expected), []byte(signed))
+}
+
+
+//{{{ Snapshots
+// Caller holds s.mu
+func (s *Server) privateSnapshotLocked(feed string) any {
+    switch feed {
+    case "open_orders":
+        orders := make([]types.FeedOrder, 0, len(s.orders))
+        for _, o := range s.orders {
+            orders = append(orders, feedOrder(o))
+        }
+        return types.OpenOrdersEvent{Feed: "open_orders_snapshot", Account: s.PublicKey, Orders: orders}
+    case "fills":
+        fills := []types.FeedFill{}
+        for i := len(s.fills) - 1; i >= 0 && len(fills) < fillsPageSize; i-- {
+            fills = append(fills, s.feedFillLocked(s.fills[i]))
+        }
+        return types.FillsEvent{Feed: "fills_snapshot", Account: s.PublicKey, Fills: fills}
+    case "open_positions":
+        return s.positionsEventLocked()
+    case "balances":
+        return types.BalancesEvent{Feed: "balances_snapshot", Account: s.PublicKey, Timestamp: s.nowLocked().UnixMilli(), FlexFutures: s.flex}
+    }
+    return nil
+}
+
+
+func feedOrder(o *types.OpenOrder) types.FeedOrder {
+    orderType := "limit"
+    if isTrigger(o.OrderType) {
+        orderType = "stop"
+        if o.OrderType == "take_profit" {
+            orderType = "take_profit"
+        }
+    }
+    fo := types.FeedOrder{
+        Instrument:     o.Symbol,
+        Time:           milli(o.ReceivedTime),
+        LastUpdateTime: milli(o.LastUpdateTime),
+        Qty:            o.UnfilledSize + o.FilledSize,
+        Filled:         o.FilledSize,
+        LimitPrice:     o.LimitPrice,
+        Type:           orderType,
+        OrderId:        o.OrderId,
+        ReduceOnly:     o.ReduceOnly,
+    }
+    if o.Side == "sell" {
+        fo.Direction = 1
+    }
+    if o.CliOrdId != nil {
+        fo.CliOrdId = *o.CliOrdId
+    }
+    if o.StopPrice != nil {
+        fo.StopPrice = *o.StopPrice
+    }
+    if o.TriggerSignal != nil {
+        fo.TriggerSignal = *o.TriggerSignal
+    }
+    return fo
+}
+
+
+// Caller holds s.mu
+func (s *Server) feedFillLocked(f types.Fill) types.FeedFill {
+    ff := types.FeedFill{
+        Instrument: f.Symbol,
+        Time:       milli(f.FillTime),
+        Price:      f.Price,
+        Buy:        f.Side == "buy",
+        Qty:        f.Size,
+        OrderId:    f.OrderId,
+        FillId:     f.FillId,
+        FillType:   f.FillType,
+    }
+    if f.CliOrdId != nil {
+        ff.CliOrdId = *f.CliOrdId
+    }
+    return ff
+}
+
+
+// Caller holds s.mu
+func (s *Server) positionsEventLocked() types.PositionsEvent {
+    positions := []types.FeedPosition{}
+    for _, p := range s.openPositionsLocked() {
+        balance := p.Size
+        if p.Side == "short" {
+            balance = -p.Size
+        }
+        fp := types.FeedPosition{Instrument: p.Symbol, Balance: balance, EntryPrice: p.Price}
+        if ticker, ok := s.tickers[p.Symbol]; ok {
+            fp.MarkPrice = ticker.MarkPrice
+            fp.Pnl = (ticker.MarkPrice - p.Price) * balance
+        }
+        positions = append(positions, fp)
+    }
+    s.feedSeq["open_positions|"]++
+    return types.PositionsEvent{
+        Feed:       "open_positions",
+        Account:    s.PublicKey,
+        Positions:  positions,
+        Seq:        s.feedSeq["open_positions|"],
+        Timestamp:  s.nowLocked().UnixMilli(),
+    }
+}
+
+
+func milli(kraken string) int64 {
+    t, err := time.Parse(time.RFC3339Nano, kraken)
+    if err != nil {
+        return 0
+    }
+    return t.UnixMilli()
+}
+//}}} Snapshots
+
+
+//{{{ Live updates
+// Caller holds s.mu
+func (s *Server) queueLocked(feed string, msg any) {
+    s.feedQueue = append(s.feedQueue, queuedMsg{feed: feed, msg: msg})
+}
+
+
+// reason: new_placed_order_by_user, edited_by_user, ...
+func (s *Server) queueOrderLocked(o *types.OpenOrder, reason string) {
+    fo := feedOrder(o)
+    s.queueLocked("open_orders", types.OpenOrdersEvent{Feed: "open_orders", Order: &fo, Reason: reason})
+}
+
+
+// reason: cancelled_by_user, full_fill, ...
+func (s *Server) queueOrderGoneLocked(o *types.OpenOrder, reason string) {
+    ev := types.OpenOrdersEvent{Feed: "open_orders", OrderId: o.OrderId, IsCancel: true, Reason: reason}
+    if o.CliOrdId != nil {
+        ev.CliOrdId = *o.CliOrdId
+    }
+    s.queueLocked("open_orders", ev)
+}
+
+
+func (s *Server) queueFillLocked(f types.Fill) {
+    s.feedSeq["fills|"]++
+    ff := s.feedFillLocked(f)
+    ff.Seq = s.feedSeq["fills|"]
+    s.queueLocked("fills", types.FillsEvent{Feed: "fills", Username: s.PublicKey, Fills: []types.FeedFill{ff}})
+    s.queueLocked("open_positions", s.positionsEventLocked())
+}
+
+
+// Multi-collateral wallet pushed on `balances`
+func (s *Server) SetBalances(flex types.FlexFuturesBalance) {
+    s.mu.Lock()
+    s.flex = flex
+    s.feedSeq["balances|"]++
+    s.queueLocked("balances", types.BalancesEvent{Feed: "balances", Account: s.PublicKey, Seq: s.feedSeq["balances|"], Timestamp: s.nowLocked().UnixMilli(), FlexFutures: flex})
+    s.mu.Unlock()
+    s.flushFeeds()
+}
+
+
+// Pushes queued changes in order, call after s.mu is released
+func (s *Server) flushFeeds() {
+    s.flushMu.Lock()
+    defer s.flushMu.Unlock()
+    s.mu.Lock()
+    queue := s.feedQueue
+    s.feedQueue = nil
+    s.mu.Unlock()
+    for _, q := range queue {
+        s.publish(q.feed, "", q.msg)
+    }
+}
+//}}} Live updates
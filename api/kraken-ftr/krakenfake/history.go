@@ -0,0 +1,288 @@
+package krakenfake
+
+import (
+    "net/http"
+    "slices"
+    "sort"
+    "strconv"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+// History API: orders/triggers/executions (v2) and account log (v3).
+// Order lifecycle and fills are recorded as they happen, account log gets
+// a "futures trade" entry per fill, everything else (funding, transfers,
+// liquidations, ...) is seeded through AddAccountLog.
+
+
+// Kraken defaults when `count` is missing
+const (
+    historyPageSize     = 500
+    accountLogPageSize  = 500
+)
+
+
+//{{{ Record
+// Kraken reports both sides, fake only knows ours
+func historyOrder(o *types.OpenOrder, now time.Time) *types.HistoryOrder {
+    ho := &types.HistoryOrder{
+        Uid:                    o.OrderId,
+        Tradeable:              o.Symbol,
+        Direction:              direction(o.Side),
+        Quantity:               o.FilledSize + o.UnfilledSize,
+        Filled:                 o.FilledSize,
+        LimitPrice:             o.LimitPrice,
+        OrderType:              o.OrderType,
+        ReduceOnly:             o.ReduceOnly,
+        LastUpdateTimestamp:    now.UnixMilli(),
+    }
+    if t, err := time.Parse(TimeLayout, o.ReceivedTime); err == nil {
+        ho.Timestamp = t.UnixMilli()
+    }
+    if o.CliOrdId != nil {
+        ho.ClientId = *o.CliOrdId
+    }
+    if o.StopPrice != nil {
+        ho.TriggerPrice = *o.StopPrice
+    }
+    if o.TriggerSignal != nil {
+        ho.TriggerSignal = *o.TriggerSignal
+    }
+    return ho
+}
+
+
+func direction(side string) string {
+    if side == "sell" {
+        return "Sell"
+    }
+    return "Buy"
+}
+
+
+// kind: orders, triggers, executions; caller holds s.mu
+func (s *Server) recordHistoryLocked(kind string, event types.HistoryEvent) {
+    s.history[kind] = append(s.history[kind], types.HistoryElement{
+        Uid:        s.newIdLocked(),
+        Timestamp:  s.nowLocked().UnixMilli(),
+        Event:      event,
+    })
+}
+
+
+// Order event, triggers go to their own stream with OrderTrigger* types
+// (OrderPlaced => OrderTriggerPlaced); old is set for OrderUpdated
+func (s *Server) recordOrderLocked(eventType string, o *types.OpenOrder, old *types.HistoryOrder, reason string) {
+    kind := "orders"
+    if isTrigger(o.OrderType) {
+        kind = "triggers"
+        eventType = "OrderTrigger" + eventType[len("Order"):]
+    }
+    s.recordHistoryLocked(kind, types.HistoryEvent{Type: eventType, Order: historyOrder(o, s.nowLocked()), OldOrder: old, Reason: reason})
+}
+
+
+// Execution + account log "futures trade", fees are not modelled so balance stays
+func (s *Server) recordFillLocked(f types.Fill) {
+    now := s.nowLocked()
+    order := &types.HistoryOrder{
+        Uid:                    f.OrderId,
+        Tradeable:              f.Symbol,
+        Direction:              direction(f.Side),
+        Quantity:               f.Size,
+        Filled:                 f.Size,
+        LimitPrice:             f.Price,
+        Timestamp:              now.UnixMilli(),
+        LastUpdateTimestamp:    now.UnixMilli(),
+    }
+    if f.CliOrdId != nil {
+        order.ClientId = *f.CliOrdId
+    }
+    execution := &types.HistoryExecution{
+        Uid:        f.FillId,
+        Timestamp:  now.UnixMilli(),
+        Quantity:   f.Size,
+        Price:      f.Price,
+        MarkPrice:  f.Price,
+        UsdValue:   f.Size * f.Price,
+    }
+    if ticker, ok := s.tickers[f.Symbol]; ok {
+        execution.MarkPrice = ticker.MarkPrice
+    }
+    if f.FillType == "maker" {
+        execution.MakerOrder = order
+        execution.LimitFilled = true
+    } else {
+        execution.TakerOrder = order
+    }
+    s.recordHistoryLocked("executions", types.HistoryEvent{Type: "Execution", Execution: execution})
+
+    balance := 0.0
+    if n := len(s.accountLog); n > 0 {
+        balance = s.accountLog[n-1].NewBalance
+    }
+    price, mark, fee, symbol, fillId := f.Price, execution.MarkPrice, 0.0, f.Symbol, f.FillId
+    s.appendAccountLogLocked(types.AccountLogEntry{
+        Date:           f.FillTime,
+        Asset:          "usd",
+        Info:           "futures trade",
+        MarginAccount:  "flex",
+        OldBalance:     balance,
+        NewBalance:     balance,
+        TradePrice:     &price,
+        MarkPrice:      &mark,
+        Fee:            &fee,
+        Execution:      &fillId,
+        Contract:       &symbol,
+    })
+}
+
+
+// Assigns id and booking uid when missing, caller holds s.mu
+func (s *Server) appendAccountLogLocked(entry types.AccountLogEntry) {
+    s.accountLogId++
+    if entry.Id == 0 {
+        entry.Id = s.accountLogId
+    }
+    if entry.BookingUid == "" {
+        entry.BookingUid = s.newIdLocked()
+    }
+    s.accountLog = append(s.accountLog, entry)
+}
+//}}} Record
+
+
+//{{{ Seed/inspect
+// Adds account log entries (funding, transfers, liquidations, ...), kept in
+// id order; Id 0 gets the next free one
+func (s *Server) AddAccountLog(entries ...types.AccountLogEntry) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for _, entry := range entries {
+        if entry.Id > s.accountLogId {
+            s.accountLogId = entry.Id - 1
+        }
+        s.appendAccountLogLocked(entry)
+    }
+    sort.SliceStable(s.accountLog, func(i, j int) bool { return s.accountLog[i].Id < s.accountLog[j].Id })
+}
+
+
+// kind: orders, triggers, executions; oldest first
+func (s *Server) History(kind string) []types.HistoryElement {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]types.HistoryElement{}, s.history[kind]...)
+}
+//}}} Seed/inspect
+
+
+//{{{ Handlers
+func queryInt(r *http.Request, key string, fallback int64) (int64, bool) {
+    v := r.URL.Query().Get(key)
+    if v == "" {
+        return fallback, true
+    }
+    n, err := strconv.ParseInt(v, 10, 64)
+    return n, err == nil
+}
+
+
+// since inclusive, before exclusive (ms); continuation_token is plain offset here
+func (s *Server) handleHistory(kind string) func(w http.ResponseWriter, r *http.Request, body string) {
+    return func(w http.ResponseWriter, r *http.Request, body string) {
+        q := r.URL.Query()
+        since, okSince := queryInt(r, "since", 0)
+        before, okBefore := queryInt(r, "before", 0)
+        count, okCount := queryInt(r, "count", historyPageSize)
+        offset, okOffset := queryInt(r, "continuation_token", 0)
+        sortOrder := q.Get("sort")
+        if !okSince || !okBefore || !okCount || !okOffset || count <= 0 || (sortOrder != "" && sortOrder != "asc" && sortOrder != "desc") {
+            writeError(w, http.StatusBadRequest, "invalidArgument", s.serverTime())
+            return
+        }
+
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        elements := []types.HistoryElement{}
+        for _, el := range s.history[kind] {
+            if el.Timestamp < since || (before > 0 && el.Timestamp >= before) {
+                continue
+            }
+            if tradeable := q.Get("tradeable"); tradeable != "" && historySymbol(el) != tradeable {
+                continue
+            }
+            elements = append(elements, el)
+        }
+        if sortOrder != "asc" {
+            slices.Reverse(elements)
+        }
+
+        resp := types.HistoryResponse{AccountUid: s.PublicKey}
+        start := min(int(offset), len(elements))
+        end := min(start+int(count), len(elements))
+        resp.Elements = elements[start:end]
+        resp.Len = len(resp.Elements)
+        if end < len(elements) {
+            resp.ContinuationToken = strconv.Itoa(end)
+        }
+        writeJSON(w, http.StatusOK, resp)
+    }
+}
+
+
+func historySymbol(el types.HistoryElement) string {
+    if el.Event.Order != nil {
+        return el.Event.Order.Tradeable
+    }
+    if el.Event.Execution != nil {
+        if o := el.Event.Execution.Order(); o != nil {
+            return o.Tradeable
+        }
+    }
+    return ""
+}
+
+
+// since/before (ms) and from/to (ids) are inclusive, Kraken default sort is desc
+func (s *Server) handleAccountLog(w http.ResponseWriter, r *http.Request, body string) {
+    q := r.URL.Query()
+    since, okSince := queryInt(r, "since", 0)
+    before, okBefore := queryInt(r, "before", 0)
+    from, okFrom := queryInt(r, "from", 0)
+    to, okTo := queryInt(r, "to", 0)
+    count, okCount := queryInt(r, "count", accountLogPageSize)
+    sortOrder := q.Get("sort")
+    if !okSince || !okBefore || !okFrom || !okTo || !okCount || count <= 0 || (sortOrder != "" && sortOrder != "asc" && sortOrder != "desc") {
+        writeError(w, http.StatusBadRequest, "invalidArgument", s.serverTime())
+        return
+    }
+    infos := map[string]bool{}
+    for _, info := range q["info"] {
+        infos[info] = true
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    logs := []types.AccountLogEntry{}
+    for _, entry := range s.accountLog {
+        t, err := time.Parse(time.RFC3339Nano, entry.Date)
+        if err != nil {
+            continue
+        }
+        ms := t.UnixMilli()
+        if ms < since || (before > 0 && ms > before) || entry.Id < from || (to > 0 && entry.Id > to) {
+            continue
+        }
+        if len(infos) > 0 && !infos[entry.Info] {
+            continue
+        }
+        logs = append(logs, entry)
+    }
+    if sortOrder != "asc" {
+        slices.Reverse(logs)
+    }
+    writeJSON(w, http.StatusOK, types.AccountLogResponse{AccountUid: s.PublicKey, Logs: logs[:min(int(count), len(logs))]})
+}
+//}}} Handlers
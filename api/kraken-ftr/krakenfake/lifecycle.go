@@ -0,0 +1,263 @@
+package krakenfake
+
+import (
+    "net/http"
+    "net/url"
+    "strconv"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+// editorder, cancelorder, cancelallorders and cancelallordersafter.
+// Dead man switch runs on fake clock: it is checked before every private
+// request, so tests move SetClock past trigger time and make any call.
+
+
+//{{{ Edit
+// Returns Kraken editStatus.status and edited order (nil when not found), caller holds s.mu
+func (s *Server) editLocked(orderId, cliOrdId string, size, limitPrice, stopPrice *float64) (string, *types.OpenOrder) {
+    i := s.findOrderLocked(orderId, cliOrdId)
+    if i < 0 {
+        return "orderForEditNotFound", nil
+    }
+    o := s.orders[i]
+    if stopPrice != nil && !isTrigger(o.OrderType) {
+        return "orderForEditNotAStop", o
+    }
+    if size != nil && *size <= o.FilledSize {
+        return "invalidSize", o
+    }
+    if limitPrice != nil && *limitPrice <= 0 || stopPrice != nil && *stopPrice <= 0 {
+        return "invalidPrice", o
+    }
+    if limitPrice != nil && o.OrderType == "post" {
+        mark := s.tickers[o.Symbol].MarkPrice
+        if (o.Side == "buy" && *limitPrice >= mark) || (o.Side == "sell" && *limitPrice <= mark) {
+            return "postWouldExecute", o
+        }
+    }
+
+    old := historyOrder(o, s.nowLocked())
+    if size != nil {
+        o.UnfilledSize = *size - o.FilledSize
+    }
+    if limitPrice != nil {
+        o.LimitPrice = *limitPrice
+    }
+    if stopPrice != nil {
+        stop := *stopPrice
+        o.StopPrice = &stop
+    }
+    o.LastUpdateTime = s.nowLocked().Format(TimeLayout)
+    s.queueOrderLocked(o, "edited_by_user")
+    s.recordOrderLocked("OrderUpdated", o, old, "edited_by_user")
+    // New price can cross market right away
+    s.matchLocked(o.Symbol)
+    return "edited", o
+}
+
+
+func optionalFloat(form url.Values, key string) *float64 {
+    v := form.Get(key)
+    if v == "" {
+        return nil
+    }
+    f, err := strconv.ParseFloat(v, 64)
+    if err != nil {
+        return nil
+    }
+    return &f
+}
+
+
+// Same for batch edit instruction (json numbers or strings)
+func floatFromMap(m map[string]any, key string) *float64 {
+    v, ok := m[key]
+    if !ok {
+        return nil
+    }
+    f, ok := numberFromAny(v)
+    if !ok {
+        return nil
+    }
+    return &f
+}
+//}}} Edit
+
+
+//{{{ Cancel all
+// Caller holds s.mu
+func (s *Server) cancelAllLocked(symbol, reason string) []types.CancelledOrder {
+    cancelled := []types.CancelledOrder{}
+    remaining := s.orders[:0]
+    for _, o := range s.orders {
+        if symbol != "" && o.Symbol != symbol {
+            remaining = append(remaining, o)
+            continue
+        }
+        c := types.CancelledOrder{OrderId: o.OrderId}
+        if o.CliOrdId != nil {
+            c.CliOrdId = *o.CliOrdId
+        }
+        cancelled = append(cancelled, c)
+        s.queueOrderGoneLocked(o, reason)
+        s.recordOrderLocked("OrderCancelled", o, nil, reason)
+    }
+    s.orders = remaining
+    return cancelled
+}
+
+
+// Fires switch once trigger time is reached, caller holds s.mu
+func (s *Server) checkDeadManLocked() {
+    if s.deadManAt.IsZero() || s.nowLocked().Before(s.deadManAt) {
+        return
+    }
+    s.deadManAt = time.Time{}
+    s.cancelAllLocked("", "dead_man_switch")
+}
+
+
+// Trigger time (zero when disarmed) and how many times it was (re)armed
+func (s *Server) DeadManSwitch() (time.Time, int) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.deadManAt, s.deadManArms
+}
+//}}} Cancel all
+
+
+//{{{ Handlers
+func (s *Server) handleEditOrder(w http.ResponseWriter, r *http.Request, body string) {
+    form, err := url.ParseQuery(body)
+    if err != nil {
+        writeError(w, http.StatusOK, "invalidArgument", s.serverTime())
+        return
+    }
+    orderId, cliOrdId := form.Get("orderId"), form.Get("cliOrdId")
+    if orderId == "" && cliOrdId == "" {
+        writeError(w, http.StatusOK, "requiredArgumentMissing", s.serverTime())
+        return
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    now := s.nowLocked().Format(TimeLayout)
+    status, o := s.editLocked(orderId, cliOrdId, optionalFloat(form, "size"), optionalFloat(form, "limitPrice"), optionalFloat(form, "stopPrice"))
+    editStatus := map[string]any{
+        "status":       status,
+        "receivedTime": now,
+        "orderId":      orderId,
+    }
+    if o != nil {
+        editStatus["orderId"] = o.OrderId
+    }
+    if cliOrdId != "" {
+        editStatus["cliOrdId"] = cliOrdId
+    }
+    writeJSON(w, http.StatusOK, map[string]any{
+        "result":       "success",
+        "serverTime":   now,
+        "editStatus":   editStatus,
+    })
+}
+
+
+func (s *Server) handleCancelOrder(w http.ResponseWriter, r *http.Request, body string) {
+    form, err := url.ParseQuery(body)
+    if err != nil {
+        writeError(w, http.StatusOK, "invalidArgument", s.serverTime())
+        return
+    }
+    orderId, cliOrdId := form.Get("order_id"), form.Get("cliOrdId")
+    if orderId == "" && cliOrdId == "" {
+        writeError(w, http.StatusOK, "requiredArgumentMissing", s.serverTime())
+        return
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    now := s.nowLocked().Format(TimeLayout)
+    if i := s.findOrderLocked(orderId, cliOrdId); i >= 0 {
+        orderId = s.orders[i].OrderId
+    }
+    cancelStatus := map[string]any{
+        "status":       s.cancelLocked(orderId, cliOrdId),
+        "receivedTime": now,
+        "order_id":     orderId,
+    }
+    if cliOrdId != "" {
+        cancelStatus["cliOrdId"] = cliOrdId
+    }
+    writeJSON(w, http.StatusOK, map[string]any{
+        "result":       "success",
+        "serverTime":   now,
+        "cancelStatus": cancelStatus,
+    })
+}
+
+
+func (s *Server) handleCancelAllOrders(w http.ResponseWriter, r *http.Request, body string) {
+    form, err := url.ParseQuery(body)
+    if err != nil {
+        writeError(w, http.StatusOK, "invalidArgument", s.serverTime())
+        return
+    }
+    symbol := form.Get("symbol")
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    now := s.nowLocked().Format(TimeLayout)
+    cancelled := s.cancelAllLocked(symbol, "cancelled_by_user")
+    status, cancelOnly := "cancelled", "all"
+    if len(cancelled) == 0 {
+        status = "noOrdersToCancel"
+    }
+    if symbol != "" {
+        cancelOnly = symbol
+    }
+    writeJSON(w, http.StatusOK, map[string]any{
+        "result":       "success",
+        "serverTime":   now,
+        "cancelStatus": map[string]any{
+            "status":           status,
+            "cancelOnly":       cancelOnly,
+            "receivedTime":     now,
+            "cancelledOrders":  cancelled,
+        },
+    })
+}
+
+
+// timeout in seconds, 0 disarms
+func (s *Server) handleCancelAllOrdersAfter(w http.ResponseWriter, r *http.Request, body string) {
+    form, err := url.ParseQuery(body)
+    if err != nil {
+        writeError(w, http.StatusOK, "invalidArgument", s.serverTime())
+        return
+    }
+    timeout, err := strconv.ParseInt(form.Get("timeout"), 10, 64)
+    if err != nil || timeout < 0 {
+        writeError(w, http.StatusOK, "invalidArgument", s.serverTime())
+        return
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    now := s.nowLocked()
+    status := map[string]any{"currentTime": now.Format(TimeLayout)}
+    if timeout == 0 {
+        s.deadManAt = time.Time{}
+    } else {
+        s.deadManAt = now.Add(time.Duration(timeout) * time.Second)
+        s.deadManArms++
+        status["triggerTime"] = s.deadManAt.Format(TimeLayout)
+    }
+    writeJSON(w, http.StatusOK, map[string]any{
+        "result":       "success",
+        "serverTime":   now.Format(TimeLayout),
+        "status":       status,
+    })
+}
+//}}} Handlers
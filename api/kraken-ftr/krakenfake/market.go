@@ -0,0 +1,335 @@
+package krakenfake
+
+import (
+    "math"
+    "net/http"
+    "sort"
+    "strconv"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+
+
+// Kraken caps /history at 100 trades per call
+const publicTradesLimit = 100
+
+
+var (
+    validTickTypes   = map[string]bool{"spot": true, "mark": true, "trade": true}
+    validResolutions = map[string]bool{
+        "1m": true, "5m": true, "15m": true, "30m": true,
+        "1h": true, "4h": true, "12h": true, "1d": true, "1w": true,
+    }
+)
+
+
+//{{{ Ticker
+// Also moves the market, resting orders and triggers get matched against new mark price
+func (s *Server) SetTicker(ticker types.Ticker) {
+    s.mu.Lock()
+    t := ticker
+    s.tickers[ticker.Symbol] = &t
+    s.matchLocked(ticker.Symbol)
+    s.mu.Unlock()
+    s.flushFeeds()
+}
+
+
+func (s *Server) handleTicker(w http.ResponseWriter, r *http.Request) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    serverTime := s.nowLocked().Format(TimeLayout)
+
+    ticker, ok := s.tickers[r.PathValue("symbol")]
+    if !ok {
+        writeError(w, http.StatusOK, "invalidArgument", serverTime)
+        return
+    }
+    writeJSON(w, http.StatusOK, map[string]any{
+        "result":       "success",
+        "serverTime":   serverTime,
+        "ticker":       ticker,
+    })
+}
+
+
+func (s *Server) handleTickers(w http.ResponseWriter, r *http.Request) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    tickers := make([]types.Ticker, 0, len(s.tickers))
+    for _, ticker := range s.tickers {
+        tickers = append(tickers, *ticker)
+    }
+    sort.Slice(tickers, func(i, j int) bool { return tickers[i].Symbol < tickers[j].Symbol })
+    writeJSON(w, http.StatusOK, types.TickersResponse{
+        Result:     "success",
+        ServerTime: s.nowLocked().Format(TimeLayout),
+        Tickers:    tickers,
+    })
+}
+//}}} Ticker
+
+
+//{{{ Instruments
+// When instrument is set, sendorder/batchorder also check tick size and size precision
+func (s *Server) SetInstruments(instruments ...types.Instrument) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for _, inst := range instruments {
+        s.instruments[inst.Symbol] = inst
+    }
+}
+
+
+func (s *Server) handleInstruments(w http.ResponseWriter, r *http.Request) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    instruments := make([]types.Instrument, 0, len(s.instruments))
+    for _, inst := range s.instruments {
+        instruments = append(instruments, inst)
+    }
+    sort.Slice(instruments, func(i, j int) bool { return instruments[i].Symbol < instruments[j].Symbol })
+    writeJSON(w, http.StatusOK, map[string]any{
+        "result":       "success",
+        "serverTime":   s.nowLocked().Format(TimeLayout),
+        "instruments":  instruments,
+    })
+}
+
+
+// True when value is whole multiple of step (with float tolerance)
+func onStep(value, step float64) bool {
+    if step <= 0 {
+        return true
+    }
+    n := value / step
+    return math.Abs(n-math.Round(n)) < 1e-6
+}
+//}}} Instruments
+
+
+//{{{ Instrument status
+// Instruments without override are tradeable, no dislocation, multiplier 1
+func (s *Server) SetInstrumentStatus(status types.InstrumentStatus) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.instrumentStatus[status.Tradeable] = status
+}
+
+
+// Caller holds s.mu
+func (s *Server) instrumentStatusLocked(symbol string) (types.InstrumentStatus, bool) {
+    if status, ok := s.instrumentStatus[symbol]; ok {
+        return status, true
+    }
+    if _, ok := s.instruments[symbol]; !ok {
+        return types.InstrumentStatus{}, false
+    }
+    return types.InstrumentStatus{Tradeable: symbol, ExtremeVolatilityInitialMarginMultiplier: 1}, true
+}
+
+
+func (s *Server) handleInstrumentStatusList(w http.ResponseWriter, r *http.Request) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    symbols := map[string]bool{}
+    for symbol := range s.instruments {
+        symbols[symbol] = true
+    }
+    for symbol := range s.instrumentStatus {
+        symbols[symbol] = true
+    }
+    list := make([]types.InstrumentStatus, 0, len(symbols))
+    for symbol := range symbols {
+        status, _ := s.instrumentStatusLocked(symbol)
+        list = append(list, status)
+    }
+    sort.Slice(list, func(i, j int) bool { return list[i].Tradeable < list[j].Tradeable })
+    writeJSON(w, http.StatusOK, types.InstrumentStatusListResponse{
+        Result:             "success",
+        ServerTime:         s.nowLocked().Format(TimeLayout),
+        InstrumentStatus:   list,
+    })
+}
+
+
+func (s *Server) handleInstrumentStatus(w http.ResponseWriter, r *http.Request) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    serverTime := s.nowLocked().Format(TimeLayout)
+    status, ok := s.instrumentStatusLocked(r.PathValue("symbol"))
+    if !ok {
+        writeError(w, http.StatusOK, "invalidArgument", serverTime)
+        return
+    }
+    writeJSON(w, http.StatusOK, types.InstrumentStatusResponse{Result: "success", ServerTime: serverTime, InstrumentStatus: status})
+}
+//}}} Instrument status
+
+
+//{{{ Public trades
+// Seeds /history, kept newest first; empty Time gets server time, TradeId 0 the next one
+func (s *Server) AddPublicTrades(symbol string, trades ...types.PublicTrade) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.addPublicTradesLocked(symbol, trades...)
+}
+
+
+// Caller holds s.mu
+func (s *Server) addPublicTradesLocked(symbol string, trades ...types.PublicTrade) {
+    list := s.publicTrades[symbol]
+    lastId := int64(0)
+    for _, trade := range list {
+        lastId = max(lastId, trade.TradeId)
+    }
+    for _, trade := range trades {
+        if trade.Time == "" {
+            trade.Time = s.nowLocked().Format(TimeLayout)
+        }
+        if trade.TradeId == 0 {
+            trade.TradeId = lastId + 1
+        }
+        lastId = max(lastId, trade.TradeId)
+        if trade.UID == "" {
+            trade.UID = s.newIdLocked()
+        }
+        list = append(list, trade)
+    }
+    sort.SliceStable(list, func(i, j int) bool { return tradeTime(list[i]).After(tradeTime(list[j])) })
+    s.publicTrades[symbol] = list
+}
+
+
+func tradeTime(trade types.PublicTrade) time.Time {
+    t, _ := time.Parse(time.RFC3339Nano, trade.Time)
+    return t
+}
+
+
+// 100 newest trades strictly before lastTime (none => now)
+func (s *Server) handlePublicTrades(w http.ResponseWriter, r *http.Request) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    serverTime := s.nowLocked().Format(TimeLayout)
+    symbol := r.URL.Query().Get("symbol")
+    var lastTime time.Time
+    if v := r.URL.Query().Get("lastTime"); v != "" {
+        t, err := time.Parse(time.RFC3339Nano, v)
+        if err != nil {
+            writeError(w, http.StatusOK, "invalidArgument", serverTime)
+            return
+        }
+        lastTime = t
+    }
+    if symbol == "" {
+        writeError(w, http.StatusOK, "invalidArgument", serverTime)
+        return
+    }
+
+    history := []types.PublicTrade{}
+    for _, trade := range s.publicTrades[symbol] {
+        if !lastTime.IsZero() && !tradeTime(trade).Before(lastTime) {
+            continue
+        }
+        if len(history) == publicTradesLimit {
+            break
+        }
+        history = append(history, trade)
+    }
+    writeJSON(w, http.StatusOK, types.PublicTradesResponse{Result: "success", ServerTime: serverTime, History: history})
+}
+//}}} Public trades
+
+
+//{{{ Funding rates
+// Replaces stored history, sorted oldest first like Kraken
+func (s *Server) SetFundingRates(symbol string, rates []types.HistoricalFundingRate) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    sorted := append([]types.HistoricalFundingRate(nil), rates...)
+    sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Timestamp < sorted[j].Timestamp })
+    s.fundingRates[symbol] = sorted
+}
+
+
+// Unknown symbol is empty list, not error
+func (s *Server) handleFundingRates(w http.ResponseWriter, r *http.Request) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    symbol := r.URL.Query().Get("symbol")
+    if symbol == "" {
+        writeError(w, http.StatusOK, "invalidArgument", s.nowLocked().Format(TimeLayout))
+        return
+    }
+    rates := append([]types.HistoricalFundingRate{}, s.fundingRates[symbol]...)
+    writeJSON(w, http.StatusOK, types.HistoricalFundingRatesResponse{Rates: rates})
+}
+//}}} Funding rates
+
+
+//{{{ Candles
+func candleKey(tickType, symbol, resolution string) string {
+    return tickType + "/" + symbol + "/" + resolution
+}
+
+
+// Replaces stored candles, time is unix ms same as Kraken
+func (s *Server) SetCandles(tickType, symbol, resolution string, candles []types.Candle) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    sorted := append([]types.Candle(nil), candles...)
+    sort.Slice(sorted, func(i, j int) bool { return sorted[i].Time < sorted[j].Time })
+    s.candles[candleKey(tickType, symbol, resolution)] = sorted
+}
+
+
+// `from`/`to` are unix seconds (inclusive), candle time is unix ms
+func (s *Server) handleCandles(w http.ResponseWriter, r *http.Request) {
+    tickType := r.PathValue("tickType")
+    resolution := r.PathValue("resolution")
+    if !validTickTypes[tickType] || !validResolutions[resolution] {
+        http.Error(w, "invalid tick type or resolution", http.StatusBadRequest)
+        return
+    }
+
+    var from, to int64 = 0, -1
+    if v := r.URL.Query().Get("from"); v != "" {
+        n, err := strconv.ParseInt(v, 10, 64)
+        if err != nil {
+            http.Error(w, "invalid from", http.StatusBadRequest)
+            return
+        }
+        from = n * 1000
+    }
+    if v := r.URL.Query().Get("to"); v != "" {
+        n, err := strconv.ParseInt(v, 10, 64)
+        if err != nil {
+            http.Error(w, "invalid to", http.StatusBadRequest)
+            return
+        }
+        to = n * 1000
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    selected := []types.Candle{}
+    more := false
+    for _, c := range s.candles[candleKey(tickType, r.PathValue("symbol"), resolution)] {
+        if c.Time < from || (to >= 0 && c.Time > to) {
+            continue
+        }
+        if len(selected) == s.candleLimit {
+            more = true
+            break
+        }
+        selected = append(selected, c)
+    }
+    writeJSON(w, http.StatusOK, types.CandleResponse{
+        Candles:        selected,
+        MoreCandles:    more,
+    })
+}
+//}}} Candles
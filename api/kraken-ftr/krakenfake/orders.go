@@ -0,0 +1,594 @@
+package krakenfake
+
+import (
+    "encoding/json"
+    "math"
+    "net/http"
+    "net/url"
+    "slices"
+    "strconv"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+// Very small matching engine, there is no real book, everything is matched
+// against ticker mark price:
+//  - mkt fills right away at mark (taker)
+//  - lmt fills right away at mark if it crosses (taker), otherwise rests
+//  - post rests, or gets rejected with `postWouldExecute` if it crosses
+//  - stp/take_profit rest until mark crosses stopPrice, then become lmt (or mkt when limitPrice is 0)
+//  - resting lmt fills at its limitPrice (maker) once mark crosses it
+
+
+//{{{ Order params
+// Common shape for sendorder (form) and batchorder (json) input
+type orderParams struct {
+    Symbol          string
+    OrderType       string
+    Side            string
+    Size            float64
+    LimitPrice      float64
+    StopPrice       *float64
+    TriggerSignal   *string
+    ReduceOnly      bool
+    CliOrdId        string
+}
+
+
+func paramsFromForm(form url.Values) orderParams {
+    p := orderParams{
+        Symbol:     form.Get("symbol"),
+        OrderType:  form.Get("orderType"),
+        Side:       form.Get("side"),
+        CliOrdId:   form.Get("cliOrdId"),
+        ReduceOnly: form.Get("reduceOnly") == "true",
+    }
+    p.Size, _ = strconv.ParseFloat(form.Get("size"), 64)
+    p.LimitPrice, _ = strconv.ParseFloat(form.Get("limitPrice"), 64)
+    if v := form.Get("stopPrice"); v != "" {
+        if f, err := strconv.ParseFloat(v, 64); err == nil {
+            p.StopPrice = &f
+        }
+    }
+    if v := form.Get("triggerSignal"); v != "" {
+        p.TriggerSignal = &v
+    }
+    return p
+}
+
+
+// Numbers can arrive as json numbers or strings
+func numberFromAny(v any) (float64, bool) {
+    switch n := v.(type) {
+    case float64:
+        return n, true
+    case string:
+        f, err := strconv.ParseFloat(n, 64)
+        return f, err == nil
+    }
+    return 0, false
+}
+
+
+func stringFromAny(v any) string {
+    s, _ := v.(string)
+    return s
+}
+
+
+func paramsFromMap(m map[string]any) orderParams {
+    p := orderParams{
+        Symbol:     stringFromAny(m["symbol"]),
+        OrderType:  stringFromAny(m["orderType"]),
+        Side:       stringFromAny(m["side"]),
+        CliOrdId:   stringFromAny(m["cliOrdId"]),
+    }
+    p.Size, _ = numberFromAny(m["size"])
+    p.LimitPrice, _ = numberFromAny(m["limitPrice"])
+    if f, ok := numberFromAny(m["stopPrice"]); ok {
+        p.StopPrice = &f
+    }
+    if s := stringFromAny(m["triggerSignal"]); s != "" {
+        p.TriggerSignal = &s
+    }
+    if b, ok := m["reduceOnly"].(bool); ok {
+        p.ReduceOnly = b
+    }
+    return p
+}
+//}}} Order params
+
+
+//{{{ Place
+func isTrigger(orderType string) bool {
+    return orderType == "stp" || orderType == "take_profit"
+}
+
+
+// Returns Kraken sendStatus.status and order id (empty when rejected), caller holds s.mu
+func (s *Server) placeLocked(p orderParams) (string, string) {
+    switch p.OrderType {
+    case "lmt", "post", "mkt", "stp", "take_profit":
+    default:
+        return "invalidOrderType", ""
+    }
+    if p.Side != "buy" && p.Side != "sell" {
+        return "invalidSide", ""
+    }
+    if p.Size <= 0 {
+        return "invalidSize", ""
+    }
+    if (p.OrderType == "lmt" || p.OrderType == "post") && p.LimitPrice <= 0 {
+        return "invalidPrice", ""
+    }
+    if isTrigger(p.OrderType) && (p.StopPrice == nil || *p.StopPrice <= 0) {
+        return "invalidPrice", ""
+    }
+    if len(p.CliOrdId) > 100 {
+        return "clientOrderIdTooLong", ""
+    }
+    if p.CliOrdId != "" && s.findOrderLocked("", p.CliOrdId) >= 0 {
+        return "clientOrderIdAlreadyExist", ""
+    }
+    if inst, ok := s.instruments[p.Symbol]; ok {
+        if !onStep(p.Size, inst.MinOrderSize()) {
+            return "invalidSize", ""
+        }
+        if !onStep(p.LimitPrice, inst.TickSize) || (p.StopPrice != nil && !onStep(*p.StopPrice, inst.TickSize)) {
+            return "invalidPrice", ""
+        }
+    }
+    ticker, ok := s.tickers[p.Symbol]
+    if !ok {
+        return "marketInactive", ""
+    }
+    if ticker.Suspended {
+        return "marketSuspended", ""
+    }
+    if p.ReduceOnly && !s.reducesLocked(p.Symbol, p.Side) {
+        return "wouldNotReducePosition", ""
+    }
+
+    mark := ticker.MarkPrice
+    crosses := (p.Side == "buy" && p.LimitPrice >= mark) || (p.Side == "sell" && p.LimitPrice <= mark)
+    if p.OrderType == "post" && crosses {
+        return "postWouldExecute", ""
+    }
+
+    orderId := s.newIdLocked()
+    switch {
+    case p.OrderType == "mkt", p.OrderType == "lmt" && crosses:
+        var cliOrdId *string
+        if p.CliOrdId != "" {
+            cliOrdId = &p.CliOrdId
+        }
+        s.fillLocked(orderId, cliOrdId, p.Symbol, p.Side, p.Size, mark, "taker")
+    default:
+        s.restLocked(orderId, p)
+        // Trigger that is already through the market fires right away
+        s.matchLocked(p.Symbol)
+    }
+    return "placed", orderId
+}
+
+
+func (s *Server) restLocked(orderId string, p orderParams) {
+    now := s.nowLocked().Format(TimeLayout)
+    order := &types.OpenOrder{
+        OrderId:        orderId,
+        Symbol:         p.Symbol,
+        Side:           p.Side,
+        OrderType:      p.OrderType,
+        LimitPrice:     p.LimitPrice,
+        UnfilledSize:   p.Size,
+        Status:         "untouched",
+        ReduceOnly:     p.ReduceOnly,
+        ReceivedTime:   now,
+        LastUpdateTime: now,
+        StopPrice:      p.StopPrice,
+        TriggerSignal:  p.TriggerSignal,
+    }
+    if p.CliOrdId != "" {
+        cliOrdId := p.CliOrdId
+        order.CliOrdId = &cliOrdId
+    }
+    s.orders = append(s.orders, order)
+    s.queueOrderLocked(order, "new_placed_order_by_user")
+    s.recordOrderLocked("OrderPlaced", order, nil, "new_user_order")
+}
+
+
+// Index of open order by id or cliOrdId, -1 if not found; caller holds s.mu
+func (s *Server) findOrderLocked(orderId, cliOrdId string) int {
+    for i, o := range s.orders {
+        if orderId != "" && o.OrderId == orderId {
+            return i
+        }
+        if cliOrdId != "" && o.CliOrdId != nil && *o.CliOrdId == cliOrdId {
+            return i
+        }
+    }
+    return -1
+}
+
+
+func (s *Server) cancelLocked(orderId, cliOrdId string) string {
+    i := s.findOrderLocked(orderId, cliOrdId)
+    if i < 0 {
+        return "notFound"
+    }
+    s.queueOrderGoneLocked(s.orders[i], "cancelled_by_user")
+    s.recordOrderLocked("OrderCancelled", s.orders[i], nil, "cancelled_by_user")
+    s.closeLocked(statusOrder(s.orders[i]), "CANCELLED")
+    s.orders = append(s.orders[:i], s.orders[i+1:]...)
+    return "cancelled"
+}
+//}}} Place
+
+
+//{{{ Match
+// Re-evaluates resting orders for symbol after mark price moved, caller holds s.mu
+func (s *Server) matchLocked(symbol string) {
+    ticker, ok := s.tickers[symbol]
+    if !ok {
+        return
+    }
+    mark := ticker.MarkPrice
+
+    remaining := s.orders[:0]
+    for _, o := range s.orders {
+        if o.Symbol != symbol {
+            remaining = append(remaining, o)
+            continue
+        }
+        if isTrigger(o.OrderType) {
+            stop := *o.StopPrice
+            triggered := false
+            switch {
+            case o.OrderType == "stp" && o.Side == "buy":
+                triggered = mark >= stop
+            case o.OrderType == "stp" && o.Side == "sell":
+                triggered = mark <= stop
+            case o.OrderType == "take_profit" && o.Side == "buy":
+                triggered = mark <= stop
+            case o.OrderType == "take_profit" && o.Side == "sell":
+                triggered = mark >= stop
+            }
+            if !triggered {
+                remaining = append(remaining, o)
+                continue
+            }
+            if o.LimitPrice <= 0 {
+                s.recordOrderLocked("OrderActivated", o, nil, "trigger_activated")
+                s.fillLocked(o.OrderId, o.CliOrdId, o.Symbol, o.Side, o.UnfilledSize, mark, "taker")
+                s.queueOrderGoneLocked(o, "full_fill")
+                continue
+            }
+            s.recordOrderLocked("OrderActivated", o, nil, "trigger_activated")
+            // Triggered stop-limit turns into plain limit order
+            o.OrderType = "lmt"
+            o.StopPrice = nil
+            o.TriggerSignal = nil
+            o.LastUpdateTime = s.nowLocked().Format(TimeLayout)
+        }
+
+        crosses := (o.Side == "buy" && o.LimitPrice >= mark) || (o.Side == "sell" && o.LimitPrice <= mark)
+        if !crosses {
+            remaining = append(remaining, o)
+            continue
+        }
+        s.fillLocked(o.OrderId, o.CliOrdId, o.Symbol, o.Side, o.UnfilledSize, o.LimitPrice, "maker")
+        s.queueOrderGoneLocked(o, "full_fill")
+    }
+    s.orders = remaining
+}
+
+
+func (s *Server) fillLocked(orderId string, cliOrdId *string, symbol, side string, size, price float64, fillType string) {
+    now := s.nowLocked()
+    fill := types.Fill{
+        FillId:     s.newIdLocked(),
+        Symbol:     symbol,
+        Side:       side,
+        OrderId:    orderId,
+        CliOrdId:   cliOrdId,
+        Size:       size,
+        Price:      price,
+        FillTime:   now.Format(TimeLayout),
+        FillType:   fillType,
+    }
+    s.fills = append(s.fills, fill)
+    s.updatePositionLocked(symbol, side, size, price, now.Format(TimeLayout))
+    s.queueFillLocked(fill)
+    s.recordFillLocked(fill)
+    s.closeLocked(types.OrderStatusOrder{
+        Type:       "ORDER",
+        OrderId:    orderId,
+        CliOrdId:   cliOrdId,
+        Symbol:     symbol,
+        Side:       side,
+        Quantity:   size,
+        Filled:     size,
+        LimitPrice: price,
+        Timestamp:  fill.FillTime,
+        LastUpdateTimestamp: fill.FillTime,
+    }, "FULLY_EXECUTED")
+}
+//}}} Match
+
+
+//{{{ Position
+func signedSize(p *types.OpenPosition) float64 {
+    if p == nil {
+        return 0
+    }
+    if p.Side == "short" {
+        return -p.Size
+    }
+    return p.Size
+}
+
+
+func (s *Server) reducesLocked(symbol, side string) bool {
+    current := signedSize(s.positions[symbol])
+    return (side == "buy" && current < 0) || (side == "sell" && current > 0)
+}
+
+
+func (s *Server) updatePositionLocked(symbol, side string, size, price float64, fillTime string) {
+    pos := s.positions[symbol]
+    current := signedSize(pos)
+    delta := size
+    if side == "sell" {
+        delta = -size
+    }
+    next := current + delta
+    if math.Abs(next) < 1e-12 {
+        delete(s.positions, symbol)
+        return
+    }
+
+    entry := price
+    switch {
+    case current == 0:
+    case (current > 0) == (delta > 0):
+        // Increase, weighted average entry
+        entry = (math.Abs(current)*pos.Price + size*price) / math.Abs(next)
+    case (current > 0) == (next > 0):
+        // Reduce, entry stays
+        entry = pos.Price
+    }
+
+    positionSide := "long"
+    if next < 0 {
+        positionSide = "short"
+    }
+    s.positions[symbol] = &types.OpenPosition{
+        Side:       positionSide,
+        Symbol:     symbol,
+        Price:      entry,
+        FillTime:   fillTime,
+        Size:       math.Abs(next),
+    }
+}
+//}}} Position
+
+
+//{{{ Order status
+// Closed orders stay on orders/status this long
+const closedOrderWindow = 5 * time.Second
+
+
+type closedOrder struct {
+    info        types.OrderStatusInfo
+    closedAt    time.Time
+}
+
+
+func statusOrder(o *types.OpenOrder) types.OrderStatusOrder {
+    orderType := "ORDER"
+    if isTrigger(o.OrderType) {
+        orderType = "TRIGGER_ORDER"
+    }
+    return types.OrderStatusOrder{
+        Type:       orderType,
+        OrderId:    o.OrderId,
+        CliOrdId:   o.CliOrdId,
+        Symbol:     o.Symbol,
+        Side:       o.Side,
+        Quantity:   o.FilledSize + o.UnfilledSize,
+        Filled:     o.FilledSize,
+        LimitPrice: o.LimitPrice,
+        ReduceOnly: o.ReduceOnly,
+        Timestamp:  o.ReceivedTime,
+        LastUpdateTimestamp: o.LastUpdateTime,
+    }
+}
+
+
+// Caller holds s.mu
+func (s *Server) closeLocked(order types.OrderStatusOrder, status string) {
+    s.closed = append(s.closed, closedOrder{
+        info:       types.OrderStatusInfo{Order: order, Status: status},
+        closedAt:   s.nowLocked(),
+    })
+}
+
+
+// Open orders and ones closed within closedOrderWindow that match any of
+// orderIds/cliOrdIds, caller holds s.mu
+func (s *Server) orderStatusLocked(orderIds, cliOrdIds []string) []types.OrderStatusInfo {
+    matches := func(o types.OrderStatusOrder) bool {
+        return slices.Contains(orderIds, o.OrderId) || (o.CliOrdId != nil && slices.Contains(cliOrdIds, *o.CliOrdId))
+    }
+    result := []types.OrderStatusInfo{}
+    for _, o := range s.orders {
+        info := types.OrderStatusInfo{Order: statusOrder(o), Status: "ENTERED_BOOK"}
+        if isTrigger(o.OrderType) {
+            info.Status = "TRIGGER_PLACED"
+        }
+        if matches(info.Order) {
+            result = append(result, info)
+        }
+    }
+    cutoff := s.nowLocked().Add(-closedOrderWindow)
+    for _, c := range s.closed {
+        if c.closedAt.After(cutoff) && matches(c.info.Order) {
+            result = append(result, c.info)
+        }
+    }
+    return result
+}
+//}}} Order status
+
+
+//{{{ Handlers
+func (s *Server) handleOpenOrders(w http.ResponseWriter, r *http.Request, body string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    orders := make([]types.OpenOrder, 0, len(s.orders))
+    for _, o := range s.orders {
+        orders = append(orders, *o)
+    }
+    writeJSON(w, http.StatusOK, map[string]any{
+        "result":       "success",
+        "serverTime":   s.nowLocked().Format(TimeLayout),
+        "openOrders":   orders,
+    })
+}
+
+
+// body: orderIds=...&cliOrdIds=..., both repeatable
+func (s *Server) handleOrderStatus(w http.ResponseWriter, r *http.Request, body string) {
+    form, err := url.ParseQuery(body)
+    if err != nil {
+        writeError(w, http.StatusOK, "invalidArgument", s.serverTime())
+        return
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    writeJSON(w, http.StatusOK, map[string]any{
+        "result":       "success",
+        "serverTime":   s.nowLocked().Format(TimeLayout),
+        "orders":       s.orderStatusLocked(form["orderIds"], form["cliOrdIds"]),
+    })
+}
+
+
+func (s *Server) handleSendOrder(w http.ResponseWriter, r *http.Request, body string) {
+    form, err := url.ParseQuery(body)
+    if err != nil {
+        writeError(w, http.StatusOK, "invalidArgument", s.serverTime())
+        return
+    }
+    p := paramsFromForm(form)
+    if p.Symbol == "" || p.OrderType == "" || p.Side == "" || form.Get("size") == "" {
+        writeError(w, http.StatusOK, "requiredArgumentMissing", s.serverTime())
+        return
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    now := s.nowLocked().Format(TimeLayout)
+    status, orderId := s.placeLocked(p)
+    if orderId == "" && p.CliOrdId != "" {
+        s.closeLocked(types.OrderStatusOrder{
+            Type:       "ORDER",
+            OrderId:    s.newIdLocked(),
+            CliOrdId:   &p.CliOrdId,
+            Symbol:     p.Symbol,
+            Side:       p.Side,
+            Quantity:   p.Size,
+            LimitPrice: p.LimitPrice,
+            ReduceOnly: p.ReduceOnly,
+            Timestamp:  now,
+            LastUpdateTimestamp: now,
+        }, "REJECTED")
+    }
+    sendStatus := map[string]any{
+        "status":       status,
+        "receivedTime": now,
+    }
+    if orderId != "" {
+        sendStatus["order_id"] = orderId
+    }
+    if p.CliOrdId != "" {
+        sendStatus["cliOrdId"] = p.CliOrdId
+    }
+    writeJSON(w, http.StatusOK, map[string]any{
+        "result":       "success",
+        "serverTime":   now,
+        "sendStatus":   sendStatus,
+    })
+}
+
+
+// body: json={"batchOrder":[{"order":"send", ...}, {"order":"cancel", "order_id": ...}]}
+func (s *Server) handleBatchOrder(w http.ResponseWriter, r *http.Request, body string) {
+    form, err := url.ParseQuery(body)
+    if err != nil {
+        writeError(w, http.StatusOK, "invalidArgument", s.serverTime())
+        return
+    }
+    var batch struct {
+        BatchOrder []map[string]any `json:"batchOrder"`
+    }
+    if err := json.Unmarshal([]byte(form.Get("json")), &batch); err != nil {
+        writeError(w, http.StatusOK, "invalidArgument", s.serverTime())
+        return
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    now := s.nowLocked().Format(TimeLayout)
+    statuses := []map[string]any{}
+    for _, instruction := range batch.BatchOrder {
+        element := map[string]any{}
+        switch stringFromAny(instruction["order"]) {
+        case "send":
+            status, orderId := s.placeLocked(paramsFromMap(instruction))
+            element["status"] = status
+            element["order_tag"] = stringFromAny(instruction["order_tag"])
+            element["dateTimeReceived"] = now
+            if orderId != "" {
+                element["order_id"] = orderId
+            }
+        case "edit":
+            orderId := stringFromAny(instruction["order_id"])
+            cliOrdId := stringFromAny(instruction["cliOrdId"])
+            status, o := s.editLocked(orderId, cliOrdId, floatFromMap(instruction, "size"), floatFromMap(instruction, "limitPrice"), floatFromMap(instruction, "stopPrice"))
+            element["status"] = status
+            element["order_id"] = orderId
+            if o != nil {
+                element["order_id"] = o.OrderId
+            }
+            if cliOrdId != "" {
+                element["cliOrdId"] = cliOrdId
+            }
+        case "cancel":
+            orderId := stringFromAny(instruction["order_id"])
+            cliOrdId := stringFromAny(instruction["cliOrdId"])
+            if i := s.findOrderLocked(orderId, cliOrdId); i >= 0 {
+                orderId = s.orders[i].OrderId
+            }
+            element["status"] = s.cancelLocked(orderId, cliOrdId)
+            element["order_id"] = orderId
+            if cliOrdId != "" {
+                element["cliOrdId"] = cliOrdId
+            }
+        default:
+            element["status"] = "invalidArgument"
+        }
+        statuses = append(statuses, element)
+    }
+    if s.reverseBatch {
+        slices.Reverse(statuses)
+    }
+    writeJSON(w, http.StatusOK, map[string]any{
+        "result":       "success",
+        "serverTime":   now,
+        "batchStatus":  statuses,
+    })
+}
+//}}} Handlers
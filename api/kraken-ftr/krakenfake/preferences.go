@@ -0,0 +1,106 @@
+package krakenfake
+
+import (
+    "net/http"
+    "sort"
+    "strconv"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+// leveragepreferences and pnlpreferences, PUT takes parameters in query.
+// Preferences show up on open positions (maxFixedLeverage, pnlCurrency).
+
+
+//{{{ Seed/inspect
+// Current leverage preferences by symbol, missing => cross margin
+func (s *Server) LeveragePreferences() map[string]float64 {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    out := map[string]float64{}
+    for symbol, leverage := range s.leverage {
+        out[symbol] = leverage
+    }
+    return out
+}
+//}}} Seed/inspect
+
+
+//{{{ Handlers
+func (s *Server) handleGetLeveragePreferences(w http.ResponseWriter, r *http.Request, body string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    preferences := []types.LeveragePreference{}
+    for symbol, leverage := range s.leverage {
+        preferences = append(preferences, types.LeveragePreference{Symbol: symbol, MaxLeverage: leverage})
+    }
+    sort.Slice(preferences, func(i, j int) bool { return preferences[i].Symbol < preferences[j].Symbol })
+    writeJSON(w, http.StatusOK, map[string]any{
+        "result":               "success",
+        "serverTime":           s.nowLocked().Format(TimeLayout),
+        "leveragePreferences":  preferences,
+    })
+}
+
+
+// Without maxLeverage preference is removed, known instruments cap leverage
+func (s *Server) handleSetLeveragePreference(w http.ResponseWriter, r *http.Request, body string) {
+    q := r.URL.Query()
+    symbol := q.Get("symbol")
+    if symbol == "" {
+        writeError(w, http.StatusOK, "requiredArgumentMissing", s.serverTime())
+        return
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    now := s.nowLocked().Format(TimeLayout)
+    if q.Get("maxLeverage") == "" {
+        delete(s.leverage, symbol)
+        writeJSON(w, http.StatusOK, map[string]any{"result": "success", "serverTime": now})
+        return
+    }
+    leverage, err := strconv.ParseFloat(q.Get("maxLeverage"), 64)
+    if err != nil || leverage <= 0 {
+        writeError(w, http.StatusOK, "invalidArgument", now)
+        return
+    }
+    if inst, ok := s.instruments[symbol]; ok && inst.MaxLeverage() > 0 && leverage > inst.MaxLeverage() {
+        writeError(w, http.StatusOK, "invalidArgument", now)
+        return
+    }
+    s.leverage[symbol] = leverage
+    writeJSON(w, http.StatusOK, map[string]any{"result": "success", "serverTime": now})
+}
+
+
+func (s *Server) handleGetPnlPreferences(w http.ResponseWriter, r *http.Request, body string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    preferences := []types.PnlPreference{}
+    for symbol, currency := range s.pnlCurrency {
+        preferences = append(preferences, types.PnlPreference{Symbol: symbol, PnlCurrency: currency})
+    }
+    sort.Slice(preferences, func(i, j int) bool { return preferences[i].Symbol < preferences[j].Symbol })
+    writeJSON(w, http.StatusOK, map[string]any{
+        "result":       "success",
+        "serverTime":   s.nowLocked().Format(TimeLayout),
+        "preferences":  preferences,
+    })
+}
+
+
+func (s *Server) handleSetPnlPreference(w http.ResponseWriter, r *http.Request, body string) {
+    q := r.URL.Query()
+    symbol, currency := q.Get("symbol"), q.Get("pnlPreference")
+    if symbol == "" || currency == "" {
+        writeError(w, http.StatusOK, "requiredArgumentMissing", s.serverTime())
+        return
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.pnlCurrency[symbol] = currency
+    writeJSON(w, http.StatusOK, map[string]any{"result": "success", "serverTime": s.nowLocked().Format(TimeLayout)})
+}
+//}}} Handlers
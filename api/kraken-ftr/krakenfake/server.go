@@ -0,0 +1,351 @@
+package krakenfake
+
+import (
+    "crypto/sha256"
+    "crypto/sha512"
+    "crypto/hmac"
+    "encoding/base64"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+// In-process stand-in for the Kraken Futures REST API (and WebSocket feeds), only covers the
+// endpoints krakenftr talks to. Private endpoints verify `Authent` the same
+// way Kraken does so a broken signRequestFn fails here too, not only on demo.
+// State (orders, fills, positions, tickers, candles) lives in memory and is
+// seeded through the Set*/Add* helpers.
+
+
+// Kraken style timestamps, ex.: "2025-09-23T16:55:10.557Z"
+const TimeLayout = "2006-01-02T15:04:05.000Z"
+
+
+type Server struct {
+    *httptest.Server
+    PublicKey   string
+    PrivateKey  string  // base64, same format Kraken hands out
+
+    mu          sync.Mutex
+    now         func() time.Time
+    strictNonce bool
+    lastNonce   int64
+    nextId      int
+    tickers     map[string]*types.Ticker
+    instruments map[string]types.Instrument
+    instrumentStatus map[string]types.InstrumentStatus  // only overrides, see market.go
+    publicTrades map[string][]types.PublicTrade     // /history, newest first
+    fundingRates map[string][]types.HistoricalFundingRate  // oldest first
+    orders      []*types.OpenOrder                  // open orders, oldest first
+    fills       []types.Fill                        // oldest first
+    closed      []closedOrder                       // see orders/status
+    positions   map[string]*types.OpenPosition
+    candles     map[string][]types.Candle           // key: tickType/symbol/resolution
+    candleLimit int
+    reverseBatch bool                               // batchStatus in reverse order
+    failures    []failure                           // served before normal handling, FIFO
+    // WebSocket, see feeds.go
+    feedConns   map[*feedConn]bool
+    books       map[string]*fakeBook
+    trades      map[string][]types.TradeEvent       // newest first
+    feedSeq     map[string]int64                    // key: feed|product
+    feedQueue   []queuedMsg                         // private feed pushes, see feeds_private.go
+    deadManAt   time.Time                           // zero => disarmed, see lifecycle.go
+    deadManArms int
+    flushMu     sync.Mutex
+    flex        types.FlexFuturesBalance
+    accounts    types.Accounts                      // served on /accounts as is
+    leverage    map[string]float64                  // leverage preferences, see preferences.go
+    pnlCurrency map[string]string
+    transfers   []types.SubaccountTransferRequest   // see transfers.go
+    history     map[string][]types.HistoryElement   // key: orders, triggers, executions; see history.go
+    accountLog  []types.AccountLogEntry             // id order
+    accountLogId int64
+}
+
+
+// Canned response for failure injection
+type failure struct {
+    status  int
+    body    string
+    handled bool    // request is processed first, only response gets replaced
+    skip    int     // requests passed through before this one fails
+}
+
+
+//{{{ New
+// Starts the server right away, caller has to Close() it
+func New(publicKey, privateKey string) *Server {
+    s := &Server{
+        PublicKey:      publicKey,
+        PrivateKey:     privateKey,
+        now:            time.Now,
+        tickers:        map[string]*types.Ticker{},
+        instruments:    map[string]types.Instrument{},
+        instrumentStatus: map[string]types.InstrumentStatus{},
+        publicTrades:   map[string][]types.PublicTrade{},
+        fundingRates:   map[string][]types.HistoricalFundingRate{},
+        positions:      map[string]*types.OpenPosition{},
+        candles:        map[string][]types.Candle{},
+        candleLimit:    2000,
+        feedConns:      map[*feedConn]bool{},
+        books:          map[string]*fakeBook{},
+        trades:         map[string][]types.TradeEvent{},
+        feedSeq:        map[string]int64{},
+        history:        map[string][]types.HistoryElement{},
+        leverage:       map[string]float64{},
+        pnlCurrency:    map[string]string{},
+    }
+
+    mux := http.NewServeMux()
+    // public
+    mux.HandleFunc("GET /derivatives/api/v3/tickers", s.handleTickers)
+    mux.HandleFunc("GET /derivatives/api/v3/tickers/{symbol}", s.handleTicker)
+    mux.HandleFunc("GET /derivatives/api/v3/instruments", s.handleInstruments)
+    mux.HandleFunc("GET /derivatives/api/v3/instruments/status", s.handleInstrumentStatusList)
+    mux.HandleFunc("GET /derivatives/api/v3/instruments/{symbol}/status", s.handleInstrumentStatus)
+    mux.HandleFunc("GET /derivatives/api/v3/history", s.handlePublicTrades)
+    mux.HandleFunc("GET /derivatives/api/v4/historicalfundingrates", s.handleFundingRates)
+    mux.HandleFunc("GET /api/charts/v1/{tickType}/{symbol}/{resolution}", s.handleCandles)
+    mux.HandleFunc("GET /ws/v1", s.handleFeed)
+    // private
+    mux.HandleFunc("GET /derivatives/api/v3/accounts", s.private(s.handleAccounts))
+    mux.HandleFunc("GET /derivatives/api/v3/openpositions", s.private(s.handleOpenPositions))
+    mux.HandleFunc("GET /derivatives/api/v3/openorders", s.private(s.handleOpenOrders))
+    mux.HandleFunc("GET /derivatives/api/v3/fills", s.private(s.handleFills))
+    mux.HandleFunc("POST /derivatives/api/v3/orders/status", s.private(s.handleOrderStatus))
+    mux.HandleFunc("POST /derivatives/api/v3/sendorder", s.private(s.handleSendOrder))
+    mux.HandleFunc("POST /derivatives/api/v3/batchorder", s.private(s.handleBatchOrder))
+    mux.HandleFunc("POST /derivatives/api/v3/editorder", s.private(s.handleEditOrder))
+    mux.HandleFunc("POST /derivatives/api/v3/cancelorder", s.private(s.handleCancelOrder))
+    mux.HandleFunc("POST /derivatives/api/v3/cancelallorders", s.private(s.handleCancelAllOrders))
+    mux.HandleFunc("POST /derivatives/api/v3/cancelallordersafter", s.private(s.handleCancelAllOrdersAfter))
+    mux.HandleFunc("GET /derivatives/api/v3/leveragepreferences", s.private(s.handleGetLeveragePreferences))
+    mux.HandleFunc("PUT /derivatives/api/v3/leveragepreferences", s.private(s.handleSetLeveragePreference))
+    mux.HandleFunc("GET /derivatives/api/v3/pnlpreferences", s.private(s.handleGetPnlPreferences))
+    mux.HandleFunc("PUT /derivatives/api/v3/pnlpreferences", s.private(s.handleSetPnlPreference))
+    mux.HandleFunc("POST /derivatives/api/v3/transfer", s.private(s.handleTransfer))
+    mux.HandleFunc("POST /derivatives/api/v3/transfer/subaccount", s.private(s.handleSubaccountTransfer))
+    mux.HandleFunc("GET /api/history/v2/orders", s.private(s.handleHistory("orders")))
+    mux.HandleFunc("GET /api/history/v2/triggers", s.private(s.handleHistory("triggers")))
+    mux.HandleFunc("GET /api/history/v2/executions", s.private(s.handleHistory("executions")))
+    mux.HandleFunc("GET /api/history/v3/account-log", s.private(s.handleAccountLog))
+
+    s.Server = httptest.NewServer(s.withFailures(mux))
+    return s
+}
+//}}} New
+
+
+//{{{ Failure injection
+// Next request (any endpoint) gets this status + raw body instead of normal handling,
+// ex.: QueueFailure(502, "<html>bad gateway</html>") or QueueFailure(200, `{"result":"error","error":"apiLimitExceeded"}`)
+func (s *Server) QueueFailure(status int, body string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.failures = append(s.failures, failure{status: status, body: body})
+}
+
+
+// Like QueueFailure but request is handled normally first (order lands),
+// only the response is replaced, ex.: gateway timing out after matching engine accepted order
+func (s *Server) QueueLostResponse(status int, body string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.failures = append(s.failures, failure{status: status, body: body, handled: true})
+}
+
+
+// Like QueueFailure but `skip` requests are handled normally before it,
+// ex.: second chunk of split batch failing
+func (s *Server) QueueFailureAfter(skip, status int, body string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.failures = append(s.failures, failure{status: status, body: body, skip: skip})
+}
+
+
+func (s *Server) withFailures(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        s.mu.Lock()
+        if len(s.failures) == 0 || s.failures[0].skip > 0 {
+            if len(s.failures) > 0 {
+                s.failures[0].skip--
+            }
+            s.mu.Unlock()
+            next.ServeHTTP(w, r)
+            return
+        }
+        f := s.failures[0]
+        s.failures = s.failures[1:]
+        s.mu.Unlock()
+
+        if f.handled {
+            next.ServeHTTP(httptest.NewRecorder(), r)
+        }
+        w.WriteHeader(f.status)
+        io.WriteString(w, f.body)
+    })
+}
+//}}} Failure injection
+
+
+//{{{ Knobs
+// Fixed clock for deterministic fill/order times
+func (s *Server) SetClock(now func() time.Time) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.now = now
+}
+
+
+// Reject nonce that is not strictly larger than previous one (like Kraken does)
+// off by default so several Exchange-s (own nonce source each) can share one fake
+func (s *Server) SetStrictNonce(strict bool) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.strictNonce = strict
+}
+
+
+// batchStatus comes back reversed, Kraken does not promise it follows
+// instruction order; clients must match by order_tag/order_id
+func (s *Server) SetBatchStatusReversed(reverse bool) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.reverseBatch = reverse
+}
+
+
+// Max candles returned per charts call before `more_candles` is set
+func (s *Server) SetCandleLimit(limit int) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.candleLimit = limit
+}
+//}}} Knobs
+
+
+//{{{ Auth
+// Same algorithm as krakenftr.signRequestFn, written again on purpose
+func (s *Server) expectedSignature(path, data, nonce string) (string, error) {
+    message := sha256.New()
+    message.Write([]byte(data + nonce + strings.TrimPrefix(path, "/derivatives")))
+    digest := message.Sum(nil)
+
+    key, err := base64.StdEncoding.DecodeString(s.PrivateKey)
+    if err != nil {
+        return "", err
+    }
+    hmacHash := hmac.New(sha512.New, key)
+    hmacHash.Write(digest)
+    return base64.StdEncoding.EncodeToString(hmacHash.Sum(nil)), nil
+}
+
+
+// Wraps handler with APIKey/Nonce/Authent checks, body is handed over as
+// string since it is part of signature and can't be read twice
+func (s *Server) private(next func(w http.ResponseWriter, r *http.Request, body string)) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        bodyBytes, err := io.ReadAll(r.Body)
+        if err != nil {
+            writeError(w, http.StatusBadRequest, "invalidArgument", s.serverTime())
+            return
+        }
+        body := string(bodyBytes)
+
+        apiKey := r.Header.Get("APIKey")
+        nonce := r.Header.Get("Nonce")
+        authent := r.Header.Get("Authent")
+        if apiKey == "" || authent == "" {
+            writeError(w, http.StatusOK, "requiredArgumentMissing", s.serverTime())
+            return
+        }
+        if apiKey != s.PublicKey {
+            writeError(w, http.StatusOK, "authenticationError", s.serverTime())
+            return
+        }
+
+        // GET signs query (without `?`), POST signs body
+        data := r.URL.RawQuery
+        if r.Method == http.MethodPost {
+            data = body
+        }
+        expected, err := s.expectedSignature(r.URL.Path, data, nonce)
+        if err != nil || !hmac.Equal([]byte(expected), []byte(authent)) {
+            writeError(w, http.StatusOK, "authenticationError", s.serverTime())
+            return
+        }
+
+        if nonce != "" {
+            var n int64
+            if _, err := fmt.Sscanf(nonce, "%d", &n); err != nil {
+                writeError(w, http.StatusOK, "invalidArgument", s.serverTime())
+                return
+            }
+            s.mu.Lock()
+            if s.strictNonce && n <= s.lastNonce {
+                s.mu.Unlock()
+                writeError(w, http.StatusOK, "nonceBelowThreshold", s.serverTime())
+                return
+            }
+            if n > s.lastNonce {
+                s.lastNonce = n
+            }
+            s.mu.Unlock()
+        }
+
+        s.mu.Lock()
+        s.checkDeadManLocked()
+        s.mu.Unlock()
+        next(w, r, body)
+        s.flushFeeds()
+    }
+}
+//}}} Auth
+
+
+//{{{ Helpers
+func (s *Server) serverTime() string {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.nowLocked().Format(TimeLayout)
+}
+
+
+// Caller holds s.mu
+func (s *Server) nowLocked() time.Time {
+    return s.now().UTC()
+}
+
+
+// Deterministic uuid looking ids, caller holds s.mu
+func (s *Server) newIdLocked() string {
+    s.nextId++
+    return fmt.Sprintf("%08x-0000-4000-8000-%012x", s.nextId, s.nextId)
+}
+
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(v)
+}
+
+
+// Kraken returns errors as 200 + `result: error` most of the time
+func writeError(w http.ResponseWriter, status int, code string, serverTime string) {
+    writeJSON(w, status, map[string]any{
+        "result":       "error",
+        "error":        code,
+        "serverTime":   serverTime,
+    })
+}
+//}}} Helpers
@@ -0,0 +1,132 @@
+package krakenfake
+
+import (
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+// transfer moves balance between accounts seeded through SetAccounts,
+// transfer/subaccount only records the request (other users are not modelled).
+
+
+//{{{ Balances
+// Key already used for unit (any case) or unit as given
+func foldKey[V any](m map[string]V, unit string) string {
+    for k := range m {
+        if strings.EqualFold(k, unit) {
+            return k
+        }
+    }
+    return unit
+}
+
+
+// false when account is missing or would go negative, caller holds s.mu
+func (s *Server) adjustBalanceLocked(account, unit string, delta float64) bool {
+    switch {
+    case account == "cash" && s.accounts.Cash != nil:
+        return adjust(s.accounts.Cash.Balances, unit, delta)
+    case account == "flex" && s.accounts.Flex != nil:
+        if s.accounts.Flex.Currencies == nil {
+            s.accounts.Flex.Currencies = map[string]types.FlexAccountCurrency{}
+        }
+        key := foldKey(s.accounts.Flex.Currencies, unit)
+        c := s.accounts.Flex.Currencies[key]
+        if c.Available+delta < 0 {
+            return false
+        }
+        c.Quantity += delta
+        c.Available += delta
+        s.accounts.Flex.Currencies[key] = c
+        return true
+    }
+    if m, ok := s.accounts.Margin[account]; ok {
+        if m.Balances == nil {
+            m.Balances = map[string]float64{}
+            s.accounts.Margin[account] = m
+        }
+        return adjust(m.Balances, unit, delta)
+    }
+    return false
+}
+
+
+func adjust(balances map[string]float64, unit string, delta float64) bool {
+    key := foldKey(balances, unit)
+    if balances[key]+delta < 0 {
+        return false
+    }
+    balances[key] += delta
+    return true
+}
+
+
+// Every accepted transfer, own account transfers have empty users
+func (s *Server) Transfers() []types.SubaccountTransferRequest {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]types.SubaccountTransferRequest{}, s.transfers...)
+}
+//}}} Balances
+
+
+//{{{ Handlers
+func parseTransfer(body string) (types.SubaccountTransferRequest, bool) {
+    form, err := url.ParseQuery(body)
+    if err != nil {
+        return types.SubaccountTransferRequest{}, false
+    }
+    amount, err := strconv.ParseFloat(form.Get("amount"), 64)
+    tr := types.SubaccountTransferRequest{
+        FromUser:       form.Get("fromUser"),
+        ToUser:         form.Get("toUser"),
+        FromAccount:    form.Get("fromAccount"),
+        ToAccount:      form.Get("toAccount"),
+        Unit:           form.Get("unit"),
+        Amount:         amount,
+    }
+    return tr, err == nil && amount > 0 && tr.FromAccount != "" && tr.ToAccount != "" && tr.Unit != ""
+}
+
+
+func (s *Server) handleTransfer(w http.ResponseWriter, r *http.Request, body string) {
+    tr, ok := parseTransfer(body)
+    if !ok {
+        writeError(w, http.StatusOK, "invalidArgument", s.serverTime())
+        return
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    now := s.nowLocked().Format(TimeLayout)
+    if !s.adjustBalanceLocked(tr.FromAccount, tr.Unit, -tr.Amount) {
+        writeError(w, http.StatusOK, "insufficientAvailableFunds", now)
+        return
+    }
+    if !s.adjustBalanceLocked(tr.ToAccount, tr.Unit, tr.Amount) {
+        s.adjustBalanceLocked(tr.FromAccount, tr.Unit, tr.Amount)
+        writeError(w, http.StatusOK, "invalidArgument", now)
+        return
+    }
+    s.transfers = append(s.transfers, tr)
+    writeJSON(w, http.StatusOK, map[string]any{"result": "success", "serverTime": now})
+}
+
+
+func (s *Server) handleSubaccountTransfer(w http.ResponseWriter, r *http.Request, body string) {
+    tr, ok := parseTransfer(body)
+    if !ok || tr.FromUser == "" || tr.ToUser == "" {
+        writeError(w, http.StatusOK, "invalidArgument", s.serverTime())
+        return
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.transfers = append(s.transfers, tr)
+    writeJSON(w, http.StatusOK, map[string]any{"result": "success", "serverTime": s.nowLocked().Format(TimeLayout)})
+}
+//}}} Handlers
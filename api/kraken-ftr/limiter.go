@@ -0,0 +1,269 @@
+package krakenftr
+
+import (
+    "context"
+    "encoding/json"
+    "net/url"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+// Client side token buckets modelled on Kraken Futures cost budgets, so bots
+// can run flat out and never get `apiLimitExceeded`:
+//  derivatives (/derivatives/api/v3/*):   500 cost per 10 sec
+//  history     (/api/history/*):          100 tokens, refilled 100 per 10 min, account log costs by count
+//  charts      (/api/charts/*):           separate from derivatives, not documented, conservative guess
+//  public      (tickers, instruments):    not documented, conservative guess
+// Budgets/costs can be overridden with NewRateLimiter.
+
+
+type Pool string
+const (
+    PoolDerivatives Pool = "derivatives"
+    PoolHistory     Pool = "history"
+    PoolCharts      Pool = "charts"
+    PoolPublic      Pool = "public"
+)
+
+
+// Capacity tokens, refilled linearly, full refill takes Interval
+type Budget struct {
+    Capacity    float64
+    Interval    time.Duration
+}
+var DefaultBudgets = map[Pool]Budget{
+    PoolDerivatives:    {Capacity: 500, Interval: 10 * time.Second},
+    PoolHistory:        {Capacity: 100, Interval: 10 * time.Minute},
+    PoolCharts:         {Capacity: 60,  Interval: 10 * time.Second},
+    PoolPublic:         {Capacity: 60,  Interval: 10 * time.Second},
+}
+
+
+// Cost per derivatives endpoint (trimmed `/derivatives/api/v3`), unknown ones cost defaultCost
+var derivativesCosts = map[string]float64{
+    "/sendorder":               10,
+    "/editorder":               10,
+    "/cancelorder":             10,
+    "/cancelallorders":         25,
+    "/cancelallordersafter":    25,
+    "/accounts":                2,
+    "/openpositions":           2,
+    "/openorders":              2,
+    "/orders/status":           1,
+    "/leveragepreferences":     2,
+    "/pnlpreferences":          2,
+    "/transfer":                10,
+    "/transfer/subaccount":     10,
+    "/withdrawal":              100,
+    "/unwindqueue":             200,
+}
+const (
+    defaultCost         = 2
+    batchBaseCost       = 9     // + 1 per element
+    fillsCost           = 2
+    fillsCursorCost     = 25    // with lastFillTime
+)
+
+
+// Account log cost by count, first tier count fits in; no count => Kraken's default
+var accountLogCosts = []struct {
+    maxCount    int
+    cost        float64
+}{
+    {25, 1}, {50, 2}, {1000, 3}, {5000, 6}, {100000, 10},
+}
+const accountLogDefaultCount = 500
+
+
+type LimitMode int
+const (
+    LimitBlock  LimitMode = iota    // sleep until budget is there
+    LimitFail                       // return *RateLimitError right away
+)
+
+
+//{{{ Bucket
+type bucket struct {
+    capacity    float64
+    perSecond   float64
+    tokens      float64
+    last        time.Time
+}
+
+
+// Caller holds lock
+func (b *bucket) refill(now time.Time) {
+    elapsed := now.Sub(b.last).Seconds()
+    if elapsed > 0 {
+        b.tokens += elapsed * b.perSecond
+        if b.tokens > b.capacity {
+            b.tokens = b.capacity
+        }
+        b.last = now
+    }
+}
+
+
+// 0 when taken, otherwise how long until enough tokens (nothing taken)
+func (b *bucket) take(cost float64, now time.Time) time.Duration {
+    b.refill(now)
+    // Request bigger than whole bucket can never pass, let it through when bucket is full
+    if cost > b.capacity {
+        cost = b.capacity
+    }
+    if b.tokens >= cost {
+        b.tokens -= cost
+        return 0
+    }
+    missing := cost - b.tokens
+    return time.Duration(missing / b.perSecond * float64(time.Second))
+}
+//}}} Bucket
+
+
+//{{{ Rate limiter
+type RateLimiter struct {
+    mu      sync.Mutex
+    mode    LimitMode
+    pools   map[Pool]*bucket
+    now     func() time.Time
+    sleep   func(context.Context, time.Duration) error
+}
+
+
+// budgets: nil => DefaultBudgets, pools missing in budgets are not limited
+func NewRateLimiter(budgets map[Pool]Budget, mode LimitMode) *RateLimiter {
+    return newRateLimiter(budgets, mode, time.Now, sleepCtx)
+}
+
+
+// Clock injectable for tests
+func newRateLimiter(budgets map[Pool]Budget, mode LimitMode, now func() time.Time, sleep func(context.Context, time.Duration) error) *RateLimiter {
+    if budgets == nil {
+        budgets = DefaultBudgets
+    }
+    l := &RateLimiter{mode: mode, pools: map[Pool]*bucket{}, now: now, sleep: sleep}
+    start := l.now()
+    for pool, budget := range budgets {
+        l.pools[pool] = &bucket{
+            capacity:   budget.Capacity,
+            perSecond:  budget.Capacity / budget.Interval.Seconds(),
+            tokens:     budget.Capacity,
+            last:       start,
+        }
+    }
+    return l
+}
+
+
+// Takes cost from pool, blocks (until ctx is done) or fails depending on mode
+func (l *RateLimiter) Wait(ctx context.Context, endpoint string, pool Pool, cost float64) error {
+    for {
+        l.mu.Lock()
+        b, ok := l.pools[pool]
+        if !ok {
+            l.mu.Unlock()
+            return nil
+        }
+        wait := b.take(cost, l.now())
+        l.mu.Unlock()
+
+        if wait == 0 {
+            return nil
+        }
+        if l.mode == LimitFail {
+            return &RateLimitError{Endpoint: endpoint, Code: "clientRateLimit", RetryAfter: wait}
+        }
+        if err := l.sleep(ctx, wait); err != nil {
+            return err
+        }
+    }
+}
+
+
+// Tokens left in pool right now, -1 when pool is not limited
+func (l *RateLimiter) Remaining(pool Pool) float64 {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    b, ok := l.pools[pool]
+    if !ok {
+        return -1
+    }
+    b.refill(l.now())
+    return b.tokens
+}
+//}}} Rate limiter
+
+
+//{{{ Costs
+// Which pool and how much, body is needed for batchorder (cost grows with batch size)
+func requestCost(endpoint, query, body string) (Pool, float64) {
+    switch {
+    case strings.HasSuffix(endpoint, "/account-log"):
+        return PoolHistory, accountLogCost(query)
+    case strings.HasPrefix(endpoint, "/api/history/"):
+        return PoolHistory, 1
+    case strings.HasPrefix(endpoint, "/api/charts/"):
+        return PoolCharts, 1
+    case endpoint == "/derivatives/api/v4/historicalfundingrates":
+        return PoolPublic, 1
+    case !strings.HasPrefix(endpoint, "/derivatives/"):
+        return PoolPublic, 1
+    }
+
+    path := strings.TrimPrefix(endpoint, "/derivatives/api/v3")
+    // Per symbol: /tickers/{symbol}, /instruments/{symbol}/status
+    if strings.HasPrefix(path, "/tickers/") || strings.HasPrefix(path, "/instruments/") {
+        return PoolPublic, 1
+    }
+    switch path {
+    case "/tickers", "/instruments", "/instruments/status", "/history":
+        return PoolPublic, 1
+    case "/fills":
+        if strings.Contains(query, "lastFillTime=") {
+            return PoolDerivatives, fillsCursorCost
+        }
+        return PoolDerivatives, fillsCost
+    case "/batchorder":
+        return PoolDerivatives, batchBaseCost + float64(batchSize(body))
+    }
+    if cost, ok := derivativesCosts[path]; ok {
+        return PoolDerivatives, cost
+    }
+    return PoolDerivatives, defaultCost
+}
+
+
+// Number of instructions in url encoded `json={"batchOrder":[...]}` body,
+// body that can not be read is charged as full batch
+func batchSize(body string) int {
+    form, err := url.ParseQuery(body)
+    if err != nil {
+        return MaxBatchSize
+    }
+    var batch struct {
+        BatchOrder []json.RawMessage `json:"batchOrder"`
+    }
+    if err := json.Unmarshal([]byte(form.Get("json")), &batch); err != nil {
+        return MaxBatchSize
+    }
+    return len(batch.BatchOrder)
+}
+
+
+func accountLogCost(query string) float64 {
+    count := accountLogDefaultCount
+    if v, err := url.ParseQuery(query); err == nil {
+        if n, err := strconv.Atoi(v.Get("count")); err == nil && n > 0 {
+            count = n
+        }
+    }
+    for _, tier := range accountLogCosts {
+        if count <= tier.maxCount {
+            return tier.cost
+        }
+    }
+    return accountLogCosts[len(accountLogCosts)-1].cost
+}
+//}}} Costs
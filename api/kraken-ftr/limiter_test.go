@@ -0,0 +1,146 @@
+package krakenftr
+
+import (
+    "context"
+    "errors"
+    "net/url"
+    "testing"
+    "time"
+)
+
+
+// Limiter with fake clock, sleep moves clock forward
+func newTestLimiter(budgets map[Pool]Budget, mode LimitMode) (*RateLimiter, *time.Duration) {
+    start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+    slept := new(time.Duration)
+    now := func() time.Time { return start.Add(*slept) }
+    sleep := func(_ context.Context, d time.Duration) error { *slept += d; return nil }
+    return newRateLimiter(budgets, mode, now, sleep), slept
+}
+
+
+//{{{ Test costs
+func TestRequestCost(t *testing.T) {
+    batch3 := url.Values{"json": {`{"batchOrder":[{"order":"send","order_tag":"a&b=c+d%;"},{"order":"send"},{"order":"cancel"}]}`}}.Encode()
+    tests := []struct {
+        name        string
+        endpoint    string
+        query       string
+        body        string
+        expectPool  Pool
+        expectCost  float64
+    }{
+        {"SendOrder", "/derivatives/api/v3/sendorder", "", "symbol=PF_BCHUSD", PoolDerivatives, 10},
+        {"BatchPerElement", "/derivatives/api/v3/batchorder", "", batch3, PoolDerivatives, 12},
+        {"BatchBadBody", "/derivatives/api/v3/batchorder", "", "json=oops", PoolDerivatives, 9 + MaxBatchSize},
+        {"BatchBadEncoding", "/derivatives/api/v3/batchorder", "", "json=%zz", PoolDerivatives, 9 + MaxBatchSize},
+        {"Fills", "/derivatives/api/v3/fills", "", "", PoolDerivatives, 2},
+        {"FillsWithCursor", "/derivatives/api/v3/fills", "lastFillTime=2025-01-01T00%3A00%3A00.000Z", "", PoolDerivatives, 25},
+        {"OpenOrders", "/derivatives/api/v3/openorders", "", "", PoolDerivatives, 2},
+        {"Unknown", "/derivatives/api/v3/somethingnew", "", "", PoolDerivatives, defaultCost},
+        {"History", "/api/history/v2/orders", "", "", PoolHistory, 1},
+        {"AccountLogDefault", "/api/history/v3/account-log", "", "", PoolHistory, 3},
+        {"AccountLogSmall", "/api/history/v3/account-log", "count=25", "", PoolHistory, 1},
+        {"AccountLog50", "/api/history/v3/account-log", "count=50&sort=asc", "", PoolHistory, 2},
+        {"AccountLog500", "/api/history/v3/account-log", "count=500", "", PoolHistory, 3},
+        {"AccountLog5000", "/api/history/v3/account-log", "since=1&count=5000", "", PoolHistory, 6},
+        {"AccountLogMax", "/api/history/v3/account-log", "count=100000", "", PoolHistory, 10},
+        {"Charts", "/api/charts/v1/trade/PF_BCHUSD/1h", "from=1", "", PoolCharts, 1},
+        {"Tickers", "/derivatives/api/v3/tickers", "", "", PoolPublic, 1},
+        {"Ticker", "/derivatives/api/v3/tickers/PF_BCHUSD", "", "", PoolPublic, 1},
+        {"InstrumentStatus", "/derivatives/api/v3/instruments/PF_BCHUSD/status", "", "", PoolPublic, 1},
+        {"PublicTrades", "/derivatives/api/v3/history", "symbol=PF_BCHUSD", "", PoolPublic, 1},
+        {"FundingRates", "/derivatives/api/v4/historicalfundingrates", "symbol=PF_BCHUSD", "", PoolPublic, 1},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            pool, cost := requestCost(tc.endpoint, tc.query, tc.body)
+            if pool != tc.expectPool || cost != tc.expectCost {
+                t.Errorf("Wrong cost\nExpected:\t%s %v\nGot:\t\t%s %v", tc.expectPool, tc.expectCost, pool, cost)
+            }
+        })
+    }
+}
+//}}} Test costs
+
+
+//{{{ Test limiter
+func TestRateLimiterFail(t *testing.T) {
+    l, slept := newTestLimiter(map[Pool]Budget{PoolDerivatives: {Capacity: 30, Interval: 10 * time.Second}}, LimitFail)
+    for i := 0; i < 3; i++ {
+        if err := l.Wait(t.Context(), "/sendorder", PoolDerivatives, 10); err != nil {
+            t.Fatalf("Request %d rejected: %v", i, err)
+        }
+    }
+    if remaining := l.Remaining(PoolDerivatives); remaining != 0 {
+        t.Errorf("Wrong remaining\nExpected:\t0\nGot:\t\t%v", remaining)
+    }
+
+    err := l.Wait(t.Context(), "/sendorder", PoolDerivatives, 10)
+    var rateErr *RateLimitError
+    if !errors.As(err, &rateErr) {
+        t.Fatalf("Expected *RateLimitError, got %T: %v", err, err)
+    }
+    // 3 tokens/sec => 10 tokens in ~3.33 sec
+    if rateErr.RetryAfter < 3*time.Second || rateErr.RetryAfter > 4*time.Second {
+        t.Errorf("Wrong RetryAfter: %v", rateErr.RetryAfter)
+    }
+    if *slept != 0 {
+        t.Errorf("LimitFail must not sleep, slept %v", *slept)
+    }
+    // Unlimited pool
+    if err := l.Wait(t.Context(), "/api/history/v2/orders", PoolHistory, 1000); err != nil {
+        t.Errorf("Pool without budget got limited: %v", err)
+    }
+    if remaining := l.Remaining(PoolHistory); remaining != -1 {
+        t.Errorf("Wrong remaining for unlimited pool: %v", remaining)
+    }
+}
+
+
+func TestRateLimiterBlock(t *testing.T) {
+    l, slept := newTestLimiter(map[Pool]Budget{PoolDerivatives: {Capacity: 20, Interval: 10 * time.Second}}, LimitBlock)
+    for i := 0; i < 4; i++ {
+        if err := l.Wait(t.Context(), "/sendorder", PoolDerivatives, 10); err != nil {
+            t.Fatalf("Request %d failed: %v", i, err)
+        }
+    }
+    // 2 requests from full bucket, 2 more need 20 tokens at 2/sec
+    if *slept < 10*time.Second || *slept > 11*time.Second {
+        t.Errorf("Wrong total wait\nExpected:\t~10s\nGot:\t\t%v", *slept)
+    }
+    // Bigger than whole bucket still passes once it is full
+    if err := l.Wait(t.Context(), "/unwindqueue", PoolDerivatives, 200); err != nil {
+        t.Errorf("Oversized request failed: %v", err)
+    }
+}
+
+
+// Budget exhausted on real Exchange against fake, no request leaves the client
+func TestFakeRateLimit(t *testing.T) {
+    _, srv := newFakeExchange(t)
+    limiter := NewRateLimiter(map[Pool]Budget{PoolDerivatives: {Capacity: 4, Interval: time.Hour}}, LimitFail)
+    exch := New(srv.URL, srv.PublicKey, srv.PrivateKey, WithRateLimiter(limiter))
+
+    for i := 0; i < 2; i++ {
+        if _, err := exch.GetOpenOrders(t.Context()); err != nil {
+            t.Fatalf("Request %d failed: %v", i, err)
+        }
+    }
+    _, err := exch.GetOpenOrders(t.Context())
+    var rateErr *RateLimitError
+    if !errors.As(err, &rateErr) || rateErr.Code != "clientRateLimit" {
+        t.Fatalf("Expected client side *RateLimitError, got %T: %v", err, err)
+    }
+    if remaining := exch.RateLimitRemaining(PoolDerivatives); remaining >= 2 {
+        t.Errorf("Wrong remaining: %v", remaining)
+    }
+
+    // Disabled limiter
+    unlimited := New(srv.URL, srv.PublicKey, srv.PrivateKey, WithRateLimiter(nil))
+    if remaining := unlimited.RateLimitRemaining(PoolDerivatives); remaining != -1 {
+        t.Errorf("Wrong remaining without limiter: %v", remaining)
+    }
+}
+//}}} Test limiter
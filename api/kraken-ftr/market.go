@@ -0,0 +1,105 @@
+package krakenftr
+
+import (
+    "context"
+    "net/url"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+// Public market data, unsigned (public pool, see limiter.go): all tickers,
+// instrument status (dislocation, extreme volatility), public trades and
+// funding rate history.
+
+
+//{{{ Tickers
+// Every listed contract and index in one call
+func (exch *Exchange) GetTickers(ctx context.Context) (*types.TickersResponse, error) {
+    var result types.TickersResponse
+    if err := exch.doPublic(ctx, "/derivatives/api/v3/tickers", "", &result); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
+//}}} Tickers
+
+
+//{{{ Instrument status
+func (exch *Exchange) GetInstrumentStatusList(ctx context.Context) (*types.InstrumentStatusListResponse, error) {
+    var result types.InstrumentStatusListResponse
+    if err := exch.doPublic(ctx, "/derivatives/api/v3/instruments/status", "", &result); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
+
+
+func (exch *Exchange) GetInstrumentStatus(ctx context.Context, symbol string) (*types.InstrumentStatusResponse, error) {
+    if symbol == "" {
+        return nil, &types.ValidationError{Field: "symbol", Reason: "required"}
+    }
+    var result types.InstrumentStatusResponse
+    if err := exch.doPublic(ctx, "/derivatives/api/v3/instruments/"+url.PathEscape(symbol)+"/status", "", &result); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
+//}}} Instrument status
+
+
+//{{{ Public trades
+// Up to 100 most recent trades (newest first), zero lastTime => now;
+// older ones by passing time of oldest trade from previous call
+func (exch *Exchange) GetPublicTrades(ctx context.Context, symbol string, lastTime time.Time) (*types.PublicTradesResponse, error) {
+    if symbol == "" {
+        return nil, &types.ValidationError{Field: "symbol", Reason: "required"}
+    }
+    v := url.Values{}
+    v.Set("symbol", symbol)
+    if !lastTime.IsZero() {
+        v.Set("lastTime", lastTime.UTC().Format(timeLayout))
+    }
+    var result types.PublicTradesResponse
+    if err := exch.doPublic(ctx, "/derivatives/api/v3/history", v.Encode(), &result); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
+//}}} Public trades
+
+
+//{{{ Funding rates
+// Whole funding history of perpetual, oldest first (no paging on Kraken side)
+func (exch *Exchange) GetHistoricalFundingRates(ctx context.Context, symbol string) (*types.HistoricalFundingRatesResponse, error) {
+    if symbol == "" {
+        return nil, &types.ValidationError{Field: "symbol", Reason: "required"}
+    }
+    var result types.HistoricalFundingRatesResponse
+    if err := exch.doPublic(ctx, "/derivatives/api/v4/historicalfundingrates", "symbol="+url.QueryEscape(symbol), &result); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
+
+
+// Rates at or after since (zero => all) as DB rows
+func (exch *Exchange) FetchFundingRates(ctx context.Context, symbol string, since time.Time) ([]types.FundingRate, error) {
+    result, err := exch.GetHistoricalFundingRates(ctx, symbol)
+    if err != nil {
+        return nil, err
+    }
+    rates := make([]types.FundingRate, 0, len(result.Rates))
+    for _, r := range result.Rates {
+        rate, err := r.ToFundingRate(symbol)
+        if err != nil {
+            return nil, &DecodeError{Endpoint: "/derivatives/api/v4/historicalfundingrates", Err: err}
+        }
+        if rate.DateTime.Before(since) {
+            continue
+        }
+        rates = append(rates, rate)
+    }
+    return rates, nil
+}
+//}}} Funding rates
@@ -0,0 +1,168 @@
+package krakenftr
+
+import (
+    "errors"
+    "testing"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+
+
+//{{{ Test tickers
+// Public calls work without valid private key, nothing gets signed
+func TestGetTickersUnsigned(t *testing.T) {
+    _, srv := newFakeExchange(t)
+    rate, bid := 0.00012, 549.5
+    srv.SetTicker(types.Ticker{Symbol: "PF_XBTUSD", Tag: "perpetual", MarkPrice: 60000, OpenInterest: 1200, FundingRate: &rate, Bid: &bid})
+    exch := New(srv.URL, fakePublicKey, "not base64 !!!")
+
+    result, err := exch.GetTickers(t.Context())
+    if err != nil {
+        t.Fatalf("GetTickers failed: %v", err)
+    }
+    if len(result.Tickers) != 2 || result.Tickers[0].Symbol != "PF_BCHUSD" || result.Tickers[1].Symbol != "PF_XBTUSD" {
+        t.Fatalf("Wrong tickers: %+v", result.Tickers)
+    }
+    xbt := result.Tickers[1]
+    if xbt.OpenInterest != 1200 || xbt.FundingRate == nil || *xbt.FundingRate != rate || xbt.Bid == nil || *xbt.Bid != bid || xbt.Ask != nil {
+        t.Errorf("Fields lost on the way: %+v", xbt)
+    }
+
+    single, err := exch.GetTicker(t.Context(), "PF_BCHUSD")
+    if err != nil {
+        t.Fatalf("GetTicker failed: %v", err)
+    }
+    if single.Ticker.MarkPrice != 550 {
+        t.Errorf("Wrong mark price\nExpected:\t550\nGot:\t\t%v", single.Ticker.MarkPrice)
+    }
+}
+//}}} Test tickers
+
+
+//{{{ Test instrument status
+func TestGetInstrumentStatus(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    srv.SetInstruments(types.Instrument{Symbol: "PF_BCHUSD"}, types.Instrument{Symbol: "PF_XBTUSD"})
+    direction := "ABOVE_UPPER_BOUND"
+    srv.SetInstrumentStatus(types.InstrumentStatus{Tradeable: "PF_XBTUSD", ExperiencingDislocation: true, PriceDislocationDirection: &direction, ExtremeVolatilityInitialMarginMultiplier: 1})
+
+    list, err := exch.GetInstrumentStatusList(t.Context())
+    if err != nil {
+        t.Fatalf("GetInstrumentStatusList failed: %v", err)
+    }
+    if len(list.InstrumentStatus) != 2 || list.InstrumentStatus[0].ExperiencingDislocation || !list.InstrumentStatus[1].ExperiencingDislocation {
+        t.Errorf("Wrong status list: %+v", list.InstrumentStatus)
+    }
+
+    tests := []struct {
+        name                string
+        symbol              string
+        expectDislocation   bool
+        expectErr           bool
+    }{
+        {"SuccDefault",     "PF_BCHUSD",    false,  false},
+        {"SuccDislocated",  "PF_XBTUSD",    true,   false},
+        {"FailUnknown",     "PF_NOPEUSD",   false,  true},
+        {"FailEmpty",       "",             false,  true},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            status, err := exch.GetInstrumentStatus(t.Context(), tc.symbol)
+            if (err != nil) != tc.expectErr {
+                t.Fatalf("Unexpected error: %v", err)
+            }
+            if tc.expectErr {
+                return
+            }
+            if status.Tradeable != tc.symbol || status.ExperiencingDislocation != tc.expectDislocation {
+                t.Errorf("Wrong status\nExpected:\t%s %v\nGot:\t\t%+v", tc.symbol, tc.expectDislocation, status.InstrumentStatus)
+            }
+        })
+    }
+}
+//}}} Test instrument status
+
+
+//{{{ Test public trades
+// Newest first, lastTime walks back page by page
+func TestGetPublicTrades(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+    trades := make([]types.PublicTrade, 150)
+    for i := range trades {
+        trades[i] = types.PublicTrade{Time: start.Add(time.Duration(i) * time.Second).Format(timeLayout), Price: 500 + float64(i), Size: 1, Side: "buy", Type: "fill"}
+    }
+    srv.AddPublicTrades("PF_BCHUSD", trades...)
+    srv.PublishTrade(types.TradeEvent{ProductId: "PF_BCHUSD", Side: "sell", Type: "fill", Time: start.Add(time.Hour).UnixMilli(), Qty: 2, Price: 700})
+
+    first, err := exch.GetPublicTrades(t.Context(), "PF_BCHUSD", time.Time{})
+    if err != nil {
+        t.Fatalf("GetPublicTrades failed: %v", err)
+    }
+    if len(first.History) != 100 || first.History[0].Price != 700 || first.History[0].Side != "sell" {
+        t.Fatalf("Wrong first page: %d trades, newest %+v", len(first.History), first.History[0])
+    }
+
+    oldest, err := time.Parse(time.RFC3339, first.History[99].Time)
+    if err != nil {
+        t.Fatalf("Bad trade time: %v", err)
+    }
+    second, err := exch.GetPublicTrades(t.Context(), "PF_BCHUSD", oldest)
+    if err != nil {
+        t.Fatalf("GetPublicTrades failed: %v", err)
+    }
+    if len(second.History) != 51 || second.History[50].Price != 500 {
+        t.Errorf("Wrong second page\nExpected:\t51 trades ending at 500\nGot:\t\t%d", len(second.History))
+    }
+
+    _, err = exch.GetPublicTrades(t.Context(), "", time.Time{})
+    var validErr *types.ValidationError
+    if !errors.As(err, &validErr) {
+        t.Errorf("Expected ValidationError, got: %T %v", err, err)
+    }
+}
+//}}} Test public trades
+
+
+//{{{ Test funding rates
+func TestFetchFundingRates(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    start := time.Date(2025, 9, 23, 0, 0, 0, 0, time.UTC)
+    rates := make([]types.HistoricalFundingRate, 5)
+    for i := range rates {
+        rates[i] = types.HistoricalFundingRate{Timestamp: start.Add(time.Duration(i) * time.Hour).Format(timeLayout), FundingRate: 0.05, RelativeFundingRate: 0.0001 * float64(i)}
+    }
+    srv.SetFundingRates("PF_BCHUSD", rates)
+
+    tests := []struct {
+        name        string
+        symbol      string
+        since       time.Time
+        expected    int
+        expectErr   bool
+    }{
+        {"SuccAll",         "PF_BCHUSD",    time.Time{},                5,  false},
+        {"SuccSince",       "PF_BCHUSD",    start.Add(3 * time.Hour),   2,  false},
+        {"SuccUnknown",     "PF_NOPEUSD",   time.Time{},                0,  false},
+        {"FailEmpty",       "",             time.Time{},                0,  true},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            got, err := exch.FetchFundingRates(t.Context(), tc.symbol, tc.since)
+            if (err != nil) != tc.expectErr {
+                t.Fatalf("Unexpected error: %v", err)
+            }
+            if len(got) != tc.expected {
+                t.Fatalf("Wrong number of rates\nExpected:\t%d\nGot:\t\t%d", tc.expected, len(got))
+            }
+            if len(got) > 0 && (got[0].Symbol != tc.symbol || got[0].DateTime.Before(tc.since)) {
+                t.Errorf("Wrong first rate: %+v", got[0])
+            }
+        })
+    }
+}
+//}}} Test funding rates
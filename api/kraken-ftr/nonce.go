@@ -0,0 +1,135 @@
+package krakenftr
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+// Kraken wants nonce strictly increasing per API key. Plain UnixMilli() repeats
+// when two requests land in same ms (goroutines) and goes back on clock skew.
+// Exchange instances that share API key must share NonceSource too.
+
+
+type NonceSource interface {
+    NextNonce() (int64, error)
+}
+
+
+//{{{ Monotonic
+// max(now in ms, last + 1), lock free
+type MonotonicNonce struct {
+    last    atomic.Int64
+}
+
+
+func NewMonotonicNonce() *MonotonicNonce {
+    return &MonotonicNonce{}
+}
+
+
+func (n *MonotonicNonce) NextNonce() (int64, error) {
+    for {
+        last := n.last.Load()
+        next := time.Now().UnixMilli()
+        if next <= last {
+            next = last + 1
+        }
+        if n.last.CompareAndSwap(last, next) {
+            return next, nil
+        }
+    }
+}
+
+
+// Never hand out nonce <= floor (ex.: value restored after restart)
+func (n *MonotonicNonce) Advance(floor int64) {
+    for {
+        last := n.last.Load()
+        if last >= floor || n.last.CompareAndSwap(last, floor) {
+            return
+        }
+    }
+}
+//}}} Monotonic
+
+
+//{{{ Persistent
+// Survives restarts even when clock went back: file keeps high water mark
+// that is reserved `block` ahead so disk is touched once per block, not per request
+type FileNonce struct {
+    mu          sync.Mutex
+    path        string
+    block       int64
+    reserved    int64
+    inner       *MonotonicNonce
+}
+
+
+const defaultNonceBlock = 10_000
+
+
+func NewFileNonce(path string) (*FileNonce, error) {
+    n := &FileNonce{path: path, block: defaultNonceBlock, inner: NewMonotonicNonce()}
+    data, err := os.ReadFile(path)
+    switch {
+    case os.IsNotExist(err):
+    case err != nil:
+        return nil, fmt.Errorf("Failed to read nonce file: %w", err)
+    default:
+        stored, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+        if err != nil {
+            return nil, fmt.Errorf("Failed to parse nonce file %s: %w", path, err)
+        }
+        // Everything up to stored could have been used before restart
+        n.inner.Advance(stored)
+        n.reserved = stored
+    }
+    return n, nil
+}
+
+
+func (n *FileNonce) NextNonce() (int64, error) {
+    next, err := n.inner.NextNonce()
+    if err != nil {
+        return 0, err
+    }
+
+    n.mu.Lock()
+    defer n.mu.Unlock()
+    if next > n.reserved {
+        reserve := next + n.block
+        if err := writeFileAtomic(n.path, []byte(strconv.FormatInt(reserve, 10))); err != nil {
+            return 0, fmt.Errorf("Failed to persist nonce: %w", err)
+        }
+        n.reserved = reserve
+    }
+    return next, nil
+}
+
+
+// Write tmp + rename so crash never leaves half written file
+func writeFileAtomic(path string, data []byte) error {
+    tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+    if _, err := tmp.Write(data); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Sync(); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        return err
+    }
+    return os.Rename(tmp.Name(), path)
+}
+//}}} Persistent
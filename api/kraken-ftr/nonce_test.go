@@ -0,0 +1,118 @@
+package krakenftr
+
+import (
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+
+//{{{ Test monotonic nonce
+func TestMonotonicNonceConcurrent(t *testing.T) {
+    nonces := NewMonotonicNonce()
+    var mu sync.Mutex
+    seen := map[int64]bool{}
+    var wg sync.WaitGroup
+    for i := 0; i < 8; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            last := int64(0)
+            for j := 0; j < 1000; j++ {
+                n, _ := nonces.NextNonce()
+                if n <= last {
+                    t.Errorf("Nonce went back in same goroutine: %d after %d", n, last)
+                }
+                last = n
+                mu.Lock()
+                seen[n] = true
+                mu.Unlock()
+            }
+        }()
+    }
+    wg.Wait()
+    if len(seen) != 8000 {
+        t.Errorf("Duplicate nonces\nExpected:\t8000 unique\nGot:\t\t%d", len(seen))
+    }
+}
+
+
+// Restored floor far in the future (clock went back) is still respected
+func TestMonotonicNonceAdvance(t *testing.T) {
+    nonces := NewMonotonicNonce()
+    future := time.Now().Add(time.Hour).UnixMilli()
+    nonces.Advance(future)
+    n, _ := nonces.NextNonce()
+    if n != future+1 {
+        t.Errorf("Wrong nonce after Advance\nExpected:\t%d\nGot:\t\t%d", future+1, n)
+    }
+}
+//}}} Test monotonic nonce
+
+
+//{{{ Test file nonce
+func TestFileNonceRestart(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "nonce")
+    // Previous run was ahead of current clock
+    future := time.Now().Add(time.Hour).UnixMilli()
+    if err := os.WriteFile(path, []byte(strconv.FormatInt(future, 10)), 0o600); err != nil {
+        t.Fatalf("Failed to write nonce file: %v", err)
+    }
+
+    nonces, err := NewFileNonce(path)
+    if err != nil {
+        t.Fatalf("NewFileNonce failed: %v", err)
+    }
+    first, err := nonces.NextNonce()
+    if err != nil {
+        t.Fatalf("NextNonce failed: %v", err)
+    }
+    if first <= future {
+        t.Errorf("Nonce not above stored one: %d <= %d", first, future)
+    }
+
+    // High water mark reserved ahead of what was handed out
+    data, _ := os.ReadFile(path)
+    reserved, _ := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+    if reserved < first {
+        t.Errorf("Reserved %d below handed out %d", reserved, first)
+    }
+
+    // "Restart"
+    restarted, err := NewFileNonce(path)
+    if err != nil {
+        t.Fatalf("NewFileNonce failed: %v", err)
+    }
+    next, _ := restarted.NextNonce()
+    if next <= first {
+        t.Errorf("Nonce went back after restart: %d <= %d", next, first)
+    }
+}
+
+
+func TestFileNonceBadFile(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "nonce")
+    os.WriteFile(path, []byte("not a number"), 0o600)
+    if _, err := NewFileNonce(path); err == nil {
+        t.Errorf("Expected error for corrupt nonce file")
+    }
+}
+//}}} Test file nonce
+
+
+//{{{ Test against strict fake
+// Back to back requests used to reuse same ms nonce
+func TestFakeStrictNonce(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    srv.SetStrictNonce(true)
+    for i := 0; i < 50; i++ {
+        if _, err := exch.GetOpenOrders(t.Context()); err != nil {
+            t.Fatalf("Request %d failed: %v", i, err)
+        }
+    }
+}
+//}}} Test against strict fake
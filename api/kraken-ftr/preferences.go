@@ -0,0 +1,85 @@
+package krakenftr
+
+import (
+    "context"
+    "fmt"
+    "net/url"
+    "strconv"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+// Leverage and PnL currency preferences, multi-collateral (flex) accounts only.
+// Set calls are PUT with parameters in (signed) query. When registry is
+// loaded symbol and leverage are checked against it before sending.
+
+
+//{{{ Leverage
+func (exch *Exchange) GetLeveragePreferences(ctx context.Context) (*types.LeveragePreferencesResponse, error) {
+    var result types.LeveragePreferencesResponse
+    if err := exch.doSigned(ctx, "GET", "/derivatives/api/v3/leveragepreferences", "", "", "", &result); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
+
+
+// Isolated margin with maxLeverage, 0 removes preference (back to cross margin)
+func (exch *Exchange) SetLeveragePreference(ctx context.Context, symbol string, maxLeverage float64) (*types.PreferenceResponse, error) {
+    if symbol == "" {
+        return nil, &types.ValidationError{Field: "symbol", Reason: "required"}
+    }
+    if maxLeverage < 0 {
+        return nil, &types.ValidationError{Field: "maxLeverage", Reason: fmt.Sprintf("0 (cross) or positive, got %v", maxLeverage)}
+    }
+    if registry := exch.Registry(); registry != nil {
+        if err := registry.CheckLeverage(symbol, maxLeverage); err != nil {
+            return nil, err
+        }
+    }
+
+    query := url.Values{"symbol": {symbol}}
+    if maxLeverage > 0 {
+        query.Set("maxLeverage", strconv.FormatFloat(maxLeverage, 'f', -1, 64))
+    }
+    var result types.PreferenceResponse
+    if err := exch.doSigned(ctx, "PUT", "/derivatives/api/v3/leveragepreferences", "", query.Encode(), "", &result); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
+//}}} Leverage
+
+
+//{{{ PnL currency
+func (exch *Exchange) GetPnlPreferences(ctx context.Context) (*types.PnlPreferencesResponse, error) {
+    var result types.PnlPreferencesResponse
+    if err := exch.doSigned(ctx, "GET", "/derivatives/api/v3/pnlpreferences", "", "", "", &result); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
+
+
+// ex.: SetPnlPreference(ctx, "PF_XBTUSD", "BTC")
+func (exch *Exchange) SetPnlPreference(ctx context.Context, symbol, pnlCurrency string) (*types.PreferenceResponse, error) {
+    if symbol == "" {
+        return nil, &types.ValidationError{Field: "symbol", Reason: "required"}
+    }
+    if pnlCurrency == "" {
+        return nil, &types.ValidationError{Field: "pnlPreference", Reason: "required"}
+    }
+    if registry := exch.Registry(); registry != nil {
+        if _, err := registry.CheckMultiCollateral(symbol); err != nil {
+            return nil, err
+        }
+    }
+
+    query := url.Values{"symbol": {symbol}, "pnlPreference": {pnlCurrency}}
+    var result types.PreferenceResponse
+    if err := exch.doSigned(ctx, "PUT", "/derivatives/api/v3/pnlpreferences", "", query.Encode(), "", &result); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
+//}}} PnL currency
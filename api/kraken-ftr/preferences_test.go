@@ -0,0 +1,119 @@
+package krakenftr
+
+import (
+    "errors"
+    "testing"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+
+
+//{{{ Test leverage preferences
+// Registry loaded => bad requests never leave client; PF_BCHUSD max is 50x
+func TestSetLeveragePreference(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    registry, err := SnapshotRegistry()
+    if err != nil {
+        t.Fatalf("SnapshotRegistry failed: %v", err)
+    }
+    exch.SetRegistry(registry)
+
+    tests := []struct {
+        name            string
+        symbol          string
+        maxLeverage     float64
+        expectErr       error
+        expectValidErr  bool
+        expectStored    map[string]float64
+    }{
+        {"SuccSet",             "PF_BCHUSD",    10,     nil,                    false,  map[string]float64{"PF_BCHUSD": 10}},
+        {"SuccMax",             "PF_XBTUSD",    50,     nil,                    false,  map[string]float64{"PF_BCHUSD": 10, "PF_XBTUSD": 50}},
+        {"FailTooHigh",         "PF_BCHUSD",    60,     ErrLeverageTooHigh,     false,  map[string]float64{"PF_BCHUSD": 10, "PF_XBTUSD": 50}},
+        {"FailInverse",         "PI_XBTUSD",    5,      ErrNotMultiCollateral,  false,  map[string]float64{"PF_BCHUSD": 10, "PF_XBTUSD": 50}},
+        {"FailUnknown",         "PF_NOPEUSD",   5,      ErrUnknownInstrument,   false,  map[string]float64{"PF_BCHUSD": 10, "PF_XBTUSD": 50}},
+        {"FailNegative",        "PF_BCHUSD",    -1,     nil,                    true,   map[string]float64{"PF_BCHUSD": 10, "PF_XBTUSD": 50}},
+        {"SuccCrossMargin",     "PF_XBTUSD",    0,      nil,                    false,  map[string]float64{"PF_BCHUSD": 10}},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            _, err := exch.SetLeveragePreference(t.Context(), tc.symbol, tc.maxLeverage)
+            var validationErr *types.ValidationError
+            switch {
+            case tc.expectErr != nil && !errors.Is(err, tc.expectErr):
+                t.Fatalf("Wrong error\nExpected:\t%v\nGot:\t\t%v", tc.expectErr, err)
+            case tc.expectValidErr && !errors.As(err, &validationErr):
+                t.Fatalf("Expected *types.ValidationError, got %v", err)
+            case tc.expectErr == nil && !tc.expectValidErr && err != nil:
+                t.Fatalf("Unexpected error: %v", err)
+            }
+            stored := srv.LeveragePreferences()
+            if len(stored) != len(tc.expectStored) {
+                t.Fatalf("Wrong preferences\nExpected:\t%v\nGot:\t\t%v", tc.expectStored, stored)
+            }
+            for symbol, leverage := range tc.expectStored {
+                if stored[symbol] != leverage {
+                    t.Errorf("Wrong preferences\nExpected:\t%v\nGot:\t\t%v", tc.expectStored, stored)
+                }
+            }
+        })
+    }
+
+    result, err := exch.GetLeveragePreferences(t.Context())
+    if err != nil {
+        t.Fatalf("GetLeveragePreferences failed: %v", err)
+    }
+    if len(result.LeveragePreferences) != 1 || result.LeveragePreferences[0] != (types.LeveragePreference{Symbol: "PF_BCHUSD", MaxLeverage: 10}) {
+        t.Errorf("Wrong leverage preferences: %+v", result.LeveragePreferences)
+    }
+}
+
+
+// Without registry fake still caps leverage by its instruments
+func TestSetLeveragePreferenceNoRegistry(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    snapshot, _ := SnapshotRegistry()
+    inst, _ := snapshot.Lookup("PF_BCHUSD")
+    srv.SetInstruments(inst)
+
+    _, err := exch.SetLeveragePreference(t.Context(), "PF_BCHUSD", 60)
+    var exchangeErr *ExchangeError
+    if !errors.As(err, &exchangeErr) || exchangeErr.Code != "invalidArgument" {
+        t.Fatalf("Expected *ExchangeError invalidArgument, got %v", err)
+    }
+}
+//}}} Test leverage preferences
+
+
+//{{{ Test PnL preferences
+func TestPnlPreferences(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    if _, err := exch.SetPnlPreference(t.Context(), "PF_BCHUSD", ""); err == nil {
+        t.Fatalf("Expected error for empty pnlPreference")
+    }
+    if _, err := exch.SetPnlPreference(t.Context(), "PF_BCHUSD", "BCH"); err != nil {
+        t.Fatalf("SetPnlPreference failed: %v", err)
+    }
+    if _, err := exch.SetLeveragePreference(t.Context(), "PF_BCHUSD", 5); err != nil {
+        t.Fatalf("SetLeveragePreference failed: %v", err)
+    }
+
+    result, err := exch.GetPnlPreferences(t.Context())
+    if err != nil {
+        t.Fatalf("GetPnlPreferences failed: %v", err)
+    }
+    if len(result.Preferences) != 1 || result.Preferences[0] != (types.PnlPreference{Symbol: "PF_BCHUSD", PnlCurrency: "BCH"}) {
+        t.Errorf("Wrong pnl preferences: %+v", result.Preferences)
+    }
+
+    // Preferences show up on position
+    if _, err := exch.SendOrder(t.Context(), types.SendOrderRequest{OrderType: "mkt", Symbol: "PF_BCHUSD", Side: "buy", Size: 1}); err != nil {
+        t.Fatalf("SendOrder failed: %v", err)
+    }
+    positions := srv.OpenPositions()
+    if len(positions) != 1 || positions[0].PnlCurrency == nil || *positions[0].PnlCurrency != "BCH" || positions[0].MaxFixedLeverage == nil || *positions[0].MaxFixedLeverage != 5 {
+        t.Errorf("Wrong position: %+v", positions)
+    }
+}
+//}}} Test PnL preferences
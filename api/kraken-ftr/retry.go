@@ -0,0 +1,173 @@
+package krakenftr
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "math/rand/v2"
+    "net/http"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+// Transient failures (5xx, timeouts, connection resets) are retried with
+// jittered exponential backoff. GETs are replayed as is. sendorder is replayed
+// only after orders/status shows the cliOrdId did not land, batchorder is
+// never replayed (partial success can not be told apart).
+
+
+type RetryPolicy struct {
+    MaxAttempts int             // first try included, <= 1 disables retries
+    BaseDelay   time.Duration   // delay before 2nd attempt, doubles every time
+    MaxDelay    time.Duration   // cap for a single delay
+}
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 4, BaseDelay: 250 * time.Millisecond, MaxDelay: 5 * time.Second}
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+
+func WithRetryPolicy(policy RetryPolicy) Option {
+    return func(exch *Exchange) {
+        exch.retry = policy
+    }
+}
+
+
+// Full jitter: random in [delay/2, delay], delay = BaseDelay * 2^(attempt-1)
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+    delay := p.MaxDelay
+    if attempt < 32 {
+        if shifted := p.BaseDelay << (attempt - 1); shifted > 0 && shifted < delay {
+            delay = shifted
+        }
+    }
+    if delay <= 0 {
+        return 0
+    }
+    half := delay / 2
+    return half + rand.N(half+1)
+}
+
+
+// attempt: how many were already made
+func (p RetryPolicy) allows(attempt int, err error) bool {
+    return attempt < p.MaxAttempts && isTransient(err)
+}
+
+
+// 5xx, transport failures (timeouts and resets included); anything Kraken
+// answered with an error code is final
+func isTransient(err error) bool {
+    var transportErr *TransportError
+    if errors.As(err, &transportErr) {
+        return true
+    }
+    var statusErr *StatusError
+    if errors.As(err, &statusErr) {
+        return statusErr.StatusCode >= http.StatusInternalServerError
+    }
+    return false
+}
+
+
+// Replays fn while failure is transient and ctx is alive
+func (exch *Exchange) withRetry(ctx context.Context, fn func() error) error {
+    for attempt := 1; ; attempt++ {
+        err := fn()
+        if err == nil || ctx.Err() != nil || !exch.retry.allows(attempt, err) {
+            return err
+        }
+        if err := exch.sleep(ctx, exch.retry.backoff(attempt)); err != nil {
+            return err
+        }
+    }
+}
+
+
+// time.Sleep that gives up when ctx is done
+func sleepCtx(ctx context.Context, d time.Duration) error {
+    timer := time.NewTimer(d)
+    defer timer.Stop()
+    select {
+    case <-ctx.Done():
+        return ctx.Err()
+    case <-timer.C:
+        return nil
+    }
+}
+
+
+//{{{ Idempotent send order
+// Kraken keeps closed (filled, cancelled, rejected) orders on orders/status
+// only this long, afterwards not found there means nothing
+const orderStatusWindow = 5 * time.Second
+var ErrLandingUnknown = errors.New("can not tell whether order landed, not resent")
+
+
+// Sends once, on transient failure checks whether order landed (by cliOrdId)
+// before sending again. Landed order is reported as `placed` even if it
+// already filled or got cancelled, `rejected` when exchange refused it.
+// Lookup that can not decide fails with ErrLandingUnknown, order is not resent
+func (exch *Exchange) sendOrderIdempotent(ctx context.Context, order types.SendOrderRequest, body string) (*types.SendOrderResponse, error) {
+    endpoint := "/derivatives/api/v3/sendorder"
+    firstSent := exch.now()
+    for attempt := 1; ; attempt++ {
+        var result types.SendOrderResponse
+        err := exch.doSigned(ctx, "POST", endpoint, "", "", body, &result)
+        if err == nil {
+            return &result, nil
+        }
+        // Without cliOrdId there is no way to tell if it landed
+        if order.CliOrdId == "" || ctx.Err() != nil || !exch.retry.allows(attempt, err) {
+            return nil, err
+        }
+        if err := exch.sleep(ctx, exch.retry.backoff(attempt)); err != nil {
+            return nil, err
+        }
+
+        status, lookupErr := exch.findByCliOrdId(ctx, order.CliOrdId, firstSent)
+        if lookupErr != nil {
+            return nil, fmt.Errorf("%w (lookup of %s before resend failed: %w)", err, order.CliOrdId, lookupErr)
+        }
+        if status != nil {
+            return &types.SendOrderResponse{Result: "success", SendStatus: *status}, nil
+        }
+    }
+}
+
+
+// orders/status first (open and recently closed orders), then latest fills.
+// nil status => did not land, trusted only while every order sent since
+// `since` would still be listed on orders/status
+func (exch *Exchange) findByCliOrdId(ctx context.Context, cliOrdId string, since time.Time) (*types.SendStatus, error) {
+    statuses, err := exch.GetOrderStatus(ctx, nil, []string{cliOrdId})
+    if err != nil {
+        return nil, err
+    }
+    for _, o := range statuses.Orders {
+        if o.Order.CliOrdId == nil || *o.Order.CliOrdId != cliOrdId {
+            continue
+        }
+        status := "placed"
+        if o.Status == "REJECTED" {
+            status = "rejected"
+        }
+        return &types.SendStatus{OrderId: o.Order.OrderId, Status: status}, nil
+    }
+    if exch.now().Sub(since) < orderStatusWindow {
+        return nil, nil
+    }
+
+    // Could have filled and left orders/status already
+    fills, err := exch.GetOrderFills(ctx, time.Time{})
+    if err != nil {
+        return nil, err
+    }
+    for _, f := range fills.Fills {
+        if f.CliOrdId != nil && *f.CliOrdId == cliOrdId {
+            return &types.SendStatus{OrderId: f.OrderId, Status: "placed"}, nil
+        }
+    }
+    return nil, ErrLandingUnknown
+}
+//}}} Idempotent send order
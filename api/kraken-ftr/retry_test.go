@@ -0,0 +1,232 @@
+package krakenftr
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/api/kraken-ftr/krakenfake"
+)
+
+
+//{{{ Test backoff
+func TestRetryBackoff(t *testing.T) {
+    policy := RetryPolicy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+    tests := []struct {
+        attempt     int
+        min         time.Duration
+        max         time.Duration
+    }{
+        {1,     50 * time.Millisecond,  100 * time.Millisecond},
+        {2,     100 * time.Millisecond, 200 * time.Millisecond},
+        {4,     400 * time.Millisecond, 800 * time.Millisecond},
+        {5,     500 * time.Millisecond, time.Second},   // capped
+        {100,   500 * time.Millisecond, time.Second},   // no overflow
+    }
+    for _, tc := range tests {
+        for i := 0; i < 50; i++ {
+            delay := policy.backoff(tc.attempt)
+            if delay < tc.min || delay > tc.max {
+                t.Fatalf("Attempt %d: delay %v not in [%v, %v]", tc.attempt, delay, tc.min, tc.max)
+            }
+        }
+    }
+}
+//}}} Test backoff
+
+
+//{{{ Test GET retry
+func TestRetryGet(t *testing.T) {
+    tests := []struct {
+        name            string
+        failures        []int           // statuses served before normal handling
+        expectErr       bool
+        expectRequests  int             // attempts made (failures consumed + final)
+    }{
+        {"SuccAfter5xx",            []int{502, 503},        false,  3},
+        {"FailGiveUp",              []int{502, 502, 502, 502, 502}, true, 4},
+        {"FailNotTransient",        []int{404},             true,   1},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            exch, srv := newFakeExchange(t)
+            sleeps := 0
+            exch.sleep = func(context.Context, time.Duration) error { sleeps++; return nil }
+            for _, status := range tc.failures {
+                srv.QueueFailure(status, "<html>upstream</html>")
+            }
+            _, err := exch.GetOpenOrders(t.Context())
+            if (err != nil) != tc.expectErr {
+                t.Fatalf("Unexpected error: %v", err)
+            }
+            if sleeps != tc.expectRequests-1 {
+                t.Errorf("Wrong number of retries\nExpected:\t%d\nGot:\t\t%d", tc.expectRequests-1, sleeps)
+            }
+        })
+    }
+}
+//}}} Test GET retry
+
+
+//{{{ Test idempotent send
+func TestRetrySendOrder(t *testing.T) {
+    post := types.SendOrderRequest{Symbol: "PF_BCHUSD", OrderType: "post", Side: "buy", Size: 1, LimitPrice: 500}
+    tests := []struct {
+        name        string
+        order       types.SendOrderRequest
+        inject      func(srv *krakenfake.Server)
+        // Runs in backoff sleep, before lookup
+        meanwhile   func(t *testing.T, exch *Exchange, srv *krakenfake.Server)
+        elapsed     time.Duration   // clocks move by it in backoff sleep
+        expectStatus string         // empty => ErrLandingUnknown
+        expectOpen  int
+        expectFills int
+    }{
+        {
+            // Never reached engine => resent
+            name:       "SuccFailedBeforeLanding",
+            order:      post,
+            inject:     func(srv *krakenfake.Server) { srv.QueueFailure(502, "bad gateway") },
+            expectStatus: "placed",
+            expectOpen: 1,
+        }, {
+            // Landed, response lost => found on orders/status, not resent
+            name:       "SuccLandedResting",
+            order:      post,
+            inject:     func(srv *krakenfake.Server) { srv.QueueLostResponse(504, "gateway timeout") },
+            expectStatus: "placed",
+            expectOpen: 1,
+        }, {
+            // Landed and filled right away => not resent
+            name:        "SuccLandedFilled",
+            order:       types.SendOrderRequest{Symbol: "PF_BCHUSD", OrderType: "mkt", Side: "buy", Size: 1},
+            inject:      func(srv *krakenfake.Server) { srv.QueueLostResponse(504, "gateway timeout") },
+            expectStatus: "placed",
+            expectFills: 1,
+        }, {
+            // Filled long ago, gone from orders/status => found in fills
+            name:        "SuccLandedFilledAfterWindow",
+            order:       types.SendOrderRequest{Symbol: "PF_BCHUSD", OrderType: "mkt", Side: "buy", Size: 1},
+            inject:      func(srv *krakenfake.Server) { srv.QueueLostResponse(504, "gateway timeout") },
+            elapsed:     10 * time.Second,
+            expectStatus: "placed",
+            expectFills: 1,
+        }, {
+            // Landed and got cancelled before lookup => not in openorders/fills, not resent
+            name:       "SuccLandedCancelled",
+            order:      post,
+            inject:     func(srv *krakenfake.Server) { srv.QueueLostResponse(504, "gateway timeout") },
+            meanwhile:  cancelOpenOrders,
+            expectStatus: "placed",
+        }, {
+            // Refused by engine => reported as rejected, not resent
+            name:       "SuccLandedRejected",
+            order:      types.SendOrderRequest{Symbol: "PF_BCHUSD", OrderType: "post", Side: "buy", Size: 1, LimitPrice: 600},
+            inject:     func(srv *krakenfake.Server) { srv.QueueLostResponse(504, "gateway timeout") },
+            expectStatus: "rejected",
+        }, {
+            // Cancelled and out of orders/status window => can not tell, not resent
+            name:       "FailLandedCancelledAfterWindow",
+            order:      post,
+            inject:     func(srv *krakenfake.Server) { srv.QueueLostResponse(504, "gateway timeout") },
+            meanwhile:  cancelOpenOrders,
+            elapsed:    10 * time.Second,
+        }, {
+            // Did not land, but after window that looks same as above
+            name:       "FailFailedBeforeLandingAfterWindow",
+            order:      post,
+            inject:     func(srv *krakenfake.Server) { srv.QueueFailure(502, "bad gateway") },
+            elapsed:    10 * time.Second,
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            exch, srv := newFakeExchange(t)
+            clock := time.Now()
+            srv.SetClock(func() time.Time { return clock })
+            exch.now = func() time.Time { return clock }
+            exch.sleep = func(context.Context, time.Duration) error {
+                if tc.meanwhile != nil {
+                    tc.meanwhile(t, exch, srv)
+                }
+                clock = clock.Add(tc.elapsed)
+                moved := clock
+                srv.SetClock(func() time.Time { return moved })
+                return nil
+            }
+            tc.inject(srv)
+            result, err := exch.SendOrder(t.Context(), tc.order)
+            if tc.expectStatus == "" {
+                if !errors.Is(err, ErrLandingUnknown) {
+                    t.Fatalf("Expected ErrLandingUnknown, got: %v", err)
+                }
+            } else {
+                if err != nil {
+                    t.Fatalf("SendOrder failed: %v", err)
+                }
+                if result.SendStatus.Status != tc.expectStatus || result.SendStatus.OrderId == "" {
+                    t.Errorf("Wrong send status\nExpected:\t%s\nGot:\t\t%+v", tc.expectStatus, result.SendStatus)
+                }
+            }
+            if open := srv.OpenOrders(); len(open) != tc.expectOpen {
+                t.Errorf("Wrong number of open orders\nExpected:\t%d\nGot:\t\t%d", tc.expectOpen, len(open))
+            }
+            fills := srv.Fills()
+            if len(fills) != tc.expectFills {
+                t.Errorf("Wrong number of fills\nExpected:\t%d\nGot:\t\t%d", tc.expectFills, len(fills))
+            }
+            if len(fills) == 1 && fills[0].OrderId != result.SendStatus.OrderId {
+                t.Errorf("Wrong order id\nExpected:\t%s\nGot:\t\t%s", fills[0].OrderId, result.SendStatus.OrderId)
+            }
+        })
+    }
+}
+
+
+func cancelOpenOrders(t *testing.T, exch *Exchange, srv *krakenfake.Server) {
+    ids := []string{}
+    for _, o := range srv.OpenOrders() {
+        ids = append(ids, o.OrderId)
+    }
+    if _, err := exch.BatchCancelOrders(t.Context(), ids); err != nil {
+        t.Fatalf("BatchCancelOrders failed: %v", err)
+    }
+}
+
+
+// Rejection by exchange is final, same cliOrdId is not sent twice
+func TestRetrySendOrderNotTransient(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    srv.QueueFailure(200, `{"result":"error","error":"accountInactive"}`)
+    _, err := exch.SendOrder(t.Context(), types.SendOrderRequest{Symbol: "PF_BCHUSD", OrderType: "post", Side: "buy", Size: 1, LimitPrice: 500})
+    var exchErr *ExchangeError
+    if !errors.As(err, &exchErr) {
+        t.Fatalf("Expected *ExchangeError, got %T: %v", err, err)
+    }
+    if open := srv.OpenOrders(); len(open) != 0 {
+        t.Errorf("Order was resent after final rejection: %+v", open)
+    }
+}
+
+
+func TestRetryBatchNotReplayed(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    srv.QueueLostResponse(502, "bad gateway")
+    _, err := exch.BatchSendOrders(t.Context(), []types.SendOrderRequest{
+        {Symbol: "PF_BCHUSD", OrderType: "post", Side: "buy", Size: 1, LimitPrice: 500},
+        {Symbol: "PF_BCHUSD", OrderType: "post", Side: "buy", Size: 1, LimitPrice: 501},
+    })
+    var statusErr *StatusError
+    if !errors.As(err, &statusErr) {
+        t.Fatalf("Expected *StatusError, got %T: %v", err, err)
+    }
+    if open := srv.OpenOrders(); len(open) != 2 {
+        t.Errorf("Batch replayed or lost\nExpected:\t2 open\nGot:\t\t%d", len(open))
+    }
+}
+//}}} Test idempotent send
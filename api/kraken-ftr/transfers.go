@@ -0,0 +1,88 @@
+package krakenftr
+
+import (
+    "context"
+    "errors"
+    "fmt"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+import (
+    "github.com/google/go-querystring/query"
+)
+// Moving collateral between own futures accounts (cash, flex, isolated margin
+// accounts) and between users (master <=> sub accounts). Before sending, the
+// source balance is read through /accounts so obvious overdrafts never reach
+// Kraken. Never retried, same as other POSTs.
+
+
+var (
+    ErrInsufficientBalance  = errors.New("insufficient balance")
+    ErrUnknownAccount       = errors.New("account or unit not found")
+    // Balances could not be read, transfer was not sent
+    ErrBalanceCheckFailed   = errors.New("balance check failed")
+)
+
+
+//{{{ Balance check
+// Source account has at least amount of unit available
+func (exch *Exchange) checkTransferBalance(ctx context.Context, account, unit string, amount float64) error {
+    result, err := exch.GetAccounts(ctx)
+    if err != nil {
+        return fmt.Errorf("%w: %w", ErrBalanceCheckFailed, err)
+    }
+    available, ok := result.Accounts.Available(account, unit)
+    if !ok {
+        return fmt.Errorf("%s %s: %w", account, unit, ErrUnknownAccount)
+    }
+    if available < amount {
+        return fmt.Errorf("%s: %v %s requested, %v available: %w", account, amount, unit, available, ErrInsufficientBalance)
+    }
+    return nil
+}
+//}}} Balance check
+
+
+//{{{ Transfer
+func (exch *Exchange) Transfer(ctx context.Context, req types.TransferRequest) (*types.TransferResponse, error) {
+    if err := req.Validate(); err != nil {
+        return nil, err
+    }
+    if err := exch.checkTransferBalance(ctx, req.FromAccount, req.Unit, req.Amount); err != nil {
+        return nil, err
+    }
+    v, err := query.Values(req)
+    if err != nil {
+        return nil, err
+    }
+
+    var result types.TransferResponse
+    if err := exch.doSigned(ctx, "POST", "/derivatives/api/v3/transfer", "", "", v.Encode(), &result); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
+
+
+func (exch *Exchange) SubaccountTransfer(ctx context.Context, req types.SubaccountTransferRequest) (*types.TransferResponse, error) {
+    if err := req.Validate(); err != nil {
+        return nil, err
+    }
+    if !req.SkipBalanceCheck {
+        if err := exch.checkTransferBalance(ctx, req.FromAccount, req.Unit, req.Amount); err != nil {
+            return nil, err
+        }
+    }
+    v, err := query.Values(req)
+    if err != nil {
+        return nil, err
+    }
+
+    var result types.TransferResponse
+    if err := exch.doSigned(ctx, "POST", "/derivatives/api/v3/transfer/subaccount", "", "", v.Encode(), &result); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
+//}}} Transfer
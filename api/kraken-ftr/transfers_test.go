@@ -0,0 +1,92 @@
+package krakenftr
+
+import (
+    "errors"
+    "testing"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+
+
+//{{{ Test transfer
+func TestTransfer(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    srv.SetAccounts(types.Accounts{
+        Cash:   &types.CashAccount{Balances: map[string]float64{"usd": 50}},
+        Margin: map[string]types.MarginAccount{"fi_xbtusd": {Currency: "xbt", Balances: map[string]float64{"xbt": 0.5}}},
+        Flex:   &types.FlexAccount{Currencies: map[string]types.FlexAccountCurrency{"USD": {Quantity: 1000, Available: 400}}},
+    })
+
+    tests := []struct {
+        name            string
+        req             types.TransferRequest
+        expectErr       error
+        expectValidErr  bool
+        expectSent      int
+    }{
+        {"SuccFlexToCash",      types.TransferRequest{FromAccount: "flex", ToAccount: "cash", Unit: "usd", Amount: 150},           nil,                    false,  1},
+        {"SuccCashToFlex",      types.TransferRequest{FromAccount: "cash", ToAccount: "flex", Unit: "USD", Amount: 200},           nil,                    false,  2},
+        {"FailOverdraft",       types.TransferRequest{FromAccount: "flex", ToAccount: "cash", Unit: "usd", Amount: 451},           ErrInsufficientBalance, false,  2},
+        {"FailUnknownUnit",     types.TransferRequest{FromAccount: "fi_xbtusd", ToAccount: "cash", Unit: "eth", Amount: 1},        ErrUnknownAccount,      false,  2},
+        {"FailUnknownAccount",  types.TransferRequest{FromAccount: "fi_nope", ToAccount: "cash", Unit: "xbt", Amount: 1},          ErrUnknownAccount,      false,  2},
+        {"FailSameAccount",     types.TransferRequest{FromAccount: "cash", ToAccount: "cash", Unit: "usd", Amount: 1},             nil,                    true,   2},
+        {"FailZeroAmount",      types.TransferRequest{FromAccount: "flex", ToAccount: "cash", Unit: "usd", Amount: 0},             nil,                    true,   2},
+        {"SuccIsolated",        types.TransferRequest{FromAccount: "fi_xbtusd", ToAccount: "cash", Unit: "xbt", Amount: 0.5},      nil,                    false,  3},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            _, err := exch.Transfer(t.Context(), tc.req)
+            var validationErr *types.ValidationError
+            switch {
+            case tc.expectErr != nil && !errors.Is(err, tc.expectErr):
+                t.Fatalf("Wrong error\nExpected:\t%v\nGot:\t\t%v", tc.expectErr, err)
+            case tc.expectValidErr && !errors.As(err, &validationErr):
+                t.Fatalf("Expected *types.ValidationError, got %v", err)
+            case tc.expectErr == nil && !tc.expectValidErr && err != nil:
+                t.Fatalf("Unexpected error: %v", err)
+            }
+            if got := len(srv.Transfers()); got != tc.expectSent {
+                t.Errorf("Wrong number of transfers on exchange\nExpected:\t%d\nGot:\t\t%d", tc.expectSent, got)
+            }
+        })
+    }
+
+    result, err := exch.GetAccounts(t.Context())
+    if err != nil {
+        t.Fatalf("GetAccounts failed: %v", err)
+    }
+    accounts := result.Accounts
+    // 400 - 150 + 200, 50 + 150 - 200
+    if flex, _ := accounts.Available("flex", "usd"); flex != 450 {
+        t.Errorf("Wrong flex balance\nExpected:\t450\nGot:\t\t%v", flex)
+    }
+    if cash, _ := accounts.Available("cash", "usd"); cash != 0 {
+        t.Errorf("Wrong cash balance\nExpected:\t0\nGot:\t\t%v", cash)
+    }
+    if xbt, _ := accounts.Available("cash", "xbt"); xbt != 0.5 {
+        t.Errorf("Wrong cash xbt balance\nExpected:\t0.5\nGot:\t\t%v", xbt)
+    }
+}
+
+
+// Balance check reads key owner's accounts, skipped for other source user
+func TestSubaccountTransfer(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    srv.SetAccounts(types.Accounts{Flex: &types.FlexAccount{Currencies: map[string]types.FlexAccountCurrency{"USD": {Available: 10}}}})
+    req := types.SubaccountTransferRequest{FromUser: "master", ToUser: "sub-1", FromAccount: "flex", ToAccount: "flex", Unit: "usd", Amount: 100}
+
+    if _, err := exch.SubaccountTransfer(t.Context(), req); !errors.Is(err, ErrInsufficientBalance) {
+        t.Fatalf("Expected ErrInsufficientBalance, got %v", err)
+    }
+    req.FromUser, req.ToUser, req.SkipBalanceCheck = "sub-1", "master", true
+    if _, err := exch.SubaccountTransfer(t.Context(), req); err != nil {
+        t.Fatalf("SubaccountTransfer failed: %v", err)
+    }
+    transfers := srv.Transfers()
+    if len(transfers) != 1 || transfers[0].FromUser != "sub-1" || transfers[0].ToUser != "master" || transfers[0].Amount != 100 {
+        t.Errorf("Wrong transfers on exchange: %+v", transfers)
+    }
+}
+//}}} Test transfer
@@ -4,6 +4,7 @@ package dbfns
 import (
     "fmt"
     "errors"
+    "time"
     "database/sql"
 )
 import (
@@ -70,6 +71,63 @@ func CreateOrderFill(db *sql.DB, of types.OrderFill, ignoreFlag bool) error {
 }
 
 
+// Bulk insert in single transaction, already stored fill_id-s are skipped
+// returns number of actually inserted rows
+func CreateOrderFills(db *sql.DB, ofList []types.OrderFill) (int, error) {
+    tx, err := db.Begin()
+    if err != nil {
+        return 0, err
+    }
+    defer tx.Rollback()
+
+    stmt, err := tx.Prepare(`INSERT INTO order_fills(
+        fill_id, symbol, side, price,
+        coin_amount, coin, currency_amount, currency,
+        fill_type, date_time, owner)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+        ON CONFLICT (fill_id) DO NOTHING;`)
+    if err != nil {
+        return 0, err
+    }
+    defer stmt.Close()
+
+    inserted := 0
+    for _, of := range ofList {
+        res, err := stmt.Exec(
+            of.FillId, of.Symbol, of.Side, of.Price,
+            of.CoinAmount, of.Coin, of.CurrencyAmount, of.Currency,
+            of.FillType, of.DateTime, of.Owner)
+        if err != nil {
+            return 0, fmt.Errorf("fill %s: %w", of.FillId, err)
+        }
+        n, err := res.RowsAffected()
+        if err != nil {
+            return 0, err
+        }
+        inserted += int(n)
+    }
+
+    if err := tx.Commit(); err != nil {
+        return 0, err
+    }
+    return inserted, nil
+}
+
+
+// Time of newest stored fill for owner, zero time when owner has none
+func ReadLastFillTime(db *sql.DB, owner string) (time.Time, error) {
+    query := `SELECT MAX(date_time) FROM order_fills WHERE owner = $1;`
+    var last sql.NullTime
+    if err := db.QueryRow(query, owner).Scan(&last); err != nil {
+        return time.Time{}, err
+    }
+    if !last.Valid {
+        return time.Time{}, nil
+    }
+    return last.Time, nil
+}
+
+
 func ReadAvgPrice(db *sql.DB, owner, coin, side, currency string, dayRange int) (float64, float64, error) {
     // VWAP = SUM(price * volume)/SUM(volume)
     query := `
@@ -92,13 +150,226 @@ func ReadAvgPrice(db *sql.DB, owner, coin, side, currency string, dayRange int)
 }
 
 
+// Audit row, returns its id
+func CreateTransfer(db *sql.DB, tr types.Transfer) (int64, error) {
+    query := `INSERT INTO transfers(
+        owner, from_user, to_user, from_account, to_account,
+        unit, amount, status, error, date_time)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        RETURNING id;`
+    var id int64
+    err := db.QueryRow(query,
+        tr.Owner, tr.FromUser, tr.ToUser, tr.FromAccount, tr.ToAccount,
+        tr.Unit, tr.Amount, tr.Status, tr.Error, tr.DateTime).Scan(&id)
+    if err != nil {
+        return 0, err
+    }
+    return id, nil
+}
 
 
+// Owner's transfers at or after since, oldest first
+func ReadTransfers(db *sql.DB, owner string, since time.Time) ([]types.Transfer, error) {
+    query := `SELECT id, owner, from_user, to_user, from_account, to_account,
+            unit, amount, status, error, date_time
+        FROM transfers
+        WHERE owner = $1 AND date_time >= $2
+        ORDER BY date_time, id;`
+    rows, err := db.Query(query, owner, since)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
 
+    transfers := []types.Transfer{}
+    for rows.Next() {
+        var tr types.Transfer
+        err := rows.Scan(
+            &tr.Id, &tr.Owner, &tr.FromUser, &tr.ToUser, &tr.FromAccount, &tr.ToAccount,
+            &tr.Unit, &tr.Amount, &tr.Status, &tr.Error, &tr.DateTime)
+        if err != nil {
+            return nil, err
+        }
+        transfers = append(transfers, tr)
+    }
+    return transfers, rows.Err()
+}
 
 
+// Bulk insert in single transaction, already stored (symbol, date_time) are skipped
+// returns number of actually inserted rows
+func CreateFundingRates(db *sql.DB, rates []types.FundingRate) (int, error) {
+    tx, err := db.Begin()
+    if err != nil {
+        return 0, err
+    }
+    defer tx.Rollback()
 
+    stmt, err := tx.Prepare(`INSERT INTO funding_rates(
+        symbol, rate, relative_rate, date_time)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (symbol, date_time) DO NOTHING;`)
+    if err != nil {
+        return 0, err
+    }
+    defer stmt.Close()
 
+    inserted := 0
+    for _, fr := range rates {
+        res, err := stmt.Exec(fr.Symbol, fr.Rate, fr.RelativeRate, fr.DateTime)
+        if err != nil {
+            return 0, fmt.Errorf("funding rate %s %s: %w", fr.Symbol, fr.DateTime, err)
+        }
+        n, err := res.RowsAffected()
+        if err != nil {
+            return 0, err
+        }
+        inserted += int(n)
+    }
 
+    if err := tx.Commit(); err != nil {
+        return 0, err
+    }
+    return inserted, nil
+}
 
 
+// Time of newest stored rate for symbol, zero time when there is none
+func ReadLastFundingTime(db *sql.DB, symbol string) (time.Time, error) {
+    query := `SELECT MAX(date_time) FROM funding_rates WHERE symbol = $1;`
+    var last sql.NullTime
+    if err := db.QueryRow(query, symbol).Scan(&last); err != nil {
+        return time.Time{}, err
+    }
+    if !last.Valid {
+        return time.Time{}, nil
+    }
+    return last.Time, nil
+}
+
+
+// Symbol's rates in [since, before), oldest first; zero before => up to now
+func ReadFundingRates(db *sql.DB, symbol string, since, before time.Time) ([]types.FundingRate, error) {
+    query := `SELECT symbol, rate, relative_rate, date_time
+        FROM funding_rates
+        WHERE symbol = $1 AND date_time >= $2 AND ($3::timestamptz IS NULL OR date_time < $3)
+        ORDER BY date_time;`
+    var until sql.NullTime
+    if !before.IsZero() {
+        until = sql.NullTime{Time: before, Valid: true}
+    }
+    rows, err := db.Query(query, symbol, since, until)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    rates := []types.FundingRate{}
+    for rows.Next() {
+        var fr types.FundingRate
+        if err := rows.Scan(&fr.Symbol, &fr.Rate, &fr.RelativeRate, &fr.DateTime); err != nil {
+            return nil, err
+        }
+        rates = append(rates, fr)
+    }
+    return rates, rows.Err()
+}
+
+
+// Bulk upsert in single transaction, stored candle at same open time is
+// overwritten (last candle keeps changing until its period is over)
+// returns number of inserted or updated rows
+func UpsertCandles(db *sql.DB, tickType, symbol, resolution string, candles []types.Candle) (int, error) {
+    tx, err := db.Begin()
+    if err != nil {
+        return 0, err
+    }
+    defer tx.Rollback()
+
+    stmt, err := tx.Prepare(`INSERT INTO candles(
+        symbol, tick_type, resolution, open_time,
+        open, high, low, close, volume)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        ON CONFLICT (symbol, tick_type, resolution, open_time) DO UPDATE SET
+            open = EXCLUDED.open, high = EXCLUDED.high, low = EXCLUDED.low,
+            close = EXCLUDED.close, volume = EXCLUDED.volume;`)
+    if err != nil {
+        return 0, err
+    }
+    defer stmt.Close()
+
+    upserted := 0
+    for _, c := range candles {
+        res, err := stmt.Exec(
+            symbol, tickType, resolution, time.UnixMilli(c.Time).UTC(),
+            c.Open, c.High, c.Low, c.Close, c.Volume)
+        if err != nil {
+            return 0, fmt.Errorf("candle %s %s %s %d: %w", tickType, symbol, resolution, c.Time, err)
+        }
+        n, err := res.RowsAffected()
+        if err != nil {
+            return 0, err
+        }
+        upserted += int(n)
+    }
+
+    if err := tx.Commit(); err != nil {
+        return 0, err
+    }
+    return upserted, nil
+}
+
+
+// Candles with open time in [from, to], oldest first; zero to => up to now
+func ReadCandles(db *sql.DB, tickType, symbol, resolution string, from, to time.Time) ([]types.Candle, error) {
+    query := `SELECT open_time, open, high, low, close, volume
+        FROM candles
+        WHERE symbol = $1 AND tick_type = $2 AND resolution = $3
+            AND open_time >= $4 AND ($5::timestamptz IS NULL OR open_time <= $5)
+        ORDER BY open_time;`
+    var until sql.NullTime
+    if !to.IsZero() {
+        until = sql.NullTime{Time: to, Valid: true}
+    }
+    rows, err := db.Query(query, symbol, tickType, resolution, from, until)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    candles := []types.Candle{}
+    for rows.Next() {
+        var c types.Candle
+        var openTime time.Time
+        if err := rows.Scan(&openTime, &c.Open, &c.High, &c.Low, &c.Close, &c.Volume); err != nil {
+            return nil, err
+        }
+        c.Time = openTime.UnixMilli()
+        candles = append(candles, c)
+    }
+    return candles, rows.Err()
+}
+
+
+// Range backfill has fetched for series, zero times when nothing yet
+func ReadCandleCoverage(db *sql.DB, tickType, symbol, resolution string) (from, to time.Time, err error) {
+    query := `SELECT covered_from, covered_to FROM candle_coverage
+        WHERE symbol = $1 AND tick_type = $2 AND resolution = $3;`
+    err = db.QueryRow(query, symbol, tickType, resolution).Scan(&from, &to)
+    if errors.Is(err, sql.ErrNoRows) {
+        return time.Time{}, time.Time{}, nil
+    }
+    return from, to, err
+}
+
+
+// Widens stored coverage to include [from, to), never shrinks it
+func UpsertCandleCoverage(db *sql.DB, tickType, symbol, resolution string, from, to time.Time) error {
+    query := `INSERT INTO candle_coverage(symbol, tick_type, resolution, covered_from, covered_to)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (symbol, tick_type, resolution) DO UPDATE SET
+            covered_from = LEAST(candle_coverage.covered_from, EXCLUDED.covered_from),
+            covered_to = GREATEST(candle_coverage.covered_to, EXCLUDED.covered_to);`
+    _, err := db.Exec(query, symbol, tickType, resolution, from.UTC(), to.UTC())
+    return err
+}
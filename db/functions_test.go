@@ -335,3 +335,314 @@ func TestReadAvgPrice(t *testing.T) {
 //}}} Read AvgPrice
 
 
+//{{{ Create OrderFills (bulk)
+func TestCreateOrderFills(t *testing.T) {
+    user := types.User{ Username: "test_user_for_bulk_fills" }
+    if err := CreateUser(DB, user); err != nil {
+        t.Fatalf("Failed to create user: %v", err)
+    }
+    orderFill := types.OrderFill{
+        FillId:         "c1000000-0000-4000-8000-000000000001",
+        Symbol:         "PF_XRPUSD",
+        Side:           "buy",
+        Price:          2.5,
+        CoinAmount:     10.0,
+        Coin:           "XRP",
+        CurrencyAmount: 25.0,
+        Currency:       "USD",
+        FillType:       "maker",
+        DateTime:       "2025-09-23T16:55:10.557Z",
+        Owner:          "test_user_for_bulk_fills",
+    }
+    of2 := orderFill
+    of2.FillId = "c1000000-0000-4000-8000-000000000002"
+    of2.DateTime = "2025-09-24T16:55:10.557Z"
+    of3 := orderFill
+    of3.FillId = "c1000000-0000-4000-8000-000000000003"
+    of3.DateTime = "2025-09-22T16:55:10.557Z"
+    ofBad := orderFill
+    ofBad.FillId = "c1000000-0000-4000-8000-000000000004"
+    ofBad.Side = "long"
+
+    tests := []struct {
+        name            string
+        ofList          []types.OrderFill
+        expectInserted  int
+        expectErrStr    string
+    }{
+        {
+            name:           "SuccTwo",
+            ofList:         []types.OrderFill{orderFill, of2},
+            expectInserted: 2,
+        }, {
+            name:           "SuccSkipExisting",
+            ofList:         []types.OrderFill{orderFill, of2, of3},
+            expectInserted: 1,
+        }, {
+            name:           "SuccEmpty",
+            ofList:         []types.OrderFill{},
+            expectInserted: 0,
+        }, {
+            name:           "FailCheckConstraintRollsBack",
+            ofList:         []types.OrderFill{ofBad},
+            expectErrStr:   "violates check constraint",
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            inserted, err := CreateOrderFills(DB, tc.ofList)
+            if tc.expectErrStr != "" {
+                if err == nil || !strings.Contains(err.Error(), tc.expectErrStr) {
+                    t.Errorf("Wrong error\nExpected:\t%q\nGot:\t\t%v", tc.expectErrStr, err)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("Error that is not expected occured: %v", err)
+            }
+            if inserted != tc.expectInserted {
+                t.Errorf("Wrong number inserted\nExpected:\t%d\nGot:\t\t%d", tc.expectInserted, inserted)
+            }
+        })
+    }
+}
+//}}} Create OrderFills (bulk)
+
+
+//{{{ Read last fill time
+func TestReadLastFillTime(t *testing.T) {
+    // Relies on fills from TestCreateOrderFills
+    last, err := ReadLastFillTime(DB, "test_user_for_bulk_fills")
+    if err != nil {
+        t.Fatalf("ReadLastFillTime failed: %v", err)
+    }
+    expected := time.Date(2025, 9, 24, 16, 55, 10, 557000000, time.UTC)
+    if !last.Equal(expected) {
+        t.Errorf("Wrong last fill time\nExpected:\t%v\nGot:\t\t%v", expected, last)
+    }
+
+    last, err = ReadLastFillTime(DB, "user_without_fills")
+    if err != nil {
+        t.Fatalf("ReadLastFillTime failed: %v", err)
+    }
+    if !last.IsZero() {
+        t.Errorf("Expected zero time, got: %v", last)
+    }
+}
+//}}} Read last fill time
+
+
+//{{{ Transfers
+func TestCreateReadTransfers(t *testing.T) {
+    strPtr := func(s string) *string { return &s }
+    owner := "test_user_for_transfers"
+    if err := CreateUser(DB, types.User{Username: owner}); err != nil {
+        t.Fatalf("Failed to create user: %v", err)
+    }
+    start := time.Date(2025, 9, 25, 10, 0, 0, 0, time.UTC)
+
+    tests := []struct {
+        name            string
+        transfer        types.Transfer
+        expectErr       bool
+        expectErrStr    string
+    }{
+        {"SuccOwnAccounts",     types.Transfer{Owner: owner, FromAccount: "flex", ToAccount: "cash", Unit: "usd", Amount: 150, Status: "success", DateTime: start},                                                                       false,  ""},
+        {"SuccSubaccount",      types.Transfer{Owner: owner, FromUser: strPtr("master"), ToUser: strPtr("sub-1"), FromAccount: "flex", ToAccount: "flex", Unit: "usd", Amount: 10, Status: "success", DateTime: start.Add(time.Minute)},  false,  ""},
+        {"SuccRejected",        types.Transfer{Owner: owner, FromAccount: "flex", ToAccount: "cash", Unit: "usd", Amount: 1e6, Status: "rejected", Error: strPtr("insufficient balance"), DateTime: start.Add(2 * time.Minute)},        false,  ""},
+        {"FailWrongStatus",     types.Transfer{Owner: owner, FromAccount: "flex", ToAccount: "cash", Unit: "usd", Amount: 1, Status: "maybe", DateTime: start},                                                                         true,   "violates check constraint"},
+        {"FailZeroAmount",      types.Transfer{Owner: owner, FromAccount: "flex", ToAccount: "cash", Unit: "usd", Amount: 0, Status: "success", DateTime: start},                                                                       true,   "violates check constraint"},
+        {"FailUnknownOwner",    types.Transfer{Owner: "nobody", FromAccount: "flex", ToAccount: "cash", Unit: "usd", Amount: 1, Status: "success", DateTime: start},                                                                     true,   "violates foreign key constraint"},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            id, err := CreateTransfer(DB, tc.transfer)
+            if tc.expectErr {
+                if err == nil || !strings.Contains(err.Error(), tc.expectErrStr) {
+                    t.Fatalf("Expected error containing %q, got: %v", tc.expectErrStr, err)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("Unexpected error: %v", err)
+            }
+            if id <= 0 {
+                t.Errorf("Wrong id: %d", id)
+            }
+        })
+    }
+
+    transfers, err := ReadTransfers(DB, owner, start.Add(time.Minute))
+    if err != nil {
+        t.Fatalf("ReadTransfers failed: %v", err)
+    }
+    if len(transfers) != 2 {
+        t.Fatalf("Wrong number of transfers\nExpected:\t2\nGot:\t\t%d", len(transfers))
+    }
+    if transfers[0].FromUser == nil || *transfers[0].FromUser != "master" || transfers[0].Amount != 10 {
+        t.Errorf("Wrong sub account transfer: %+v", transfers[0])
+    }
+    if transfers[1].Status != "rejected" || transfers[1].Error == nil || transfers[1].FromUser != nil {
+        t.Errorf("Wrong rejected transfer: %+v", transfers[1])
+    }
+}
+//}}} Transfers
+
+
+//{{{ Funding rates
+func TestCreateReadFundingRates(t *testing.T) {
+    start := time.Date(2025, 9, 23, 0, 0, 0, 0, time.UTC)
+    rates := []types.FundingRate{
+        {Symbol: "PF_BCHUSD", Rate: 0.05, RelativeRate: 0.0001, DateTime: start},
+        {Symbol: "PF_BCHUSD", Rate: 0.06, RelativeRate: 0.00012, DateTime: start.Add(time.Hour)},
+        {Symbol: "PF_BCHUSD", Rate: -0.02, RelativeRate: -0.00004, DateTime: start.Add(2 * time.Hour)},
+        {Symbol: "PF_XBTUSD", Rate: 6, RelativeRate: 0.0001, DateTime: start},
+    }
+    inserted, err := CreateFundingRates(DB, rates)
+    if err != nil {
+        t.Fatalf("CreateFundingRates failed: %v", err)
+    }
+    if inserted != len(rates) {
+        t.Errorf("Wrong number of inserted rows\nExpected:\t%d\nGot:\t\t%d", len(rates), inserted)
+    }
+    // Same rates again, nothing new
+    inserted, err = CreateFundingRates(DB, rates)
+    if err != nil || inserted != 0 {
+        t.Errorf("Duplicates not skipped: %d inserted, err %v", inserted, err)
+    }
+
+    last, err := ReadLastFundingTime(DB, "PF_BCHUSD")
+    if err != nil || !last.Equal(start.Add(2*time.Hour)) {
+        t.Errorf("Wrong last funding time: %v (%v)", last, err)
+    }
+    if none, err := ReadLastFundingTime(DB, "PF_NOPEUSD"); err != nil || !none.IsZero() {
+        t.Errorf("Expected zero time for unknown symbol, got %v (%v)", none, err)
+    }
+
+    tests := []struct {
+        name        string
+        since       time.Time
+        before      time.Time
+        expected    int
+    }{
+        {"All",         time.Time{},                time.Time{},                3},
+        {"Since",       start.Add(time.Hour),       time.Time{},                2},
+        {"Before",      time.Time{},                start.Add(2 * time.Hour),   2},
+        {"Window",      start.Add(time.Hour),       start.Add(2 * time.Hour),   1},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            got, err := ReadFundingRates(DB, "PF_BCHUSD", tc.since, tc.before)
+            if err != nil {
+                t.Fatalf("ReadFundingRates failed: %v", err)
+            }
+            if len(got) != tc.expected {
+                t.Fatalf("Wrong number of rates\nExpected:\t%d\nGot:\t\t%d", tc.expected, len(got))
+            }
+            for i := 1; i < len(got); i++ {
+                if !got[i].DateTime.After(got[i-1].DateTime) {
+                    t.Errorf("Rates not oldest first at %d", i)
+                }
+            }
+        })
+    }
+}
+//}}} Funding rates
+
+
+//{{{ Candles
+func TestUpsertReadCandles(t *testing.T) {
+    start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+    candle := func(hour int, close float64) types.Candle {
+        return types.Candle{Time: start.Add(time.Duration(hour) * time.Hour).UnixMilli(), Open: 500, High: 510, Low: 490, Close: close, Volume: 3}
+    }
+
+    tests := []struct {
+        name            string
+        tickType        string
+        resolution      string
+        candles         []types.Candle
+        expected        int
+        expectErr       bool
+        expectErrStr    string
+    }{
+        {"SuccInsert",      "trade",    "1h",   []types.Candle{candle(0, 501), candle(1, 502), candle(2, 503)},    3,  false,  ""},
+        {"SuccUpdate",      "trade",    "1h",   []types.Candle{candle(2, 510), candle(3, 504)},                    2,  false,  ""},
+        {"FailTickType",    "last",     "1h",   []types.Candle{candle(0, 501)},                                    0,  true,   "violates check constraint"},
+        {"FailResolution",  "trade",    "2h",   []types.Candle{candle(0, 501)},                                    0,  true,   "violates check constraint"},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            n, err := UpsertCandles(DB, tc.tickType, "PF_BCHUSD", tc.resolution, tc.candles)
+            if tc.expectErr {
+                if err == nil || !strings.Contains(err.Error(), tc.expectErrStr) {
+                    t.Fatalf("Expected error containing %q, got: %v", tc.expectErrStr, err)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("Unexpected error: %v", err)
+            }
+            if n != tc.expected {
+                t.Errorf("Wrong number of upserted rows\nExpected:\t%d\nGot:\t\t%d", tc.expected, n)
+            }
+        })
+    }
+
+    candles, err := ReadCandles(DB, "trade", "PF_BCHUSD", "1h", start.Add(time.Hour), time.Time{})
+    if err != nil {
+        t.Fatalf("ReadCandles failed: %v", err)
+    }
+    if len(candles) != 3 {
+        t.Fatalf("Wrong number of candles\nExpected:\t3\nGot:\t\t%d", len(candles))
+    }
+    if candles[0].Time != candle(1, 0).Time || candles[1].Close != 510 || candles[2].Volume != 3 {
+        t.Errorf("Wrong candles: %+v", candles)
+    }
+    if other, err := ReadCandles(DB, "mark", "PF_BCHUSD", "1h", start, time.Time{}); err != nil || len(other) != 0 {
+        t.Errorf("Other tick type leaked: %d candles (%v)", len(other), err)
+    }
+}
+
+
+func TestCandleCoverage(t *testing.T) {
+    start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+    h := func(n int) time.Time { return start.Add(time.Duration(n) * time.Hour) }
+
+    from, to, err := ReadCandleCoverage(DB, "spot", "PF_BCHUSD", "1h")
+    if err != nil || !from.IsZero() || !to.IsZero() {
+        t.Fatalf("Expected no coverage, got %v - %v (%v)", from, to, err)
+    }
+
+    tests := []struct {
+        name            string
+        from            time.Time
+        to              time.Time
+        expectFrom      time.Time
+        expectTo        time.Time
+    }{
+        {"SuccInsert",      h(5),   h(10),  h(5),   h(10)},
+        {"SuccWiden",       h(2),   h(12),  h(2),   h(12)},
+        {"SuccNoShrink",    h(6),   h(8),   h(2),   h(12)},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            if err := UpsertCandleCoverage(DB, "spot", "PF_BCHUSD", "1h", tc.from, tc.to); err != nil {
+                t.Fatalf("UpsertCandleCoverage failed: %v", err)
+            }
+            from, to, err := ReadCandleCoverage(DB, "spot", "PF_BCHUSD", "1h")
+            if err != nil {
+                t.Fatalf("ReadCandleCoverage failed: %v", err)
+            }
+            if !from.Equal(tc.expectFrom) || !to.Equal(tc.expectTo) {
+                t.Errorf("Wrong coverage\nExpected:\t%v - %v\nGot:\t\t%v - %v", tc.expectFrom, tc.expectTo, from, to)
+            }
+        })
+    }
+}
+//}}} Candles
@@ -24,3 +24,51 @@ CREATE TABLE IF NOT EXISTS order_fills (
     FOREIGN KEY (owner) REFERENCES users(username) ON DELETE CASCADE
 );
 
+CREATE TABLE IF NOT EXISTS transfers (
+    id              BIGSERIAL PRIMARY KEY,
+    owner           VARCHAR(32) NOT NULL,
+    from_user       VARCHAR(64),    -- only sub account transfers
+    to_user         VARCHAR(64),
+    from_account    VARCHAR(64) NOT NULL,
+    to_account      VARCHAR(64) NOT NULL,
+    unit            VARCHAR(16) NOT NULL,
+    amount          DECIMAL(24, 8) NOT NULL CHECK (amount > 0),
+    status          VARCHAR(16) NOT NULL CHECK (status in ('success', 'rejected', 'unknown')),
+    error           TEXT,
+    date_time       TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (owner) REFERENCES users(username) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS funding_rates (
+    symbol          VARCHAR(32) NOT NULL,
+    rate            DOUBLE PRECISION NOT NULL,  -- absolute, quote currency per contract
+    relative_rate   DOUBLE PRECISION NOT NULL,  -- fraction of price per period
+    date_time       TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (symbol, date_time)
+);
+
+CREATE TABLE IF NOT EXISTS candles (
+    symbol          VARCHAR(32) NOT NULL,
+    tick_type       VARCHAR(8) NOT NULL CHECK (tick_type in ('spot', 'mark', 'trade')),
+    resolution      VARCHAR(4) NOT NULL CHECK (resolution in ('1m', '5m', '15m', '30m', '1h', '4h', '12h', '1d', '1w')),
+    open_time       TIMESTAMPTZ NOT NULL,
+    open            DOUBLE PRECISION NOT NULL,
+    high            DOUBLE PRECISION NOT NULL,
+    low             DOUBLE PRECISION NOT NULL,
+    close           DOUBLE PRECISION NOT NULL,
+    volume          DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (symbol, tick_type, resolution, open_time)
+);
+
+-- Only real candles are stored; range backfill already fetched is kept per
+-- series, so stretches without candles (before listing, no trades) are not
+-- fetched again. Candles opening before covered_to are final
+CREATE TABLE IF NOT EXISTS candle_coverage (
+    symbol          VARCHAR(32) NOT NULL,
+    tick_type       VARCHAR(8) NOT NULL CHECK (tick_type in ('spot', 'mark', 'trade')),
+    resolution      VARCHAR(4) NOT NULL CHECK (resolution in ('1m', '5m', '15m', '30m', '1h', '4h', '12h', '1d', '1w')),
+    covered_from    TIMESTAMPTZ NOT NULL,
+    covered_to      TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (symbol, tick_type, resolution)
+);
@@ -0,0 +1,121 @@
+package jobs
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/api/kraken-ftr"
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/db"
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+// Local candle store: only real candles are stored, gaps are filled when
+// reading; backfill fetches only what candle_coverage does not cover yet,
+// strategies read from DB instead of hitting Kraken on every start
+
+
+//{{{ Missing ranges
+type candleRange struct {
+    from    time.Time
+    to      time.Time
+}
+
+
+// Parts of [from, to] outside covered [covFrom, covTo) (zero => nothing
+// covered); each stays adjacent to covered range so coverage remains one
+// interval, even if that means fetching more than asked for
+func missingCandleRanges(covFrom, covTo, from, to time.Time) []candleRange {
+    if covFrom.IsZero() && covTo.IsZero() {
+        return []candleRange{{from: from, to: to}}
+    }
+    ranges := []candleRange{}
+    if from.Before(covFrom) {
+        ranges = append(ranges, candleRange{from: from, to: covFrom.Add(-time.Millisecond)})
+    }
+    if !to.Before(covTo) {
+        ranges = append(ranges, candleRange{from: covTo, to: to})
+    }
+    return ranges
+}
+
+
+// End of coverage after fetching up to `to`: period to is in is covered only
+// once it is over, otherwise it is fetched again next time
+func coveredUntil(to, now time.Time, step time.Duration) time.Time {
+    end := to
+    if end.After(now) {
+        end = now
+    }
+    period := end.Truncate(step)
+    if next := period.Add(step); !next.After(now) {
+        return next
+    }
+    return period
+}
+//}}} Missing ranges
+
+
+//{{{ Backfill
+type candleSource interface {
+    FetchCandles(ctx context.Context, q types.CandleQuery) ([]types.Candle, error)
+}
+var _ candleSource = (*krakenftr.Exchange)(nil)
+
+
+// Fetches missing parts of q (zero To => now), upserts them and records
+// coverage, returns number of stored rows
+func BackfillCandles(ctx context.Context, db *sql.DB, exch candleSource, q types.CandleQuery) (int, error) {
+    if err := q.Validate(); err != nil {
+        return 0, err
+    }
+    now := time.Now()
+    if q.To.IsZero() {
+        q.To = now
+    }
+    covFrom, covTo, err := dbfns.ReadCandleCoverage(db, q.TickType, q.Symbol, q.Resolution)
+    if err != nil {
+        return 0, fmt.Errorf("Failed to read candle coverage: %w", err)
+    }
+
+    total := 0
+    for _, r := range missingCandleRanges(covFrom, covTo, q.From, q.To) {
+        candles, err := exch.FetchCandles(ctx, types.CandleQuery{TickType: q.TickType, Symbol: q.Symbol, Resolution: q.Resolution, From: r.from, To: r.to})
+        if err != nil {
+            return total, fmt.Errorf("Failed to fetch candles %s - %s: %w", r.from.Format(time.RFC3339), r.to.Format(time.RFC3339), err)
+        }
+        upserted, err := dbfns.UpsertCandles(db, q.TickType, q.Symbol, q.Resolution, candles)
+        if err != nil {
+            return total, fmt.Errorf("Failed to store candles: %w", err)
+        }
+        total += upserted
+
+        // Ranges are adjacent to coverage, so it can grow range by range
+        if covFrom.IsZero() || r.from.Before(covFrom) {
+            covFrom = r.from
+        }
+        if end := coveredUntil(r.to, now, types.CandleResolutions[q.Resolution]); end.After(covTo) {
+            covTo = end
+        }
+        if err := dbfns.UpsertCandleCoverage(db, q.TickType, q.Symbol, q.Resolution, covFrom, covTo); err != nil {
+            return total, fmt.Errorf("Failed to store candle coverage: %w", err)
+        }
+    }
+    return total, nil
+}
+
+
+// Backfill then read with gaps filled, what strategies should call instead
+// of GetOHLC
+func LoadCandles(ctx context.Context, db *sql.DB, exch candleSource, q types.CandleQuery) ([]types.Candle, error) {
+    if _, err := BackfillCandles(ctx, db, exch, q); err != nil {
+        return nil, err
+    }
+    candles, err := dbfns.ReadCandles(db, q.TickType, q.Symbol, q.Resolution, q.From, q.To)
+    if err != nil {
+        return nil, err
+    }
+    return types.FillCandleGaps(candles, types.CandleResolutions[q.Resolution]), nil
+}
+//}}} Backfill
@@ -0,0 +1,135 @@
+package jobs
+
+import (
+    "testing"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/db"
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+
+
+//{{{ Missing ranges
+func TestMissingCandleRanges(t *testing.T) {
+    start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+    h := func(n int) time.Time { return start.Add(time.Duration(n) * time.Hour) }
+    ms := time.Millisecond
+    tests := []struct {
+        name        string
+        covFrom     time.Time
+        covTo       time.Time
+        from        time.Time
+        to          time.Time
+        expected    []candleRange
+    }{
+        {"Empty",       time.Time{},    time.Time{},    h(0),   h(10),  []candleRange{{h(0), h(10)}}},
+        {"Covered",     h(0),           h(10),          h(2),   h(8),   []candleRange{}},
+        {"Head",        h(3),           h(6),           h(0),   h(5),   []candleRange{{h(0), h(3).Add(-ms)}}},
+        {"Tail",        h(0),           h(3),           h(0),   h(8),   []candleRange{{h(3), h(8)}}},
+        {"Both",        h(3),           h(6),           h(0),   h(8),   []candleRange{{h(0), h(3).Add(-ms)}, {h(6), h(8)}}},
+        // Not adjacent => bridged, coverage stays one interval
+        {"AfterCover",  h(0),           h(3),           h(6),   h(8),   []candleRange{{h(3), h(8)}}},
+        {"BeforeCover", h(6),           h(8),           h(0),   h(2),   []candleRange{{h(0), h(6).Add(-ms)}}},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            got := missingCandleRanges(tc.covFrom, tc.covTo, tc.from, tc.to)
+            if len(got) != len(tc.expected) {
+                t.Fatalf("Wrong ranges\nExpected:\t%v\nGot:\t\t%v", tc.expected, got)
+            }
+            for i := range got {
+                if !got[i].from.Equal(tc.expected[i].from) || !got[i].to.Equal(tc.expected[i].to) {
+                    t.Errorf("Wrong range %d\nExpected:\t%v\nGot:\t\t%v", i, tc.expected[i], got[i])
+                }
+            }
+        })
+    }
+}
+
+
+func TestCoveredUntil(t *testing.T) {
+    start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+    h := func(n int) time.Time { return start.Add(time.Duration(n) * time.Hour) }
+    tests := []struct {
+        name        string
+        to          time.Time
+        now         time.Time
+        expected    time.Time
+    }{
+        {"PastPeriodOver",      h(5).Add(30 * time.Minute), h(10),                      h(6)},
+        {"CurrentPeriod",       h(10),                      h(10).Add(20 * time.Minute), h(10)},
+        {"FutureClampedToNow",  h(20),                      h(10).Add(20 * time.Minute), h(10)},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            if got := coveredUntil(tc.to, tc.now, time.Hour); !got.Equal(tc.expected) {
+                t.Errorf("Wrong coverage end\nExpected:\t%v\nGot:\t\t%v", tc.expected, got)
+            }
+        })
+    }
+}
+//}}} Missing ranges
+
+
+//{{{ Backfill
+// Second run fetches only what is past coverage, older stored candles are
+// left alone; gaps are stored as gaps and filled on read
+func TestBackfillCandles(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+    series := func(n int, close float64) []types.Candle {
+        candles := []types.Candle{}
+        for i := range n {
+            // No trades in hour 3
+            if i == 3 {
+                continue
+            }
+            candles = append(candles, types.Candle{Time: start.Add(time.Duration(i) * time.Hour).UnixMilli(), Open: close, High: close, Low: close, Close: close, Volume: 1})
+        }
+        return candles
+    }
+    srv.SetCandles("trade", "PF_ETHUSD", "1h", series(10, 2500))
+    srv.SetCandleLimit(4)
+    // Listed at start, first 5 hours of query are empty
+    q := types.CandleQuery{TickType: "trade", Symbol: "PF_ETHUSD", Resolution: "1h", From: start.Add(-5 * time.Hour), To: start.Add(5 * time.Hour)}
+
+    stored, err := BackfillCandles(t.Context(), DB, exch, q)
+    if err != nil || stored != 5 {
+        t.Fatalf("First backfill\nExpected:\t5\nGot:\t\t%d (%v)", stored, err)
+    }
+    if gap, err := dbfns.ReadCandles(DB, "trade", "PF_ETHUSD", "1h", start.Add(3*time.Hour), start.Add(3*time.Hour)); err != nil || len(gap) != 0 {
+        t.Errorf("Synthetic candle stored: %d candles (%v)", len(gap), err)
+    }
+
+    // Same range again => nothing fetched, not even the empty head
+    stored, err = BackfillCandles(t.Context(), DB, exch, q)
+    if err != nil || stored != 0 {
+        t.Errorf("Covered backfill\nExpected:\t0\nGot:\t\t%d (%v)", stored, err)
+    }
+
+    // Exchange now has 2 more candles and different history
+    srv.SetCandles("trade", "PF_ETHUSD", "1h", series(12, 2600))
+    q.To = start.Add(11 * time.Hour)
+    candles, err := LoadCandles(t.Context(), DB, exch, q)
+    if err != nil {
+        t.Fatalf("LoadCandles failed: %v", err)
+    }
+    if len(candles) != 12 {
+        t.Fatalf("Wrong number of candles\nExpected:\t12\nGot:\t\t%d", len(candles))
+    }
+    if candles[0].Close != 2500 || candles[5].Close != 2500 || candles[6].Close != 2600 || candles[11].Close != 2600 {
+        t.Errorf("Wrong refetch: first %v, last covered %v, first new %v, newest %v", candles[0].Close, candles[5].Close, candles[6].Close, candles[11].Close)
+    }
+    if filler := candles[3]; filler.Volume != 0 || filler.Close != 2500 {
+        t.Errorf("Gap not filled on read: %+v", filler)
+    }
+
+    from, to, err := dbfns.ReadCandleCoverage(DB, "trade", "PF_ETHUSD", "1h")
+    if err != nil || !from.Equal(q.From) || !to.Equal(start.Add(12*time.Hour)) {
+        t.Errorf("Wrong coverage\nExpected:\t%v - %v\nGot:\t\t%v - %v (%v)", q.From, start.Add(12*time.Hour), from, to, err)
+    }
+}
+//}}} Backfill
@@ -0,0 +1,156 @@
+package jobs
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "log"
+    "sort"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/api"
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/api/kraken-ftr"
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/cipher"
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/db"
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+// Mirrors exchange fills into order_fills so ReadAvgPrice reflects real trading
+
+
+//{{{ Exchange for user
+// Decrypts user's stored API keys and builds Kraken client for them
+// opts as for krakenftr.New, ex.: shared WithHTTPClient
+func ExchangeForUser(db *sql.DB, username, password, baseURL string, opts ...krakenftr.Option) (*krakenftr.Exchange, error) {
+    u, err := dbfns.ReadUser(db, username)
+    if err != nil {
+        return nil, fmt.Errorf("Failed to read user %s: %w", username, err)
+    }
+    if u.Salt == nil || u.EncPubKey == nil || u.EncPrivKey == nil {
+        return nil, fmt.Errorf("user %s has no API keys stored", username)
+    }
+    publicKey, err := cipherfns.DecryptApiKey(*u.Salt, password, *u.EncPubKey)
+    if err != nil {
+        return nil, fmt.Errorf("Failed to decrypt public key: %w", err)
+    }
+    privateKey, err := cipherfns.DecryptApiKey(*u.Salt, password, *u.EncPrivKey)
+    if err != nil {
+        return nil, fmt.Errorf("Failed to decrypt private key: %w", err)
+    }
+    return krakenftr.New(baseURL, publicKey, privateKey, opts...), nil
+}
+//}}} Exchange for user
+
+
+//{{{ Sync fills
+// Pulls fills newer than last stored one and inserts them, returns number of new rows
+// Re-fetching fill at exactly last date_time is fine, duplicates are skipped by DB
+func SyncUserFills(ctx context.Context, db *sql.DB, exch api.Exchange, owner string) (int, error) {
+    since, err := dbfns.ReadLastFillTime(db, owner)
+    if err != nil {
+        return 0, fmt.Errorf("Failed to read last fill time: %w", err)
+    }
+
+    fills, err := exch.FetchFillsSince(ctx, since)
+    if err != nil {
+        return 0, fmt.Errorf("Failed to fetch fills: %w", err)
+    }
+
+    ofList := make([]types.OrderFill, 0, len(fills))
+    for _, fill := range fills {
+        of, err := fill.ToOrderFill(owner)
+        if err != nil {
+            return 0, fmt.Errorf("Failed to map fill %s: %w", fill.FillId, err)
+        }
+        ofList = append(ofList, of)
+    }
+
+    inserted, err := dbfns.CreateOrderFills(db, ofList)
+    if err != nil {
+        return 0, fmt.Errorf("Failed to store fills: %w", err)
+    }
+    return inserted, nil
+}
+
+
+// One pass over all users (owner => exchange), one failing user does not stop others
+func SyncAllFills(ctx context.Context, db *sql.DB, exchanges map[string]api.Exchange) (int, error) {
+    owners := make([]string, 0, len(exchanges))
+    for owner := range exchanges {
+        owners = append(owners, owner)
+    }
+    sort.Strings(owners)
+
+    total := 0
+    var errs []error
+    for _, owner := range owners {
+        if err := ctx.Err(); err != nil {
+            errs = append(errs, err)
+            break
+        }
+        inserted, err := SyncUserFills(ctx, db, exchanges[owner], owner)
+        if err != nil {
+            errs = append(errs, fmt.Errorf("%s: %w", owner, err))
+            continue
+        }
+        total += inserted
+    }
+    return total, errors.Join(errs...)
+}
+
+
+// Runs SyncAllFills every interval until ctx is cancelled (in-flight requests are aborted too)
+func RunFillSync(ctx context.Context, db *sql.DB, exchanges map[string]api.Exchange, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        inserted, err := SyncAllFills(ctx, db, exchanges)
+        if err != nil && ctx.Err() == nil {
+            log.Printf("Fill sync failed: %v", err)
+        }
+        if inserted > 0 {
+            log.Printf("Fill sync stored %d new fill(s)", inserted)
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+//}}} Sync fills
+
+
+//{{{ Stream fills
+// Stores fills as they arrive on `fills` feed (FeedClient.Fills), snapshot
+// included, returns when ctx is done or channel is closed. RunFillSync can
+// stay on as a slow backstop, duplicates are skipped by DB
+// Fill that fails to map or insert is returned right away, nothing from its
+// batch or newer is stored, so SyncUserFills (from last stored fill) still gets it
+func StoreFeedFills(ctx context.Context, db *sql.DB, fills <-chan types.FillsEvent, owner string) error {
+    for {
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case ev, ok := <-fills:
+            if !ok {
+                return nil
+            }
+            ofList := make([]types.OrderFill, 0, len(ev.Fills))
+            for _, ff := range ev.Fills {
+                fill := ff.ToFill()
+                of, err := fill.ToOrderFill(owner)
+                if err != nil {
+                    return fmt.Errorf("Failed to map feed fill %s: %w", ff.FillId, err)
+                }
+                ofList = append(ofList, of)
+            }
+            if _, err := dbfns.CreateOrderFills(db, ofList); err != nil {
+                return fmt.Errorf("Failed to store feed fills: %w", err)
+            }
+        }
+    }
+}
+//}}} Stream fills
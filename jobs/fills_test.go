@@ -0,0 +1,139 @@
+package jobs
+
+import (
+    "math"
+    "testing"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/api"
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/api/kraken-ftr"
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/api/kraken-ftr/krakenfake"
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/db"
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+
+
+const (
+    fakePublicKey   = "fake-public-key"
+    fakePrivateKey  = "BwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyAhIiMkJSYnKCkqKywtLi8wMTIzNDU2Nzg5Ojs8PT4/QEFCQ0RFRg=="
+)
+
+
+func newFakeExchange(t *testing.T) (*krakenftr.Exchange, *krakenfake.Server) {
+    t.Helper()
+    srv := krakenfake.New(fakePublicKey, fakePrivateKey)
+    t.Cleanup(srv.Close)
+    return krakenftr.New(srv.URL, fakePublicKey, fakePrivateKey), srv
+}
+
+
+//{{{ Sync fills
+func TestSyncUserFills(t *testing.T) {
+    owner := "test_user_for_fill_sync"
+    if err := dbfns.CreateUser(DB, types.User{ Username: owner }); err != nil {
+        t.Fatalf("Failed to create user: %v", err)
+    }
+    exch, srv := newFakeExchange(t)
+    srv.AddFills(
+        types.Fill{FillId: "d1000000-0000-4000-8000-000000000001", Symbol: "PF_XRPUSD", Side: "buy", OrderId: "o1", Size: 10, Price: 2.5, FillTime: "2025-09-20T10:00:00.000Z", FillType: "maker"},
+        types.Fill{FillId: "d1000000-0000-4000-8000-000000000002", Symbol: "PF_XRPUSD", Side: "buy", OrderId: "o2", Size: 10, Price: 3.5, FillTime: "2025-09-21T10:00:00.000Z", FillType: "taker"},
+    )
+
+    // First sync stores everything
+    inserted, err := SyncUserFills(t.Context(), DB, exch, owner)
+    if err != nil {
+        t.Fatalf("SyncUserFills failed: %v", err)
+    }
+    if inserted != 2 {
+        t.Errorf("Wrong number inserted\nExpected:\t2\nGot:\t\t%d", inserted)
+    }
+
+    // Nothing new, last fill is re-fetched but skipped
+    inserted, err = SyncUserFills(t.Context(), DB, exch, owner)
+    if err != nil {
+        t.Fatalf("SyncUserFills failed: %v", err)
+    }
+    if inserted != 0 {
+        t.Errorf("Wrong number inserted\nExpected:\t0\nGot:\t\t%d", inserted)
+    }
+
+    // New fill arrives
+    srv.AddFills(types.Fill{FillId: "d1000000-0000-4000-8000-000000000003", Symbol: "PF_XRPUSD", Side: "sell", OrderId: "o3", Size: 5, Price: 4, FillTime: "2025-09-22T10:00:00.000Z", FillType: "taker"})
+    exchanges := map[string]api.Exchange{owner: exch}
+    inserted, err = SyncAllFills(t.Context(), DB, exchanges)
+    if err != nil {
+        t.Fatalf("SyncAllFills failed: %v", err)
+    }
+    if inserted != 1 {
+        t.Errorf("Wrong number inserted\nExpected:\t1\nGot:\t\t%d", inserted)
+    }
+
+    // Mapped fields end up usable by ReadAvgPrice, (2.5*25 + 3.5*35) / 60
+    avgEntry, volume, err := dbfns.ReadAvgPrice(DB, owner, "XRP", "buy", "USD", 3650)
+    if err != nil {
+        t.Fatalf("ReadAvgPrice failed: %v", err)
+    }
+    if math.Round(avgEntry*1000) != math.Round(3.0833*1000) || volume != 60 {
+        t.Errorf("Wrong avg/volume\nExpected:\t3.0833/60\nGot:\t\t%.4f/%.4f", avgEntry, volume)
+    }
+}
+//}}} Sync fills
+
+
+//{{{ Stream fills
+func TestStoreFeedFills(t *testing.T) {
+    owner := "test_user_for_feed_fills"
+    if err := dbfns.CreateUser(DB, types.User{ Username: owner }); err != nil {
+        t.Fatalf("Failed to create user: %v", err)
+    }
+    fill := types.FeedFill{Instrument: "PF_XRPUSD", Time: 1758621600000, Price: 2.5, Buy: true, Qty: 10, OrderId: "o1", FillId: "d2000000-0000-4000-8000-000000000001", FillType: "maker"}
+    fills := make(chan types.FillsEvent, 2)
+    fills <- types.FillsEvent{Feed: "fills_snapshot", Fills: []types.FeedFill{fill}}
+    // Same fill again (ex.: snapshot after reconnect) is skipped
+    fills <- types.FillsEvent{Feed: "fills", Fills: []types.FeedFill{fill}}
+    close(fills)
+
+    if err := StoreFeedFills(t.Context(), DB, fills, owner); err != nil {
+        t.Fatalf("StoreFeedFills failed: %v", err)
+    }
+    last, err := dbfns.ReadLastFillTime(DB, owner)
+    if err != nil {
+        t.Fatalf("ReadLastFillTime failed: %v", err)
+    }
+    if expected := time.UnixMilli(fill.Time).UTC(); !last.Equal(expected) {
+        t.Errorf("Wrong last fill time\nExpected:\t%v\nGot:\t\t%v", expected, last)
+    }
+
+    // Unknown owner => insert fails, error returned and nothing after it stored
+    later := fill
+    later.FillId, later.Time = "d2000000-0000-4000-8000-000000000002", fill.Time+1000
+    failing := make(chan types.FillsEvent, 2)
+    failing <- types.FillsEvent{Feed: "fills", Fills: []types.FeedFill{later}}
+    failing <- types.FillsEvent{Feed: "fills", Fills: []types.FeedFill{later}}
+    close(failing)
+    if err := StoreFeedFills(t.Context(), DB, failing, "no_such_owner"); err == nil {
+        t.Fatalf("Expected insert error, got nil")
+    }
+    if len(failing) != 1 {
+        t.Errorf("Events consumed after failure\nExpected:\t1 left\nGot:\t\t%d left", len(failing))
+    }
+
+    // Unmappable fill => error returned, rest of its batch not stored
+    unknown := later
+    unknown.Instrument, unknown.FillId = "XX_XRPUSD", "d2000000-0000-4000-8000-000000000003"
+    unmappable := make(chan types.FillsEvent, 1)
+    unmappable <- types.FillsEvent{Feed: "fills", Fills: []types.FeedFill{unknown, later}}
+    close(unmappable)
+    if err := StoreFeedFills(t.Context(), DB, unmappable, owner); err == nil {
+        t.Fatalf("Expected mapping error, got nil")
+    }
+    last, err = dbfns.ReadLastFillTime(DB, owner)
+    if err != nil {
+        t.Fatalf("ReadLastFillTime failed: %v", err)
+    }
+    if expected := time.UnixMilli(fill.Time).UTC(); !last.Equal(expected) {
+        t.Errorf("Fill after unmappable one stored\nExpected:\t%v\nGot:\t\t%v", expected, last)
+    }
+}
+//}}} Stream fills
@@ -0,0 +1,99 @@
+package jobs
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "log"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/api/kraken-ftr"
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/db"
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+// Mirrors funding rate history of perpetuals into funding_rates, estimator
+// projects funding cost of open position from stored rates
+
+
+//{{{ Sync funding rates
+type fundingSource interface {
+    FetchFundingRates(ctx context.Context, symbol string, since time.Time) ([]types.FundingRate, error)
+}
+var _ fundingSource = (*krakenftr.Exchange)(nil)
+
+
+// Stores rates newer than last stored one, returns number of new rows
+// Rate at exactly last date_time comes again, DB skips it
+func SyncFundingRates(ctx context.Context, db *sql.DB, exch fundingSource, symbol string) (int, error) {
+    since, err := dbfns.ReadLastFundingTime(db, symbol)
+    if err != nil {
+        return 0, fmt.Errorf("Failed to read last funding time: %w", err)
+    }
+    rates, err := exch.FetchFundingRates(ctx, symbol, since)
+    if err != nil {
+        return 0, fmt.Errorf("Failed to fetch funding rates: %w", err)
+    }
+    inserted, err := dbfns.CreateFundingRates(db, rates)
+    if err != nil {
+        return 0, fmt.Errorf("Failed to store funding rates: %w", err)
+    }
+    return inserted, nil
+}
+
+
+// One pass over symbols, one failing symbol does not stop others
+func SyncAllFundingRates(ctx context.Context, db *sql.DB, exch fundingSource, symbols []string) (int, error) {
+    total := 0
+    var errs []error
+    for _, symbol := range symbols {
+        if err := ctx.Err(); err != nil {
+            errs = append(errs, err)
+            break
+        }
+        inserted, err := SyncFundingRates(ctx, db, exch, symbol)
+        if err != nil {
+            errs = append(errs, fmt.Errorf("%s: %w", symbol, err))
+            continue
+        }
+        total += inserted
+    }
+    return total, errors.Join(errs...)
+}
+
+
+// Runs SyncAllFundingRates every interval until ctx is cancelled
+func RunFundingSync(ctx context.Context, db *sql.DB, exch fundingSource, symbols []string, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        inserted, err := SyncAllFundingRates(ctx, db, exch, symbols)
+        if err != nil && ctx.Err() == nil {
+            log.Printf("Funding sync failed: %v", err)
+        }
+        if inserted > 0 {
+            log.Printf("Funding sync stored %d new rate(s)", inserted)
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+//}}} Sync funding rates
+
+
+//{{{ Estimate funding
+// Projects funding of pos over horizon from rates stored in last lookback,
+// price is current mark (0 => entry price); run SyncFundingRates first
+func EstimatePositionFunding(db *sql.DB, pos types.OpenPosition, price float64, lookback, horizon time.Duration) (types.FundingEstimate, error) {
+    rates, err := dbfns.ReadFundingRates(db, pos.Symbol, time.Now().Add(-lookback), time.Time{})
+    if err != nil {
+        return types.FundingEstimate{}, fmt.Errorf("Failed to read funding rates: %w", err)
+    }
+    return types.EstimateFunding(pos, price, rates, horizon)
+}
+//}}} Estimate funding
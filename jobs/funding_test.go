@@ -0,0 +1,44 @@
+package jobs
+
+import (
+    "math"
+    "testing"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+
+
+//{{{ Sync funding rates
+// Second sync only picks up what is new, estimate runs on stored rates
+func TestSyncFundingRates(t *testing.T) {
+    exch, srv := newFakeExchange(t)
+    symbol := "PF_ETHUSD"
+    start := time.Now().UTC().Truncate(time.Hour).Add(-4 * time.Hour)
+    rate := func(i int) types.HistoricalFundingRate {
+        return types.HistoricalFundingRate{Timestamp: start.Add(time.Duration(i) * time.Hour).Format(time.RFC3339), FundingRate: 0.5, RelativeFundingRate: 0.0002}
+    }
+    srv.SetFundingRates(symbol, []types.HistoricalFundingRate{rate(0), rate(1), rate(2)})
+
+    inserted, err := SyncFundingRates(t.Context(), DB, exch, symbol)
+    if err != nil || inserted != 3 {
+        t.Fatalf("First sync\nExpected:\t3\nGot:\t\t%d (%v)", inserted, err)
+    }
+    srv.SetFundingRates(symbol, []types.HistoricalFundingRate{rate(0), rate(1), rate(2), rate(3)})
+    inserted, err = SyncAllFundingRates(t.Context(), DB, exch, []string{symbol})
+    if err != nil || inserted != 1 {
+        t.Fatalf("Second sync\nExpected:\t1\nGot:\t\t%d (%v)", inserted, err)
+    }
+
+    pos := types.OpenPosition{Side: "short", Symbol: symbol, Size: 10, Price: 2500}
+    est, err := EstimatePositionFunding(DB, pos, 0, 24*time.Hour, 8*time.Hour)
+    if err != nil {
+        t.Fatalf("EstimatePositionFunding failed: %v", err)
+    }
+    // Short with positive rate receives: 10 * 2500 * 0.0002 * 8
+    if expected := -40.0; math.Abs(est.Amount-expected) > 1e-9 {
+        t.Errorf("Wrong estimate\nExpected:\t%v\nGot:\t\t%v", expected, est.Amount)
+    }
+}
+//}}} Sync funding rates
@@ -0,0 +1,96 @@
+package jobs
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/api/kraken-ftr"
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/db"
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+// Every transfer attempt ends up in transfers table (audit), also the ones
+// rejected by balance check or by exchange; malformed requests are not
+// attempts and are only returned
+
+
+//{{{ Transfer status
+// Nothing moved for balance check and errors Kraken answered with, anything else
+// (transport, 5xx, lost response) may have gone through
+func transferStatus(err error) string {
+    if err == nil {
+        return "success"
+    }
+    var (
+        exchangeErr     *krakenftr.ExchangeError
+        authErr         *krakenftr.AuthError
+        nonceErr        *krakenftr.NonceError
+        rateLimitErr    *krakenftr.RateLimitError
+    )
+    switch {
+    case errors.As(err, &exchangeErr), errors.As(err, &authErr),
+        errors.As(err, &nonceErr), errors.As(err, &rateLimitErr),
+        errors.Is(err, krakenftr.ErrInsufficientBalance), errors.Is(err, krakenftr.ErrUnknownAccount),
+        errors.Is(err, krakenftr.ErrBalanceCheckFailed):
+        return "rejected"
+    }
+    return "unknown"
+}
+
+
+func recordTransfer(db *sql.DB, tr types.Transfer, transferErr error) error {
+    var validationErr *types.ValidationError
+    if errors.As(transferErr, &validationErr) {
+        return transferErr
+    }
+    tr.Status = transferStatus(transferErr)
+    tr.DateTime = time.Now().UTC()
+    if transferErr != nil {
+        msg := transferErr.Error()
+        tr.Error = &msg
+    }
+    if _, err := dbfns.CreateTransfer(db, tr); err != nil {
+        return errors.Join(transferErr, fmt.Errorf("Failed to record transfer: %w", err))
+    }
+    return transferErr
+}
+//}}} Transfer status
+
+
+//{{{ Transfer
+type transferer interface {
+    Transfer(ctx context.Context, req types.TransferRequest) (*types.TransferResponse, error)
+    SubaccountTransfer(ctx context.Context, req types.SubaccountTransferRequest) (*types.TransferResponse, error)
+}
+var _ transferer = (*krakenftr.Exchange)(nil)
+
+
+// Between owner's own futures accounts, error is the transfer's (or audit insert's)
+func TransferAndRecord(ctx context.Context, db *sql.DB, exch transferer, owner string, req types.TransferRequest) error {
+    _, err := exch.Transfer(ctx, req)
+    return recordTransfer(db, types.Transfer{
+        Owner:          owner,
+        FromAccount:    req.FromAccount,
+        ToAccount:      req.ToAccount,
+        Unit:           req.Unit,
+        Amount:         req.Amount,
+    }, err)
+}
+
+
+func SubaccountTransferAndRecord(ctx context.Context, db *sql.DB, exch transferer, owner string, req types.SubaccountTransferRequest) error {
+    _, err := exch.SubaccountTransfer(ctx, req)
+    return recordTransfer(db, types.Transfer{
+        Owner:          owner,
+        FromUser:       &req.FromUser,
+        ToUser:         &req.ToUser,
+        FromAccount:    req.FromAccount,
+        ToAccount:      req.ToAccount,
+        Unit:           req.Unit,
+        Amount:         req.Amount,
+    }, err)
+}
+//}}} Transfer
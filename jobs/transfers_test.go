@@ -0,0 +1,87 @@
+package jobs
+
+import (
+    "errors"
+    "fmt"
+    "testing"
+    "time"
+)
+import (
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/api/kraken-ftr"
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/db"
+    "github.com/FAH2S/illusory-exchange-of-scarlet-fortune/types"
+)
+
+
+//{{{ Transfer status
+func TestTransferStatus(t *testing.T) {
+    tests := []struct {
+        name    string
+        err     error
+        status  string
+    }{
+        {"Success",         nil,                                                                    "success"},
+        {"Overdraft",       fmt.Errorf("flex: %w", krakenftr.ErrInsufficientBalance),               "rejected"},
+        {"Exchange",        &krakenftr.ExchangeError{Code: "insufficientAvailableFunds"},           "rejected"},
+        {"NoAccounts",      fmt.Errorf("%w: boom", krakenftr.ErrBalanceCheckFailed),                "rejected"},
+        {"LostResponse",    &krakenftr.StatusError{StatusCode: 502},                                "unknown"},
+        {"Transport",       errors.New("connection reset"),                                        "unknown"},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            if got := transferStatus(tc.err); got != tc.status {
+                t.Errorf("Expected:\t%s\nGot:\t\t%s", tc.status, got)
+            }
+        })
+    }
+}
+//}}} Transfer status
+
+
+//{{{ Transfer and record
+func TestTransferAndRecord(t *testing.T) {
+    owner := "test_user_for_transfer_audit"
+    if err := dbfns.CreateUser(DB, types.User{Username: owner}); err != nil {
+        t.Fatalf("Failed to create user: %v", err)
+    }
+    exch, srv := newFakeExchange(t)
+    srv.SetAccounts(types.Accounts{
+        Cash:   &types.CashAccount{Balances: map[string]float64{"usd": 0}},
+        Flex:   &types.FlexAccount{Currencies: map[string]types.FlexAccountCurrency{"USD": {Available: 100}}},
+    })
+    since := time.Now().Add(-time.Minute)
+
+    if err := TransferAndRecord(t.Context(), DB, exch, owner, types.TransferRequest{FromAccount: "flex", ToAccount: "cash", Unit: "usd", Amount: 60}); err != nil {
+        t.Fatalf("TransferAndRecord failed: %v", err)
+    }
+    err := TransferAndRecord(t.Context(), DB, exch, owner, types.TransferRequest{FromAccount: "flex", ToAccount: "cash", Unit: "usd", Amount: 60})
+    if !errors.Is(err, krakenftr.ErrInsufficientBalance) {
+        t.Fatalf("Expected ErrInsufficientBalance, got %v", err)
+    }
+    // Malformed, not recorded
+    if err := TransferAndRecord(t.Context(), DB, exch, owner, types.TransferRequest{FromAccount: "flex", ToAccount: "cash", Unit: "usd"}); err == nil {
+        t.Fatalf("Expected validation error")
+    }
+    if err := SubaccountTransferAndRecord(t.Context(), DB, exch, owner, types.SubaccountTransferRequest{FromUser: "sub-1", ToUser: "master", FromAccount: "flex", ToAccount: "flex", Unit: "usd", Amount: 5, SkipBalanceCheck: true}); err != nil {
+        t.Fatalf("SubaccountTransferAndRecord failed: %v", err)
+    }
+
+    transfers, err := dbfns.ReadTransfers(DB, owner, since)
+    if err != nil {
+        t.Fatalf("ReadTransfers failed: %v", err)
+    }
+    expected := []string{"success", "rejected", "success"}
+    if len(transfers) != len(expected) {
+        t.Fatalf("Wrong number of transfers\nExpected:\t%d\nGot:\t\t%d", len(expected), len(transfers))
+    }
+    for i, status := range expected {
+        if transfers[i].Status != status {
+            t.Errorf("Wrong status %d\nExpected:\t%s\nGot:\t\t%s", i, status, transfers[i].Status)
+        }
+    }
+    if transfers[2].ToUser == nil || *transfers[2].ToUser != "master" {
+        t.Errorf("Wrong sub account transfer: %+v", transfers[2])
+    }
+}
+//}}} Transfer and record
@@ -0,0 +1,208 @@
+package types
+
+import (
+    "encoding/json"
+    "fmt"
+    "strings"
+)
+// /accounts returns one object keyed by account name, shape depends on
+// `type`: "cash" (cashAccount), single-collateral margin accounts
+// (marginAccount, ex.: "fi_xbtusd") and "flex" (multiCollateralMarginAccount).
+
+
+//{{{ Account-s
+type CashAccount struct {
+    Type        string              `json:"type"`
+    Balances    map[string]float64  `json:"balances"`
+}
+
+
+// Single-collateral values are in account currency (ex.: xbt for fi_xbtusd)
+type MarginAuxiliary struct {
+    Usd         float64 `json:"usd"`
+    Pv          float64 `json:"pv"`        // portfolio value
+    Pnl         float64 `json:"pnl"`
+    Af          float64 `json:"af"`        // available funds
+    Funding     float64 `json:"funding"`
+}
+// Initial, maintenance margin, liquidation and termination threshold
+type MarginLevels struct {
+    Im          float64 `json:"im"`
+    Mm          float64 `json:"mm"`
+    Lt          float64 `json:"lt"`
+    Tt          float64 `json:"tt"`
+}
+type MarginAccount struct {
+    Type                string              `json:"type"`
+    Currency            string              `json:"currency"`
+    Balances            map[string]float64  `json:"balances"`
+    Auxiliary           MarginAuxiliary     `json:"auxiliary"`
+    MarginRequirements  MarginLevels        `json:"marginRequirements"`
+    TriggerEstimates    MarginLevels        `json:"triggerEstimates"`  // prices at which levels are hit
+}
+
+
+// Values in USD
+type FlexAccountCurrency struct {
+    Quantity    float64 `json:"quantity"`
+    Value       float64 `json:"value"`
+    Collateral  float64 `json:"collateral"`
+    Available   float64 `json:"available"`
+}
+type FlexAccount struct {
+    Type                    string                          `json:"type"`
+    Currencies              map[string]FlexAccountCurrency  `json:"currencies"`
+    InitialMargin           float64                         `json:"initialMargin"`
+    InitialMarginWithOrders float64                         `json:"initialMarginWithOrders"`
+    MaintenanceMargin       float64                         `json:"maintenanceMargin"`
+    BalanceValue            float64                         `json:"balanceValue"`
+    PortfolioValue          float64                         `json:"portfolioValue"`
+    CollateralValue         float64                         `json:"collateralValue"`
+    Pnl                     float64                         `json:"pnl"`
+    UnrealizedFunding       float64                         `json:"unrealizedFunding"`
+    TotalUnrealized         float64                         `json:"totalUnrealized"`
+    TotalUnrealizedAsMargin float64                         `json:"totalUnrealizedAsMargin"`
+    AvailableMargin         float64                         `json:"availableMargin"`
+    MarginEquity            float64                         `json:"marginEquity"`
+}
+//}}} Account-s
+
+
+//{{{ Accounts
+// Cash and Flex are nil when account does not have them
+type Accounts struct {
+    Cash    *CashAccount
+    Margin  map[string]MarginAccount    // key: account name, ex.: fi_xbtusd
+    Flex    *FlexAccount
+}
+
+
+func (a *Accounts) UnmarshalJSON(data []byte) error {
+    var raw map[string]json.RawMessage
+    if err := json.Unmarshal(data, &raw); err != nil {
+        return err
+    }
+    *a = Accounts{Margin: map[string]MarginAccount{}}
+    for name, body := range raw {
+        var head struct {
+            Type string `json:"type"`
+        }
+        if err := json.Unmarshal(body, &head); err != nil {
+            return fmt.Errorf("account %s: %w", name, err)
+        }
+        var err error
+        switch head.Type {
+        case "cashAccount":
+            a.Cash = &CashAccount{}
+            err = json.Unmarshal(body, a.Cash)
+        case "marginAccount":
+            var m MarginAccount
+            err = json.Unmarshal(body, &m)
+            a.Margin[name] = m
+        case "multiCollateralMarginAccount":
+            a.Flex = &FlexAccount{}
+            err = json.Unmarshal(body, a.Flex)
+        default:
+            // Unknown kinds are skipped, not worth failing whole call
+            continue
+        }
+        if err != nil {
+            return fmt.Errorf("account %s: %w", name, err)
+        }
+    }
+    return nil
+}
+
+
+// `type` is set from the field account sits in, no need to fill it
+func (a Accounts) MarshalJSON() ([]byte, error) {
+    out := map[string]any{}
+    if a.Cash != nil {
+        cash := *a.Cash
+        cash.Type = "cashAccount"
+        out["cash"] = cash
+    }
+    for name, m := range a.Margin {
+        m.Type = "marginAccount"
+        out[name] = m
+    }
+    if a.Flex != nil {
+        flex := *a.Flex
+        flex.Type = "multiCollateralMarginAccount"
+        out["flex"] = flex
+    }
+    return json.Marshal(out)
+}
+
+
+// USD equity for position sizing: flex margin equity plus portfolio value of
+// single-collateral accounts held in usd (other currencies need a price, skipped)
+func (a Accounts) Equity() float64 {
+    equity := 0.0
+    if a.Flex != nil {
+        equity += a.Flex.MarginEquity
+    }
+    for _, m := range a.Margin {
+        if m.Currency == "usd" {
+            equity += m.Auxiliary.Pv
+        }
+    }
+    return equity
+}
+
+
+// USD margin that can still be used for new orders, same scope as Equity
+func (a Accounts) AvailableMargin() float64 {
+    available := 0.0
+    if a.Flex != nil {
+        available += a.Flex.AvailableMargin
+    }
+    for _, m := range a.Margin {
+        if m.Currency == "usd" {
+            available += m.Auxiliary.Af
+        }
+    }
+    return available
+}
+
+
+// Balance of unit that can leave account (cash, flex or margin account name),
+// false when account or unit is not there; unit is case insensitive
+func (a Accounts) Available(account, unit string) (float64, bool) {
+    switch {
+    case account == "cash" && a.Cash != nil:
+        return lookupFold(a.Cash.Balances, unit)
+    case account == "flex" && a.Flex != nil:
+        for currency, c := range a.Flex.Currencies {
+            if strings.EqualFold(currency, unit) {
+                return c.Available, true
+            }
+        }
+        return 0, false
+    }
+    if m, ok := a.Margin[account]; ok {
+        return lookupFold(m.Balances, unit)
+    }
+    return 0, false
+}
+
+
+func lookupFold(balances map[string]float64, key string) (float64, bool) {
+    for k, v := range balances {
+        if strings.EqualFold(k, key) {
+            return v, true
+        }
+    }
+    return 0, false
+}
+
+
+type AccountsResponse struct {
+    Result      string      `json:"result"`
+    ServerTime  string      `json:"serverTime"`
+    // Optional, only on success
+    Accounts    Accounts    `json:"accounts"`
+    // Optional, only on failure
+    Error       *string     `json:"error,omitempty"`
+}
+//}}} Accounts
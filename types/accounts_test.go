@@ -0,0 +1,52 @@
+package types
+
+import (
+    "encoding/json"
+    "testing"
+)
+
+
+//{{{ Accounts
+// Shape taken from Kraken docs, one of each account type plus unknown one
+func TestAccountsUnmarshal(t *testing.T) {
+    data := `{
+        "cash": {"type": "cashAccount", "balances": {"xbt": 141.3, "usd": 10}},
+        "fi_xbtusd": {"type": "marginAccount", "currency": "xbt", "balances": {"xbt": 2},
+            "auxiliary": {"usd": 0, "pv": 2.1, "pnl": 0.1, "af": 1.5, "funding": 0},
+            "marginRequirements": {"im": 0.5, "mm": 0.2, "lt": 0.15, "tt": 0.1},
+            "triggerEstimates": {"im": 3000, "mm": 2500, "lt": 2400, "tt": 2300}},
+        "fi_usd": {"type": "marginAccount", "currency": "usd", "auxiliary": {"pv": 200, "af": 150}},
+        "flex": {"type": "multiCollateralMarginAccount", "currencies": {"USD": {"quantity": 1000, "value": 1000, "collateral": 1000, "available": 950}},
+            "initialMargin": 50, "maintenanceMargin": 25, "availableMargin": 940, "marginEquity": 990, "portfolioValue": 1000},
+        "something_new": {"type": "futureAccountType"}
+    }`
+    var accounts Accounts
+    if err := json.Unmarshal([]byte(data), &accounts); err != nil {
+        t.Fatalf("Unmarshal failed: %v", err)
+    }
+
+    tests := []struct {
+        name        string
+        got         float64
+        expected    float64
+    }{
+        {"CashXbt",         accounts.Cash.Balances["xbt"],                          141.3},
+        {"MarginIm",        accounts.Margin["fi_xbtusd"].MarginRequirements.Im,     0.5},
+        {"MarginTrigger",   accounts.Margin["fi_xbtusd"].TriggerEstimates.Lt,       2400},
+        {"FlexAvailable",   accounts.Flex.Currencies["USD"].Available,              950},
+        {"Equity",          accounts.Equity(),                                      990 + 200},
+        {"AvailableMargin", accounts.AvailableMargin(),                             940 + 150},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            if tc.got != tc.expected {
+                t.Errorf("Expected:\t%v\nGot:\t\t%v", tc.expected, tc.got)
+            }
+        })
+    }
+    if len(accounts.Margin) != 2 {
+        t.Errorf("Wrong number of margin accounts\nExpected:\t2\nGot:\t\t%d", len(accounts.Margin))
+    }
+}
+//}}} Accounts
@@ -0,0 +1,175 @@
+package types
+
+import (
+    "crypto/rand"
+    "encoding/hex"
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+    "sync/atomic"
+)
+// cliOrdId has to be unique, price alone is not enough (two orders at same
+// price collide) so every id carries session + sequence:
+//  {tag}:{symbol}:{orderType}:{side}@{limitPrice}[:trig@{stopPrice}-{triggerSignal}]:{session}.{seq}
+//  ex.: `grid:PF_BCHUSD:post:buy@550:3f9a1c.17`
+//  ex.: `:PF_BCHUSD:stp:sell@650:trig@550-last:3f9a1c.18`     (no tag)
+// Prices are rounded to 8 decimals with trailing zeros dropped, so float noise
+// (3.0000000000000004 => 3) does not blow up length; krakenftr still checks
+// final length with ValidateCliOrdId.
+
+
+//{{{ Strategy
+// Pluggable strategy, krakenftr uses it for orders sent without cliOrdId
+type CliOrdIdGenerator interface {
+    NextCliOrdId(sor SendOrderRequest) string
+}
+
+
+// What can be read back from cliOrdId made by SequenceGenerator
+type CliOrdIdMeta struct {
+    Tag             string
+    Symbol          string
+    OrderType       string
+    Side            string
+    LimitPrice      float64
+    StopPrice       *float64
+    TriggerSignal   *string
+    Session         string
+    Seq             uint64
+}
+
+
+// Default strategy, safe for concurrent use
+type SequenceGenerator struct {
+    tag     string
+    session string
+    seq     atomic.Uint64
+}
+
+
+var tagMatch = regexp.MustCompile(`^[A-Za-z0-9_.]{0,16}$`)
+
+
+// Random 6 hex char session so ids don't collide across restarts
+func NewSequenceGenerator(tag string) (*SequenceGenerator, error) {
+    bytes := make([]byte, 3)
+    if _, err := rand.Read(bytes); err != nil {
+        return nil, fmt.Errorf("Failed to generate session: %v", err)
+    }
+    return NewSequenceGeneratorWithSession(tag, hex.EncodeToString(bytes), 0)
+}
+
+
+// Deterministic variant (tests, or session/seq restored from storage), next id gets seq = lastSeq+1
+func NewSequenceGeneratorWithSession(tag, session string, lastSeq uint64) (*SequenceGenerator, error) {
+    if !tagMatch.MatchString(tag) {
+        return nil, fmt.Errorf("invalid tag %q: max 16 of [A-Za-z0-9_.]", tag)
+    }
+    if !tagMatch.MatchString(session) || session == "" {
+        return nil, fmt.Errorf("invalid session %q: 1-16 of [A-Za-z0-9_.]", session)
+    }
+    g := &SequenceGenerator{tag: tag, session: session}
+    g.seq.Store(lastSeq)
+    return g, nil
+}
+
+
+// Decimals kept in cliOrdId prices, finer than any Kraken tick size
+const cliOrdIdPriceDecimals = 8
+
+
+func formatPrice(price float64) string {
+    formatted := strconv.FormatFloat(price, 'f', cliOrdIdPriceDecimals, 64)
+    formatted = strings.TrimRight(formatted, "0")
+    return strings.TrimSuffix(formatted, ".")
+}
+
+
+func (g *SequenceGenerator) NextCliOrdId(sor SendOrderRequest) string {
+    var b strings.Builder
+    fmt.Fprintf(&b, "%s:%s:%s:%s@%s", g.tag, sor.Symbol, sor.OrderType, sor.Side, formatPrice(sor.LimitPrice))
+    if sor.StopPrice != nil {
+        signal := ""
+        if sor.TriggerSignal != nil {
+            signal = *sor.TriggerSignal
+        }
+        fmt.Fprintf(&b, ":trig@%s-%s", formatPrice(*sor.StopPrice), signal)
+    }
+    fmt.Fprintf(&b, ":%s.%d", g.session, g.seq.Add(1))
+    return b.String()
+}
+
+
+// Used by CreateLimitOrderId/CreateTriggerEntryOrderId and krakenftr by default
+var DefaultCliOrdIdGenerator CliOrdIdGenerator = mustDefaultGenerator()
+
+
+func mustDefaultGenerator() *SequenceGenerator {
+    g, err := NewSequenceGenerator("")
+    if err != nil {
+        panic(err)
+    }
+    return g
+}
+
+
+// Generated id has to fit too, long tag/dated symbol/trigger can push it over
+func ValidateCliOrdId(cliOrdId string) error {
+    if len(cliOrdId) > MaxCliOrdIdLen {
+        return &ValidationError{Field: "cliOrdId", Reason: fmt.Sprintf("%d characters, max %d", len(cliOrdId), MaxCliOrdIdLen)}
+    }
+    return nil
+}
+//}}} Strategy
+
+
+//{{{ Parse
+var cliOrdIdMatch = regexp.MustCompile(
+    `^([A-Za-z0-9_.]*):([A-Za-z0-9_]+):([a-z_]+):(buy|sell)@([0-9.]+)(?::trig@([0-9.]+)-([a-z]*))?:([A-Za-z0-9_.]+)\.([0-9]+)$`,
+)
+
+
+// Inverse of SequenceGenerator.NextCliOrdId
+func ParseCliOrdId(cliOrdId string) (CliOrdIdMeta, error) {
+    m := cliOrdIdMatch.FindStringSubmatch(cliOrdId)
+    if m == nil {
+        return CliOrdIdMeta{}, fmt.Errorf("cliOrdId %q is not in {tag}:{symbol}:{orderType}:{side}@{price}[:trig@{stop}-{signal}]:{session}.{seq} format", cliOrdId)
+    }
+    meta := CliOrdIdMeta{
+        Tag:        m[1],
+        Symbol:     m[2],
+        OrderType:  m[3],
+        Side:       m[4],
+        Session:    m[8],
+    }
+    var err error
+    if meta.LimitPrice, err = strconv.ParseFloat(m[5], 64); err != nil {
+        return CliOrdIdMeta{}, fmt.Errorf("cliOrdId %q: limit price: %w", cliOrdId, err)
+    }
+    if m[6] != "" {
+        stopPrice, err := strconv.ParseFloat(m[6], 64)
+        if err != nil {
+            return CliOrdIdMeta{}, fmt.Errorf("cliOrdId %q: stop price: %w", cliOrdId, err)
+        }
+        meta.StopPrice = &stopPrice
+        if m[7] != "" {
+            signal := m[7]
+            meta.TriggerSignal = &signal
+        }
+    }
+    if meta.Seq, err = strconv.ParseUint(m[9], 10, 64); err != nil {
+        return CliOrdIdMeta{}, fmt.Errorf("cliOrdId %q: seq: %w", cliOrdId, err)
+    }
+    return meta, nil
+}
+
+
+// For reconciling open orders with what we placed, error when order has no/foreign cliOrdId
+func (oo OpenOrder) CliOrdIdMeta() (CliOrdIdMeta, error) {
+    if oo.CliOrdId == nil {
+        return CliOrdIdMeta{}, fmt.Errorf("order %s has no cliOrdId", oo.OrderId)
+    }
+    return ParseCliOrdId(*oo.CliOrdId)
+}
+//}}} Parse
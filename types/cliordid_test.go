@@ -0,0 +1,161 @@
+package types
+
+import (
+    "errors"
+    "sync"
+    "testing"
+)
+
+
+//{{{ Generate + parse
+func TestCliOrdIdRoundTrip(t *testing.T) {
+    floatPtr := func(f float64) *float64 { return &f }
+    strPtr := func(s string) *string { return &s }
+    g, err := NewSequenceGeneratorWithSession("grid", "abc123", 41)
+    if err != nil {
+        t.Fatalf("NewSequenceGeneratorWithSession failed: %v", err)
+    }
+
+    tests := []struct {
+        name        string
+        sor         SendOrderRequest
+        expectId    string
+    }{
+        {
+            name:       "SuccLimit",
+            sor:        SendOrderRequest{Symbol: "PF_BCHUSD", OrderType: "post", Side: "buy", LimitPrice: 550},
+            expectId:   "grid:PF_BCHUSD:post:buy@550:abc123.42",
+        }, {
+            name:       "SuccTrigger",
+            sor:        SendOrderRequest{Symbol: "PF_BCHUSD", OrderType: "stp", Side: "sell", LimitPrice: 650.5, StopPrice: floatPtr(550.25), TriggerSignal: strPtr("last")},
+            expectId:   "grid:PF_BCHUSD:stp:sell@650.5:trig@550.25-last:abc123.43",
+        }, {
+            name:       "SuccSmallPriceNoExponent",
+            sor:        SendOrderRequest{Symbol: "PF_SHIBUSD", OrderType: "lmt", Side: "buy", LimitPrice: 0.00001234},
+            expectId:   "grid:PF_SHIBUSD:lmt:buy@0.00001234:abc123.44",
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            id := g.NextCliOrdId(tc.sor)
+            if id != tc.expectId {
+                t.Fatalf("Wrong id\nExpected:\t%s\nGot:\t\t%s", tc.expectId, id)
+            }
+            meta, err := ParseCliOrdId(id)
+            if err != nil {
+                t.Fatalf("ParseCliOrdId failed: %v", err)
+            }
+            if meta.Tag != "grid" || meta.Symbol != tc.sor.Symbol || meta.OrderType != tc.sor.OrderType ||
+                meta.Side != tc.sor.Side || meta.LimitPrice != tc.sor.LimitPrice || meta.Session != "abc123" {
+                t.Errorf("Wrong meta: %+v", meta)
+            }
+            if (meta.StopPrice == nil) != (tc.sor.StopPrice == nil) ||
+                (meta.StopPrice != nil && *meta.StopPrice != *tc.sor.StopPrice) {
+                t.Errorf("Wrong stop price: %v", meta.StopPrice)
+            }
+        })
+    }
+}
+
+
+// Float noise rounded away instead of 17 significant digits in id
+func TestCliOrdIdPriceRounding(t *testing.T) {
+    g, err := NewSequenceGeneratorWithSession("grid", "abc123", 0)
+    if err != nil {
+        t.Fatalf("NewSequenceGeneratorWithSession failed: %v", err)
+    }
+    stop := 0.1 + 0.2
+    sor := SendOrderRequest{Symbol: "PF_BCHUSD", OrderType: "stp", Side: "sell", LimitPrice: 3.0000000000000004, StopPrice: &stop}
+    expected := "grid:PF_BCHUSD:stp:sell@3:trig@0.3-:abc123.1"
+    if id := g.NextCliOrdId(sor); id != expected {
+        t.Errorf("Wrong id\nExpected:\t%s\nGot:\t\t%s", expected, id)
+    }
+}
+
+
+// Validate passes before id exists, generated id still has to fit
+func TestValidateCliOrdId(t *testing.T) {
+    floatPtr := func(f float64) *float64 { return &f }
+    strPtr := func(s string) *string { return &s }
+    g, err := NewSequenceGeneratorWithSession("grid_long_tag.01", "session_long_001", 0)
+    if err != nil {
+        t.Fatalf("NewSequenceGeneratorWithSession failed: %v", err)
+    }
+
+    tests := []struct {
+        name        string
+        sor         SendOrderRequest
+        expectErr   bool
+    }{
+        {
+            name:       "SuccLimit",
+            sor:        SendOrderRequest{Symbol: "PF_BCHUSD", OrderType: "post", Side: "buy", Size: 1, LimitPrice: 550},
+            expectErr:  false,
+        }, {
+            name:       "SuccTrigger",
+            sor:        SendOrderRequest{Symbol: "PF_BCHUSD", OrderType: "stp", Side: "sell", Size: 1, LimitPrice: 540, StopPrice: floatPtr(545), TriggerSignal: strPtr("mark")},
+            expectErr:  false,
+        }, {
+            name:       "FailDatedTriggerLongPrices",
+            sor:        SendOrderRequest{Symbol: "FF_XBTUSD_251226", OrderType: "stp", Side: "sell", Size: 1, LimitPrice: 123455.87654321, StopPrice: floatPtr(123456.12345678), TriggerSignal: strPtr("index")},
+            expectErr:  true,
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            if err := tc.sor.Validate(); err != nil {
+                t.Fatalf("Request should pass Validate: %v", err)
+            }
+            id := g.NextCliOrdId(tc.sor)
+            err := ValidateCliOrdId(id)
+            var validationErr *ValidationError
+            if tc.expectErr != errors.As(err, &validationErr) {
+                t.Fatalf("Unexpected result for %q (%d characters): %v", id, len(id), err)
+            }
+            if tc.expectErr && validationErr.Field != "cliOrdId" {
+                t.Errorf("Wrong field\nExpected:\tcliOrdId\nGot:\t\t%s", validationErr.Field)
+            }
+        })
+    }
+}
+
+
+func TestParseCliOrdIdFail(t *testing.T) {
+    for _, id := range []string{"", "test123", "PF_BCHUSD-post:buy@550.00000000", ":PF_BCHUSD:post:long@550:abc.1"} {
+        if _, err := ParseCliOrdId(id); err == nil {
+            t.Errorf("Expected error for %q", id)
+        }
+    }
+    if _, err := (OpenOrder{OrderId: "x"}).CliOrdIdMeta(); err == nil {
+        t.Errorf("Expected error for order without cliOrdId")
+    }
+}
+
+
+// Same price, same side, many goroutines => all unique
+func TestCliOrdIdUnique(t *testing.T) {
+    sor := SendOrderRequest{Symbol: "PF_BCHUSD", OrderType: "post", Side: "buy", Size: 1, LimitPrice: 550}
+    var mu sync.Mutex
+    seen := map[string]bool{}
+    var wg sync.WaitGroup
+    for i := 0; i < 8; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for j := 0; j < 200; j++ {
+                s := sor
+                s.CreateLimitOrderId()
+                mu.Lock()
+                seen[s.CliOrdId] = true
+                mu.Unlock()
+            }
+        }()
+    }
+    wg.Wait()
+    if len(seen) != 1600 {
+        t.Errorf("Collisions\nExpected:\t1600 unique\nGot:\t\t%d", len(seen))
+    }
+}
+//}}} Generate + parse
@@ -0,0 +1,265 @@
+package types
+
+import (
+    "time"
+)
+// WebSocket feed messages (wss://futures.kraken.com/ws/v1), times are unix ms
+// Field names follow the feed as is, it mixes snake_case and camelCase too
+
+
+//{{{ Ticker
+// Feed `ticker`, only fields we care about, feed has more
+type TickerEvent struct {
+    Feed                    string  `json:"feed"`
+    ProductId               string  `json:"product_id"`
+    Time                    int64   `json:"time"`
+    Bid                     float64 `json:"bid"`
+    Ask                     float64 `json:"ask"`
+    BidSize                 float64 `json:"bid_size"`
+    AskSize                 float64 `json:"ask_size"`
+    Last                    float64 `json:"last"`
+    MarkPrice               float64 `json:"markPrice"`
+    Index                   float64 `json:"index"`
+    Volume                  float64 `json:"volume"`
+    Change                  float64 `json:"change"`
+    OpenInterest            float64 `json:"openInterest"`
+    FundingRate             float64 `json:"funding_rate"`
+    FundingRatePrediction   float64 `json:"funding_rate_prediction"`
+    NextFundingRateTime     int64   `json:"next_funding_rate_time"`
+    Suspended               bool    `json:"suspended"`
+    PostOnly                bool    `json:"post_only"`
+}
+//}}} Ticker
+
+
+//{{{ Book
+type BookLevel struct {
+    Price   float64 `json:"price"`
+    Qty     float64 `json:"qty"`
+}
+
+
+// Feed `book_snapshot`, full book sent on subscribe (and resubscribe)
+type BookSnapshot struct {
+    Feed        string      `json:"feed"`
+    ProductId   string      `json:"product_id"`
+    Timestamp   int64       `json:"timestamp"`
+    Seq         int64       `json:"seq"`
+    Bids        []BookLevel `json:"bids"`
+    Asks        []BookLevel `json:"asks"`
+}
+
+
+// Feed `book`, single level update, Qty 0 removes level
+type BookDelta struct {
+    Feed        string  `json:"feed"`
+    ProductId   string  `json:"product_id"`
+    Side        string  `json:"side"`      // buy, sell
+    Seq         int64   `json:"seq"`
+    Price       float64 `json:"price"`
+    Qty         float64 `json:"qty"`
+    Timestamp   int64   `json:"timestamp"`
+}
+//}}} Book
+
+
+//{{{ Trade
+// Feed `trade`, trade_snapshot on subscribe is delivered as separate TradeEvent-s
+type TradeEvent struct {
+    Feed        string  `json:"feed"`
+    ProductId   string  `json:"product_id"`
+    Uid         string  `json:"uid"`
+    Side        string  `json:"side"`
+    Type        string  `json:"type"`      // fill, liquidation, termination, block
+    Seq         int64   `json:"seq"`
+    Time        int64   `json:"time"`
+    Qty         float64 `json:"qty"`
+    Price       float64 `json:"price"`
+}
+type TradeSnapshot struct {
+    Feed        string          `json:"feed"`
+    ProductId   string          `json:"product_id"`
+    Trades      []TradeEvent    `json:"trades"`
+}
+//}}} Trade
+
+
+//{{{ Heartbeat
+type Heartbeat struct {
+    Feed    string  `json:"feed"`
+    Time    int64   `json:"time"`
+}
+//}}} Heartbeat
+
+
+//{{{ Book event
+// Snapshot and deltas go through one channel so consumer sees them in feed order,
+// exactly one of the two is set
+type BookEvent struct {
+    Snapshot    *BookSnapshot
+    Delta       *BookDelta
+}
+//}}} Book event
+
+
+//{{{ Private: open orders
+// Order as the `open_orders` feed sends it, differs from REST OpenOrder
+type FeedOrder struct {
+    Instrument      string  `json:"instrument"`
+    Time            int64   `json:"time"`
+    LastUpdateTime  int64   `json:"last_update_time"`
+    Qty             float64 `json:"qty"`
+    Filled          float64 `json:"filled"`
+    LimitPrice      float64 `json:"limit_price"`
+    StopPrice       float64 `json:"stop_price"`
+    Type            string  `json:"type"`          // limit, stop, take_profit
+    OrderId         string  `json:"order_id"`
+    CliOrdId        string  `json:"cli_ord_id"`
+    Direction       int     `json:"direction"`     // 0 buy, 1 sell
+    ReduceOnly      bool    `json:"reduce_only"`
+    TriggerSignal   string  `json:"triggerSignal"`
+}
+
+
+func (o FeedOrder) Side() string {
+    if o.Direction == 1 {
+        return "sell"
+    }
+    return "buy"
+}
+
+
+// Feed `open_orders_snapshot` (Orders set) or `open_orders` (Order set, or
+// only ids when IsCancel), Reason ex.: new_placed_order_by_user, cancelled_by_user, full_fill
+type OpenOrdersEvent struct {
+    Feed        string      `json:"feed"`
+    Account     string      `json:"account,omitempty"`
+    Orders      []FeedOrder `json:"orders,omitempty"`
+    Order       *FeedOrder  `json:"order,omitempty"`
+    OrderId     string      `json:"order_id,omitempty"`
+    CliOrdId    string      `json:"cli_ord_id,omitempty"`
+    IsCancel    bool        `json:"is_cancel"`
+    Reason      string      `json:"reason,omitempty"`
+}
+
+
+func (e OpenOrdersEvent) IsSnapshot() bool {
+    return e.Feed == "open_orders_snapshot"
+}
+//}}} Private: open orders
+
+
+//{{{ Private: fills
+type FeedFill struct {
+    Instrument  string  `json:"instrument"`
+    Time        int64   `json:"time"`
+    Price       float64 `json:"price"`
+    Seq         int64   `json:"seq"`
+    Buy         bool    `json:"buy"`
+    Qty         float64 `json:"qty"`
+    OrderId     string  `json:"order_id"`
+    CliOrdId    string  `json:"cli_ord_id,omitempty"`
+    FillId      string  `json:"fill_id"`
+    FillType    string  `json:"fill_type"`
+    FeePaid     float64 `json:"fee_paid"`
+    FeeCurrency string  `json:"fee_currency"`
+}
+
+
+// Same shape as REST fills, so feed and polling share ToOrderFill/DB code
+func (f FeedFill) ToFill() Fill {
+    side := "sell"
+    if f.Buy {
+        side = "buy"
+    }
+    fill := Fill{
+        FillId:     f.FillId,
+        Symbol:     f.Instrument,
+        Side:       side,
+        OrderId:    f.OrderId,
+        Size:       f.Qty,
+        Price:      f.Price,
+        FillTime:   time.UnixMilli(f.Time).UTC().Format("2006-01-02T15:04:05.000Z"),
+        FillType:   f.FillType,
+    }
+    if f.CliOrdId != "" {
+        cliOrdId := f.CliOrdId
+        fill.CliOrdId = &cliOrdId
+    }
+    return fill
+}
+
+
+// Feed `fills_snapshot` (last fills on subscribe) or `fills` (new ones)
+type FillsEvent struct {
+    Feed        string      `json:"feed"`
+    Account     string      `json:"account,omitempty"`
+    Username    string      `json:"username,omitempty"`
+    Fills       []FeedFill  `json:"fills"`
+}
+
+
+func (e FillsEvent) IsSnapshot() bool {
+    return e.Feed == "fills_snapshot"
+}
+//}}} Private: fills
+
+
+//{{{ Private: positions
+type FeedPosition struct {
+    Instrument              string  `json:"instrument"`
+    Balance                 float64 `json:"balance"`       // signed size, < 0 short
+    Pnl                     float64 `json:"pnl"`
+    EntryPrice              float64 `json:"entry_price"`
+    MarkPrice               float64 `json:"mark_price"`
+    IndexPrice              float64 `json:"index_price"`
+    LiquidationThreshold    float64 `json:"liquidation_threshold"`
+    EffectiveLeverage       float64 `json:"effective_leverage"`
+    ReturnOnEquity          float64 `json:"return_on_equity"`
+    UnrealizedFunding       float64 `json:"unrealized_funding"`
+}
+
+
+// Feed `open_positions`, always full list
+type PositionsEvent struct {
+    Feed        string          `json:"feed"`
+    Account     string          `json:"account,omitempty"`
+    Positions   []FeedPosition  `json:"positions"`
+    Seq         int64           `json:"seq"`
+    Timestamp   int64           `json:"timestamp"`
+}
+//}}} Private: positions
+
+
+//{{{ Private: balances
+type FlexCurrency struct {
+    Quantity        float64 `json:"quantity"`
+    Value           float64 `json:"value"`
+    CollateralValue float64 `json:"collateral_value"`
+    AvailableMargin float64 `json:"available_margin"`
+}
+// Multi-collateral wallet summary
+type FlexFuturesBalance struct {
+    Currencies          map[string]FlexCurrency `json:"currencies"`
+    BalanceValue        float64                 `json:"balance_value"`
+    PortfolioValue      float64                 `json:"portfolio_value"`
+    CollateralValue     float64                 `json:"collateral_value"`
+    InitialMargin       float64                 `json:"initial_margin"`
+    MaintenanceMargin   float64                 `json:"maintenance_margin"`
+    Pnl                 float64                 `json:"pnl"`
+    UnrealizedFunding   float64                 `json:"unrealized_funding"`
+    AvailableMargin     float64                 `json:"available_margin"`
+    MarginEquity        float64                 `json:"margin_equity"`
+}
+
+
+// Feed `balances_snapshot` or `balances`
+type BalancesEvent struct {
+    Feed        string              `json:"feed"`
+    Account     string              `json:"account,omitempty"`
+    Seq         int64               `json:"seq"`
+    Timestamp   int64               `json:"timestamp"`
+    Holding     map[string]float64  `json:"holding,omitempty"`     // spot wallet
+    FlexFutures FlexFuturesBalance  `json:"flex_futures"`
+}
+//}}} Private: balances
@@ -0,0 +1,101 @@
+package types
+
+import (
+    "errors"
+    "fmt"
+    "math"
+    "time"
+)
+// Funding for perpetuals (PF_): longs pay shorts when rate is positive,
+// shorts pay longs when negative. Kraken settles every hour.
+
+
+// Kraken funding period for PF_ contracts
+const FundingPeriod = time.Hour
+
+
+var ErrNoFundingRates = errors.New("no funding rates to estimate from")
+
+
+//{{{ Historical funding rate-s
+// FundingRate is absolute (quote currency per contract), RelativeFundingRate
+// is fraction of price per period; oldest first
+type HistoricalFundingRate struct {
+    Timestamp           string  `json:"timestamp"`
+    FundingRate         float64 `json:"fundingRate"`
+    RelativeFundingRate float64 `json:"relativeFundingRate"`
+}
+// v4 endpoint, result/serverTime are only there on failure
+type HistoricalFundingRatesResponse struct {
+    Result      string                  `json:"result,omitempty"`
+    ServerTime  string                  `json:"serverTime,omitempty"`
+    Rates       []HistoricalFundingRate `json:"rates"`
+    // Optional, only on failure
+    Error       *string                 `json:"error,omitempty"`
+}
+
+
+func (r HistoricalFundingRate) ToFundingRate(symbol string) (FundingRate, error) {
+    t, err := time.Parse(time.RFC3339Nano, r.Timestamp)
+    if err != nil {
+        return FundingRate{}, fmt.Errorf("funding rate timestamp %q: %w", r.Timestamp, err)
+    }
+    return FundingRate{
+        Symbol:         symbol,
+        Rate:           r.FundingRate,
+        RelativeRate:   r.RelativeFundingRate,
+        DateTime:       t.UTC(),
+    }, nil
+}
+//}}} Historical funding rate-s
+
+
+//{{{ Estimator
+// Positive Amount/Accrued => paid by position, negative => received
+type FundingEstimate struct {
+    Symbol      string
+    Notional    float64     // |size| * price, quote currency
+    AvgRate     float64     // mean relative rate per period
+    Periods     float64     // periods in horizon
+    Amount      float64     // projected over horizon, quote currency
+    Accrued     float64     // unrealized funding already on position
+}
+
+
+// Projects funding for pos over horizon assuming rates (relative, one per
+// period) keep their mean; price is current mark (0 => entry price)
+func EstimateFunding(pos OpenPosition, price float64, rates []FundingRate, horizon time.Duration) (FundingEstimate, error) {
+    if pos.Side != "long" && pos.Side != "short" {
+        return FundingEstimate{}, &ValidationError{Field: "side", Reason: "must be long or short"}
+    }
+    if horizon < 0 {
+        return FundingEstimate{}, &ValidationError{Field: "horizon", Reason: "must not be negative"}
+    }
+    if len(rates) == 0 {
+        return FundingEstimate{}, ErrNoFundingRates
+    }
+    if price <= 0 {
+        price = pos.Price
+    }
+
+    sum := 0.0
+    for _, r := range rates {
+        sum += r.RelativeRate
+    }
+    est := FundingEstimate{
+        Symbol:     pos.Symbol,
+        Notional:   math.Abs(pos.Size) * price,
+        AvgRate:    sum / float64(len(rates)),
+        Periods:    horizon.Hours() / FundingPeriod.Hours(),
+    }
+    est.Amount = est.Notional * est.AvgRate * est.Periods
+    if pos.Side == "short" {
+        est.Amount = -est.Amount
+    }
+    // Kraken reports it as PnL (negative => cost), flip to match Amount
+    if pos.UnrealizedFunding != nil {
+        est.Accrued = -*pos.UnrealizedFunding
+    }
+    return est, nil
+}
+//}}} Estimator
@@ -0,0 +1,73 @@
+package types
+
+import (
+    "errors"
+    "math"
+    "testing"
+    "time"
+)
+
+
+//{{{ Estimate funding
+func TestEstimateFunding(t *testing.T) {
+    floatPtr := func(f float64) *float64 { return &f }
+    rates := []FundingRate{{RelativeRate: 0.0001}, {RelativeRate: 0.0003}}   // mean 0.0002 per hour
+
+    tests := []struct {
+        name            string
+        pos             OpenPosition
+        price           float64
+        rates           []FundingRate
+        horizon         time.Duration
+        expectAmount    float64
+        expectAccrued   float64
+        expectErr       bool
+    }{
+        {"SuccLongPays",        OpenPosition{Side: "long", Symbol: "PF_BCHUSD", Size: 2, Price: 500},                               550,    rates,  24 * time.Hour, 2 * 550 * 0.0002 * 24,  0,      false},
+        {"SuccShortReceives",   OpenPosition{Side: "short", Symbol: "PF_BCHUSD", Size: 2, Price: 500},                              550,    rates,  24 * time.Hour, -2 * 550 * 0.0002 * 24, 0,      false},
+        {"SuccEntryPrice",      OpenPosition{Side: "long", Symbol: "PF_BCHUSD", Size: 1, Price: 500, UnrealizedFunding: floatPtr(-0.3)}, 0, rates,  time.Hour,      500 * 0.0002,           0.3,    false},
+        {"SuccZeroHorizon",     OpenPosition{Side: "long", Symbol: "PF_BCHUSD", Size: 1, Price: 500},                               500,    rates,  0,              0,                      0,      false},
+        {"FailNoRates",         OpenPosition{Side: "long", Symbol: "PF_BCHUSD", Size: 1, Price: 500},                               500,    nil,    time.Hour,      0,                      0,      true},
+        {"FailSide",            OpenPosition{Side: "buy", Symbol: "PF_BCHUSD", Size: 1, Price: 500},                                500,    rates,  time.Hour,      0,                      0,      true},
+        {"FailNegativeHorizon", OpenPosition{Side: "long", Symbol: "PF_BCHUSD", Size: 1, Price: 500},                               500,    rates,  -time.Hour,     0,                      0,      true},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            est, err := EstimateFunding(tc.pos, tc.price, tc.rates, tc.horizon)
+            if (err != nil) != tc.expectErr {
+                t.Fatalf("Unexpected error: %v", err)
+            }
+            if tc.expectErr {
+                return
+            }
+            if math.Abs(est.Amount-tc.expectAmount) > 1e-9 {
+                t.Errorf("Wrong amount\nExpected:\t%v\nGot:\t\t%v", tc.expectAmount, est.Amount)
+            }
+            if math.Abs(est.Accrued-tc.expectAccrued) > 1e-9 {
+                t.Errorf("Wrong accrued\nExpected:\t%v\nGot:\t\t%v", tc.expectAccrued, est.Accrued)
+            }
+        })
+    }
+
+    if _, err := EstimateFunding(OpenPosition{Side: "long"}, 1, nil, time.Hour); !errors.Is(err, ErrNoFundingRates) {
+        t.Errorf("Expected ErrNoFundingRates, got: %v", err)
+    }
+}
+//}}} Estimate funding
+
+
+//{{{ Historical funding rate
+func TestToFundingRate(t *testing.T) {
+    fr, err := HistoricalFundingRate{Timestamp: "2025-09-23T16:00:00.000Z", FundingRate: 0.06, RelativeFundingRate: 0.0001}.ToFundingRate("PF_BCHUSD")
+    if err != nil {
+        t.Fatalf("ToFundingRate failed: %v", err)
+    }
+    if fr.Symbol != "PF_BCHUSD" || !fr.DateTime.Equal(time.Date(2025, 9, 23, 16, 0, 0, 0, time.UTC)) || fr.RelativeRate != 0.0001 {
+        t.Errorf("Wrong funding rate: %+v", fr)
+    }
+    if _, err := (HistoricalFundingRate{Timestamp: "yesterday"}).ToFundingRate("PF_BCHUSD"); err == nil {
+        t.Errorf("Expected error for bad timestamp")
+    }
+}
+//}}} Historical funding rate
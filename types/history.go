@@ -0,0 +1,227 @@
+package types
+
+import (
+    "encoding/json"
+    "fmt"
+    "sort"
+    "time"
+)
+// History API: orders, triggers and executions (/api/history/v2/...) and the
+// account log (/api/history/v3/account-log). Account log is the one that
+// explains balance changes (funding, fees, liquidations, transfers, ...),
+// AccountEvent merges everything into one chronological stream.
+
+
+//{{{ Orders, triggers, executions
+// Numbers come as strings in history API
+type HistoryOrder struct {
+    Uid                 string  `json:"uid"`
+    AccountUid          string  `json:"accountUid"`
+    Tradeable           string  `json:"tradeable"`
+    Direction           string  `json:"direction"`      // Buy, Sell
+    Quantity            float64 `json:"quantity,string"`
+    Filled              float64 `json:"filled,string"`
+    Timestamp           int64   `json:"timestamp"`
+    LimitPrice          float64 `json:"limitPrice,string"`
+    OrderType           string  `json:"orderType"`
+    ClientId            string  `json:"clientId"`
+    ReduceOnly          bool    `json:"reduceOnly"`
+    LastUpdateTimestamp int64   `json:"lastUpdateTimestamp"`
+    // Trigger history only
+    TriggerPrice        float64 `json:"triggerPrice,string,omitempty"`
+    TriggerSignal       string  `json:"triggerSignal,omitempty"`
+}
+
+
+type HistoryExecution struct {
+    Uid         string          `json:"uid"`
+    MakerOrder  *HistoryOrder   `json:"makerOrder,omitempty"`
+    TakerOrder  *HistoryOrder   `json:"takerOrder,omitempty"`
+    Timestamp   int64           `json:"timestamp"`
+    Quantity    float64         `json:"quantity,string"`
+    Price       float64         `json:"price,string"`
+    MarkPrice   float64         `json:"markPrice,string"`
+    LimitFilled bool            `json:"limitFilled"`
+    UsdValue    float64         `json:"usdValue,string"`
+}
+
+
+// Own side of execution (the one that is ours, other one is usually absent)
+func (e HistoryExecution) Order() *HistoryOrder {
+    if e.TakerOrder != nil {
+        return e.TakerOrder
+    }
+    return e.MakerOrder
+}
+
+
+// Kraken wraps payload in object keyed by event type, ex.:
+//  {"OrderPlaced": {"order": {...}, "reason": "new_user_order"}}
+//  {"OrderUpdated": {"oldOrder": {...}, "newOrder": {...}, "reason": "edited_by_user"}}
+//  {"Execution": {"execution": {...}}}
+type HistoryEvent struct {
+    Type        string              // OrderPlaced, OrderCancelled, OrderUpdated, OrderRejected, OrderTriggerPlaced, ..., Execution
+    Order       *HistoryOrder       // newOrder for OrderUpdated
+    OldOrder    *HistoryOrder
+    Reason      string
+    Execution   *HistoryExecution
+}
+type historyEventBody struct {
+    Order       *HistoryOrder       `json:"order,omitempty"`
+    OldOrder    *HistoryOrder       `json:"oldOrder,omitempty"`
+    NewOrder    *HistoryOrder       `json:"newOrder,omitempty"`
+    Reason      string              `json:"reason,omitempty"`
+    Execution   *HistoryExecution   `json:"execution,omitempty"`
+}
+
+
+func (e *HistoryEvent) UnmarshalJSON(data []byte) error {
+    var wrapper map[string]historyEventBody
+    if err := json.Unmarshal(data, &wrapper); err != nil {
+        return err
+    }
+    if len(wrapper) != 1 {
+        return fmt.Errorf("history event: expected one event type, got %d", len(wrapper))
+    }
+    for eventType, body := range wrapper {
+        *e = HistoryEvent{Type: eventType, Order: body.Order, OldOrder: body.OldOrder, Reason: body.Reason, Execution: body.Execution}
+        if body.NewOrder != nil {
+            e.Order = body.NewOrder
+        }
+    }
+    return nil
+}
+
+
+func (e HistoryEvent) MarshalJSON() ([]byte, error) {
+    body := historyEventBody{Order: e.Order, Reason: e.Reason, Execution: e.Execution}
+    if e.OldOrder != nil {
+        body.Order, body.OldOrder, body.NewOrder = nil, e.OldOrder, e.Order
+    }
+    return json.Marshal(map[string]historyEventBody{e.Type: body})
+}
+
+
+type HistoryElement struct {
+    Uid         string          `json:"uid"`
+    Timestamp   int64           `json:"timestamp"`     // ms
+    Event       HistoryEvent    `json:"event"`
+}
+type HistoryResponse struct {
+    AccountUid          string              `json:"accountUid"`
+    Len                 int                 `json:"len"`
+    ContinuationToken   string              `json:"continuationToken,omitempty"`   // empty on last page
+    Elements            []HistoryElement    `json:"elements"`
+}
+
+
+// Zero values are left out; Sort asc or desc (Kraken default)
+type HistoryQuery struct {
+    Since       time.Time
+    Before      time.Time
+    Sort        string
+    Count       int
+    Tradeable   string
+}
+//}}} Orders, triggers, executions
+
+
+//{{{ Account log
+// One balance change, optional fields depend on Info
+type AccountLogEntry struct {
+    Id                      int64       `json:"id"`
+    Date                    string      `json:"date"`
+    Asset                   string      `json:"asset"`
+    Info                    string      `json:"info"`          // futures trade, funding rate change, futures liquidation, transfer, ...
+    BookingUid              string      `json:"booking_uid"`
+    MarginAccount           string      `json:"margin_account"`
+    OldBalance              float64     `json:"old_balance"`
+    NewBalance              float64     `json:"new_balance"`
+    OldAverageEntryPrice    *float64    `json:"old_average_entry_price,omitempty"`
+    NewAverageEntryPrice    *float64    `json:"new_average_entry_price,omitempty"`
+    TradePrice              *float64    `json:"trade_price,omitempty"`
+    MarkPrice               *float64    `json:"mark_price,omitempty"`
+    RealizedPnl             *float64    `json:"realized_pnl,omitempty"`
+    Fee                     *float64    `json:"fee,omitempty"`
+    Execution               *string     `json:"execution,omitempty"`
+    Collateral              *string     `json:"collateral,omitempty"`
+    FundingRate             *float64    `json:"funding_rate,omitempty"`
+    RealizedFunding         *float64    `json:"realized_funding,omitempty"`
+    Contract                *string     `json:"contract,omitempty"`
+    LiquidationFee          *float64    `json:"liquidation_fee,omitempty"`
+}
+type AccountLogResponse struct {
+    AccountUid  string              `json:"accountUid"`
+    Logs        []AccountLogEntry   `json:"logs"`
+}
+
+
+// Zero values are left out; From/To are entry ids (inclusive)
+type AccountLogQuery struct {
+    Since       time.Time
+    Before      time.Time
+    From        int64
+    To          int64
+    Sort        string      // asc, desc (Kraken default)
+    Info        []string    // only these kinds, ex.: "funding rate change"
+    Count       int
+}
+//}}} Account log
+
+
+//{{{ Unified stream
+type AccountEvent struct {
+    Time        time.Time
+    Source      string      // order, trigger, execution, account_log
+    Type        string      // history event type, or account log info
+    Symbol      string
+    Reason      string
+    Order       *HistoryOrder
+    Execution   *HistoryExecution
+    Log         *AccountLogEntry
+}
+
+
+func (el HistoryElement) AccountEvent(source string) AccountEvent {
+    ev := AccountEvent{
+        Time:       time.UnixMilli(el.Timestamp).UTC(),
+        Source:     source,
+        Type:       el.Event.Type,
+        Reason:     el.Event.Reason,
+        Order:      el.Event.Order,
+        Execution:  el.Event.Execution,
+    }
+    order := el.Event.Order
+    if order == nil && el.Event.Execution != nil {
+        order = el.Event.Execution.Order()
+    }
+    if order != nil {
+        ev.Symbol = order.Tradeable
+    }
+    return ev
+}
+
+
+func (entry AccountLogEntry) AccountEvent() (AccountEvent, error) {
+    t, err := time.Parse(time.RFC3339Nano, entry.Date)
+    if err != nil {
+        return AccountEvent{}, fmt.Errorf("account log %d: date %q: %w", entry.Id, entry.Date, err)
+    }
+    ev := AccountEvent{Time: t.UTC(), Source: "account_log", Type: entry.Info, Log: &entry}
+    if entry.Contract != nil {
+        ev.Symbol = *entry.Contract
+    }
+    return ev, nil
+}
+
+
+// Oldest first, same time keeps stream order (streams as passed)
+func MergeAccountEvents(streams ...[]AccountEvent) []AccountEvent {
+    var merged []AccountEvent
+    for _, stream := range streams {
+        merged = append(merged, stream...)
+    }
+    sort.SliceStable(merged, func(i, j int) bool { return merged[i].Time.Before(merged[j].Time) })
+    return merged
+}
+//}}} Unified stream
@@ -0,0 +1,58 @@
+package types
+
+import (
+    "encoding/json"
+    "testing"
+)
+
+
+//{{{ History event
+func TestHistoryEventUnmarshal(t *testing.T) {
+    tests := []struct {
+        name            string
+        data            string
+        expectType      string
+        expectPrice     float64
+        expectOldPrice  float64
+        expectErr       bool
+    }{
+        {"SuccPlaced",      `{"OrderPlaced":{"order":{"uid":"o1","tradeable":"PF_BCHUSD","quantity":"1","filled":"0","limitPrice":"500"},"reason":"new_user_order"}}`,          "OrderPlaced",  500,    0,      false},
+        {"SuccUpdated",     `{"OrderUpdated":{"oldOrder":{"uid":"o1","limitPrice":"500"},"newOrder":{"uid":"o1","limitPrice":"495"},"reason":"edited_by_user"}}`,             "OrderUpdated", 495,    500,    false},
+        {"SuccExecution",   `{"Execution":{"execution":{"uid":"e1","quantity":"2","price":"550","markPrice":"550","usdValue":"1100","takerOrder":{"uid":"o2"}}}}`,             "Execution",    0,      0,      false},
+        {"FailTwoTypes",    `{"OrderPlaced":{},"OrderCancelled":{}}`,                                                                                                       "",             0,      0,      true},
+        {"FailNumber",      `{"OrderPlaced":{"order":{"limitPrice":500}}}`,                                                                                                 "",             0,      0,      true},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            var ev HistoryEvent
+            err := json.Unmarshal([]byte(tc.data), &ev)
+            if (err != nil) != tc.expectErr {
+                t.Fatalf("Unexpected error: %v", err)
+            }
+            if tc.expectErr {
+                return
+            }
+            if ev.Type != tc.expectType {
+                t.Errorf("Wrong type\nExpected:\t%s\nGot:\t\t%s", tc.expectType, ev.Type)
+            }
+            if ev.Order != nil && ev.Order.LimitPrice != tc.expectPrice {
+                t.Errorf("Wrong limit price\nExpected:\t%v\nGot:\t\t%v", tc.expectPrice, ev.Order.LimitPrice)
+            }
+            if ev.OldOrder != nil && ev.OldOrder.LimitPrice != tc.expectOldPrice {
+                t.Errorf("Wrong old limit price\nExpected:\t%v\nGot:\t\t%v", tc.expectOldPrice, ev.OldOrder.LimitPrice)
+            }
+
+            // Round trip keeps shape
+            data, err := json.Marshal(ev)
+            if err != nil {
+                t.Fatalf("Marshal failed: %v", err)
+            }
+            var again HistoryEvent
+            if err := json.Unmarshal(data, &again); err != nil || again.Type != ev.Type || (again.OldOrder == nil) != (ev.OldOrder == nil) {
+                t.Errorf("Round trip differs: %s (%v)", data, err)
+            }
+        })
+    }
+}
+//}}} History event
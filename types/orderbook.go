@@ -0,0 +1,325 @@
+package types
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+    "sync"
+)
+// Local L2 book kept from `book` feed (BookEvent-s), read side is for strategies.
+// Any inconsistency (seq gap, crossed book, invalid level) clears the book
+// and returns *BookCorruptError, caller then asks for new snapshot
+// (krakenftr FeedClient.ResyncBook) and book is usable again once it arrives.
+// Kraken futures feed has no checksum, seq is the only drift detection.
+
+
+var ErrBookNotSynced = errors.New("book not synced, waiting for snapshot")
+
+
+//{{{ Book error
+type BookCorruptError struct {
+    ProductId   string
+    Seq         int64
+    Reason      string
+}
+func (e *BookCorruptError) Error() string {
+    return fmt.Sprintf("book %s corrupt at seq %d: %s", e.ProductId, e.Seq, e.Reason)
+}
+//}}} Book error
+
+
+type OrderBook struct {
+    ProductId   string
+
+    mu          sync.RWMutex
+    synced      bool
+    seq         int64
+    bids        []BookLevel     // best (highest) first
+    asks        []BookLevel     // best (lowest) first
+}
+
+
+func NewOrderBook(productId string) *OrderBook {
+    return &OrderBook{ProductId: productId}
+}
+
+
+//{{{ Update
+// Events for other products are ignored, so one Book channel can feed several books
+func (b *OrderBook) Apply(ev BookEvent) error {
+    switch {
+    case ev.Snapshot != nil:
+        return b.ApplySnapshot(*ev.Snapshot)
+    case ev.Delta != nil:
+        return b.ApplyDelta(*ev.Delta)
+    }
+    return nil
+}
+
+
+// Replaces book whatever its seq, deltas continue from snapshot seq
+func (b *OrderBook) ApplySnapshot(snap BookSnapshot) error {
+    if snap.ProductId != b.ProductId {
+        return nil
+    }
+    bids := cleanLevels(snap.Bids, true)
+    asks := cleanLevels(snap.Asks, false)
+
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    b.bids, b.asks, b.seq, b.synced = bids, asks, snap.Seq, true
+    if crossed(b.bids, b.asks) {
+        return b.corruptLocked(snap.Seq, "crossed snapshot")
+    }
+    return nil
+}
+
+
+// Stale (already applied) deltas are skipped
+func (b *OrderBook) ApplyDelta(delta BookDelta) error {
+    if delta.ProductId != b.ProductId {
+        return nil
+    }
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if !b.synced {
+        return ErrBookNotSynced
+    }
+    if delta.Seq <= b.seq {
+        return nil
+    }
+    if delta.Seq != b.seq+1 {
+        return b.corruptLocked(delta.Seq, fmt.Sprintf("seq gap, expected %d", b.seq+1))
+    }
+    if delta.Qty < 0 || delta.Price <= 0 {
+        return b.corruptLocked(delta.Seq, fmt.Sprintf("invalid level %v@%v", delta.Qty, delta.Price))
+    }
+    switch delta.Side {
+    case "buy":
+        b.bids = setLevel(b.bids, delta.Price, delta.Qty, true)
+    case "sell":
+        b.asks = setLevel(b.asks, delta.Price, delta.Qty, false)
+    default:
+        return b.corruptLocked(delta.Seq, fmt.Sprintf("invalid side %q", delta.Side))
+    }
+    b.seq = delta.Seq
+    if crossed(b.bids, b.asks) {
+        return b.corruptLocked(delta.Seq, "crossed book")
+    }
+    return nil
+}
+
+
+// Clears book until next snapshot
+func (b *OrderBook) Invalidate() {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    b.bids, b.asks, b.synced = nil, nil, false
+}
+
+
+// Caller holds b.mu
+func (b *OrderBook) corruptLocked(seq int64, reason string) error {
+    b.bids, b.asks, b.synced = nil, nil, false
+    return &BookCorruptError{ProductId: b.ProductId, Seq: seq, Reason: reason}
+}
+
+
+// Sorted copy without empty levels
+func cleanLevels(levels []BookLevel, desc bool) []BookLevel {
+    out := make([]BookLevel, 0, len(levels))
+    for _, l := range levels {
+        if l.Qty > 0 {
+            out = append(out, l)
+        }
+    }
+    sort.Slice(out, func(i, j int) bool {
+        if desc {
+            return out[i].Price > out[j].Price
+        }
+        return out[i].Price < out[j].Price
+    })
+    return out
+}
+
+
+// Qty 0 removes level
+func setLevel(levels []BookLevel, price, qty float64, desc bool) []BookLevel {
+    i := sort.Search(len(levels), func(i int) bool {
+        if desc {
+            return levels[i].Price <= price
+        }
+        return levels[i].Price >= price
+    })
+    exists := i < len(levels) && levels[i].Price == price
+    switch {
+    case exists && qty == 0:
+        return append(levels[:i], levels[i+1:]...)
+    case exists:
+        levels[i].Qty = qty
+    case qty > 0:
+        levels = append(levels, BookLevel{})
+        copy(levels[i+1:], levels[i:])
+        levels[i] = BookLevel{Price: price, Qty: qty}
+    }
+    return levels
+}
+
+
+func crossed(bids, asks []BookLevel) bool {
+    return len(bids) > 0 && len(asks) > 0 && bids[0].Price >= asks[0].Price
+}
+//}}} Update
+
+
+//{{{ Read
+func (b *OrderBook) Synced() bool {
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+    return b.synced
+}
+
+
+func (b *OrderBook) Seq() int64 {
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+    return b.seq
+}
+
+
+func (b *OrderBook) BestBid() (BookLevel, bool) {
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+    if len(b.bids) == 0 {
+        return BookLevel{}, false
+    }
+    return b.bids[0], true
+}
+
+
+func (b *OrderBook) BestAsk() (BookLevel, bool) {
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+    if len(b.asks) == 0 {
+        return BookLevel{}, false
+    }
+    return b.asks[0], true
+}
+
+
+// Best ask - best bid, false when a side is empty
+func (b *OrderBook) Spread() (float64, bool) {
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+    if len(b.bids) == 0 || len(b.asks) == 0 {
+        return 0, false
+    }
+    return b.asks[0].Price - b.bids[0].Price, true
+}
+
+
+func (b *OrderBook) Mid() (float64, bool) {
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+    if len(b.bids) == 0 || len(b.asks) == 0 {
+        return 0, false
+    }
+    return (b.asks[0].Price + b.bids[0].Price) / 2, true
+}
+
+
+// Top n levels per side (copies), n <= 0 => whole book
+func (b *OrderBook) Depth(n int) ([]BookLevel, []BookLevel) {
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+    return topLevels(b.bids, n), topLevels(b.asks, n)
+}
+
+
+func topLevels(levels []BookLevel, n int) []BookLevel {
+    if n <= 0 || n > len(levels) {
+        n = len(levels)
+    }
+    return append([]BookLevel{}, levels[:n]...)
+}
+
+
+// Size resting on `side` (buy => bids, sell => asks) from best level up to
+// and including price
+func (b *OrderBook) CumulativeSize(side string, price float64) float64 {
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+    total := 0.0
+    switch side {
+    case "buy":
+        for _, l := range b.bids {
+            if l.Price < price {
+                break
+            }
+            total += l.Qty
+        }
+    case "sell":
+        for _, l := range b.asks {
+            if l.Price > price {
+                break
+            }
+            total += l.Qty
+        }
+    }
+    return total
+}
+
+
+// Average price market order of `size` on `side` would get (buy walks asks,
+// sell walks bids); filled < size when book is too thin
+func (b *OrderBook) VWAP(side string, size float64) (vwap float64, filled float64, err error) {
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+    if !b.synced {
+        return 0, 0, ErrBookNotSynced
+    }
+    var levels []BookLevel
+    switch side {
+    case "buy":
+        levels = b.asks
+    case "sell":
+        levels = b.bids
+    default:
+        return 0, 0, &ValidationError{Field: "side", Reason: fmt.Sprintf("%q is not one of buy, sell", side)}
+    }
+    if size <= 0 {
+        return 0, 0, &ValidationError{Field: "size", Reason: fmt.Sprintf("must be positive, got %v", size)}
+    }
+    notional := 0.0
+    for _, l := range levels {
+        take := min(l.Qty, size-filled)
+        notional += take * l.Price
+        filled += take
+        if filled >= size {
+            break
+        }
+    }
+    if filled == 0 {
+        return 0, 0, nil
+    }
+    return notional / filled, filled, nil
+}
+
+
+// (bid size - ask size) / (bid size + ask size) over top n levels, in [-1, 1],
+// > 0 means more resting on bid side; 0 for empty book
+func (b *OrderBook) Imbalance(n int) float64 {
+    bids, asks := b.Depth(n)
+    bidSize, askSize := 0.0, 0.0
+    for _, l := range bids {
+        bidSize += l.Qty
+    }
+    for _, l := range asks {
+        askSize += l.Qty
+    }
+    if bidSize+askSize == 0 {
+        return 0
+    }
+    return (bidSize - askSize) / (bidSize + askSize)
+}
+//}}} Read
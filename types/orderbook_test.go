@@ -0,0 +1,143 @@
+package types
+
+import (
+    "errors"
+    "math"
+    "testing"
+)
+
+
+func testBook(t *testing.T) *OrderBook {
+    t.Helper()
+    book := NewOrderBook("PF_BCHUSD")
+    err := book.Apply(BookEvent{Snapshot: &BookSnapshot{
+        ProductId:  "PF_BCHUSD",
+        Seq:        10,
+        // Unsorted on purpose, empty level dropped
+        Bids:       []BookLevel{{Price: 548, Qty: 5}, {Price: 549, Qty: 2}, {Price: 547, Qty: 0}},
+        Asks:       []BookLevel{{Price: 552, Qty: 4}, {Price: 551, Qty: 1}},
+    }})
+    if err != nil {
+        t.Fatalf("Snapshot failed: %v", err)
+    }
+    return book
+}
+
+
+//{{{ Read
+func TestOrderBookMetrics(t *testing.T) {
+    book := testBook(t)
+    bid, _ := book.BestBid()
+    ask, _ := book.BestAsk()
+    spread, _ := book.Spread()
+    mid, _ := book.Mid()
+    if bid.Price != 549 || ask.Price != 551 || spread != 2 || mid != 550 {
+        t.Errorf("Wrong top of book\nExpected:\t549/551 spread 2 mid 550\nGot:\t\t%v/%v spread %v mid %v", bid.Price, ask.Price, spread, mid)
+    }
+    bids, asks := book.Depth(1)
+    if len(bids) != 1 || len(asks) != 1 || bids[0].Price != 549 || asks[0].Price != 551 {
+        t.Errorf("Wrong depth: %v %v", bids, asks)
+    }
+    if size := book.CumulativeSize("buy", 548); size != 7 {
+        t.Errorf("Wrong cumulative bid size\nExpected:\t7\nGot:\t\t%v", size)
+    }
+    if size := book.CumulativeSize("sell", 551.5); size != 1 {
+        t.Errorf("Wrong cumulative ask size\nExpected:\t1\nGot:\t\t%v", size)
+    }
+    // (7 - 5) / 12
+    if imb := book.Imbalance(0); math.Abs(imb-2.0/12) > 1e-9 {
+        t.Errorf("Wrong imbalance\nExpected:\t%v\nGot:\t\t%v", 2.0/12, imb)
+    }
+}
+
+
+func TestOrderBookVWAP(t *testing.T) {
+    book := testBook(t)
+    tests := []struct {
+        name            string
+        side            string
+        size            float64
+        expectVwap      float64
+        expectFilled    float64
+        expectErr       bool
+    }{
+        {"SuccBuyTopLevel",     "buy",  1,      551,                1,  false},
+        // 1@551 + 2@552
+        {"SuccBuyTwoLevels",    "buy",  3,      (551 + 2*552) / 3.0, 3, false},
+        // 2@549 + 3@548
+        {"SuccSellTwoLevels",   "sell", 5,      (2*549 + 3*548) / 5.0, 5, false},
+        {"SuccTooThin",         "buy",  10,     (551 + 4*552) / 5.0, 5, false},
+        {"FailSide",            "long", 1,      0,                  0,  true},
+        {"FailSize",            "buy",  0,      0,                  0,  true},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            vwap, filled, err := book.VWAP(tc.side, tc.size)
+            if (err != nil) != tc.expectErr {
+                t.Fatalf("Wrong error\nExpected:\t%v\nGot:\t\t%v", tc.expectErr, err)
+            }
+            if math.Abs(vwap-tc.expectVwap) > 1e-9 || filled != tc.expectFilled {
+                t.Errorf("Wrong vwap/filled\nExpected:\t%v/%v\nGot:\t\t%v/%v", tc.expectVwap, tc.expectFilled, vwap, filled)
+            }
+        })
+    }
+}
+//}}} Read
+
+
+//{{{ Update
+func TestOrderBookDeltas(t *testing.T) {
+    tests := []struct {
+        name        string
+        deltas      []BookDelta
+        expectBid   float64
+        expectAsk   float64
+        expectSeq   int64
+        expectErr   bool
+    }{
+        {"SuccNewBestBid",      []BookDelta{{Side: "buy", Seq: 11, Price: 550, Qty: 1}},                                           550, 551, 11, false},
+        {"SuccRemoveBestAsk",   []BookDelta{{Side: "sell", Seq: 11, Price: 551, Qty: 0}},                                          549, 552, 11, false},
+        {"SuccUpdateQty",       []BookDelta{{Side: "buy", Seq: 11, Price: 549, Qty: 9}},                                           549, 551, 11, false},
+        {"SuccStaleSkipped",    []BookDelta{{Side: "buy", Seq: 10, Price: 550, Qty: 1}},                                           549, 551, 10, false},
+        {"FailGap",             []BookDelta{{Side: "buy", Seq: 12, Price: 550, Qty: 1}},                                           0,   0,   10, true},
+        {"FailCrossed",         []BookDelta{{Side: "buy", Seq: 11, Price: 551, Qty: 1}},                                           0,   0,   11, true},
+        {"FailSide",            []BookDelta{{Side: "long", Seq: 11, Price: 550, Qty: 1}},                                          0,   0,   10, true},
+        {"SuccOtherProduct",    []BookDelta{{ProductId: "PF_XBTUSD", Side: "buy", Seq: 99, Price: 90000, Qty: 1}},                 549, 551, 10, false},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            book := testBook(t)
+            var err error
+            for _, d := range tc.deltas {
+                if d.ProductId == "" {
+                    d.ProductId = "PF_BCHUSD"
+                }
+                if err = book.Apply(BookEvent{Delta: &d}); err != nil {
+                    break
+                }
+            }
+            var corrupt *BookCorruptError
+            if tc.expectErr != errors.As(err, &corrupt) {
+                t.Fatalf("Wrong error\nExpected:\t%v\nGot:\t\t%v", tc.expectErr, err)
+            }
+            if tc.expectErr {
+                if book.Synced() {
+                    t.Errorf("Book still synced after %v", err)
+                }
+                delta := BookDelta{ProductId: "PF_BCHUSD", Side: "buy", Seq: 13, Price: 540, Qty: 1}
+                if err := book.ApplyDelta(delta); !errors.Is(err, ErrBookNotSynced) {
+                    t.Errorf("Expected ErrBookNotSynced, got %v", err)
+                }
+                return
+            }
+            bid, _ := book.BestBid()
+            ask, _ := book.BestAsk()
+            if bid.Price != tc.expectBid || ask.Price != tc.expectAsk || book.Seq() != tc.expectSeq {
+                t.Errorf("Wrong book\nExpected:\t%v/%v seq %d\nGot:\t\t%v/%v seq %d", tc.expectBid, tc.expectAsk, tc.expectSeq, bid.Price, ask.Price, book.Seq())
+            }
+        })
+    }
+}
+//}}} Update
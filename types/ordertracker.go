@@ -0,0 +1,81 @@
+package types
+
+import (
+    "sort"
+    "sync"
+)
+// Open orders kept in sync from `open_orders` feed, replaces polling GetOpenOrders
+
+
+type OrderTracker struct {
+    mu      sync.RWMutex
+    orders  map[string]FeedOrder    // by order_id
+}
+
+
+func NewOrderTracker() *OrderTracker {
+    return &OrderTracker{orders: map[string]FeedOrder{}}
+}
+
+
+// Snapshot replaces everything, update upserts, cancel (also full fill) removes
+func (t *OrderTracker) Apply(ev OpenOrdersEvent) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    if ev.IsSnapshot() {
+        t.orders = make(map[string]FeedOrder, len(ev.Orders))
+        for _, o := range ev.Orders {
+            t.orders[o.OrderId] = o
+        }
+        return
+    }
+    if ev.IsCancel {
+        orderId := ev.OrderId
+        if orderId == "" && ev.Order != nil {
+            orderId = ev.Order.OrderId
+        }
+        delete(t.orders, orderId)
+        return
+    }
+    if ev.Order != nil {
+        t.orders[ev.Order.OrderId] = *ev.Order
+    }
+}
+
+
+func (t *OrderTracker) Get(orderId string) (FeedOrder, bool) {
+    t.mu.RLock()
+    defer t.mu.RUnlock()
+    o, ok := t.orders[orderId]
+    return o, ok
+}
+
+
+func (t *OrderTracker) ByCliOrdId(cliOrdId string) (FeedOrder, bool) {
+    t.mu.RLock()
+    defer t.mu.RUnlock()
+    for _, o := range t.orders {
+        if cliOrdId != "" && o.CliOrdId == cliOrdId {
+            return o, true
+        }
+    }
+    return FeedOrder{}, false
+}
+
+
+// Oldest first
+func (t *OrderTracker) Open() []FeedOrder {
+    t.mu.RLock()
+    defer t.mu.RUnlock()
+    orders := make([]FeedOrder, 0, len(t.orders))
+    for _, o := range t.orders {
+        orders = append(orders, o)
+    }
+    sort.Slice(orders, func(i, j int) bool {
+        if orders[i].Time != orders[j].Time {
+            return orders[i].Time < orders[j].Time
+        }
+        return orders[i].OrderId < orders[j].OrderId
+    })
+    return orders
+}
@@ -0,0 +1,63 @@
+package types
+
+import (
+    "testing"
+)
+
+
+//{{{ Order tracker
+func TestOrderTrackerApply(t *testing.T) {
+    tracker := NewOrderTracker()
+    tracker.Apply(OpenOrdersEvent{Feed: "open_orders_snapshot", Orders: []FeedOrder{
+        {OrderId: "o2", Time: 2, CliOrdId: "c2"},
+        {OrderId: "o1", Time: 1},
+    }})
+    updated := FeedOrder{OrderId: "o1", Time: 1, Filled: 0.5}
+    placed := FeedOrder{OrderId: "o3", Time: 3}
+
+    tests := []struct {
+        name        string
+        ev          OpenOrdersEvent
+        expectIds   []string
+    }{
+        {"SuccUpdate",  OpenOrdersEvent{Feed: "open_orders", Order: &updated},                      []string{"o1", "o2"}},
+        {"SuccNew",     OpenOrdersEvent{Feed: "open_orders", Order: &placed},                       []string{"o1", "o2", "o3"}},
+        {"SuccCancel",  OpenOrdersEvent{Feed: "open_orders", OrderId: "o2", IsCancel: true},        []string{"o1", "o3"}},
+        {"SuccUnknown", OpenOrdersEvent{Feed: "open_orders", OrderId: "nope", IsCancel: true},      []string{"o1", "o3"}},
+        {"SuccSnapshot",OpenOrdersEvent{Feed: "open_orders_snapshot", Orders: []FeedOrder{placed}}, []string{"o3"}},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            tracker.Apply(tc.ev)
+            var ids []string
+            for _, o := range tracker.Open() {
+                ids = append(ids, o.OrderId)
+            }
+            if len(ids) != len(tc.expectIds) {
+                t.Fatalf("Wrong open orders\nExpected:\t%v\nGot:\t\t%v", tc.expectIds, ids)
+            }
+            for i := range ids {
+                if ids[i] != tc.expectIds[i] {
+                    t.Errorf("Wrong open orders\nExpected:\t%v\nGot:\t\t%v", tc.expectIds, ids)
+                }
+            }
+        })
+    }
+    if o, ok := tracker.Get("o3"); !ok || o.Time != 3 {
+        t.Errorf("Get(o3) failed: %+v %v", o, ok)
+    }
+    if _, ok := tracker.ByCliOrdId("c2"); ok {
+        t.Errorf("Cancelled order still found by cliOrdId")
+    }
+}
+
+
+func TestFeedFillToFill(t *testing.T) {
+    ff := FeedFill{Instrument: "PF_XRPUSD", Time: 1758621600557, Price: 2.5, Buy: false, Qty: 10, OrderId: "o1", CliOrdId: "c1", FillId: "f1", FillType: "taker"}
+    fill := ff.ToFill()
+    if fill.Side != "sell" || fill.Symbol != "PF_XRPUSD" || fill.FillTime != "2025-09-23T10:00:00.557Z" || fill.CliOrdId == nil || *fill.CliOrdId != "c1" {
+        t.Errorf("Wrong fill: %+v", fill)
+    }
+}
+//}}} Order tracker
@@ -3,6 +3,10 @@ package types
 
 import (
     "fmt"
+    "math"
+    "sort"
+    "strings"
+    "time"
 )
 // Kraken sometimes uses CamelCase and sometimes `_` not consistant
 
@@ -20,6 +24,19 @@ type CandleResponse struct {
     Candles     []Candle    `json:"candles"`
     MoreCandles bool        `json:"more_candles"`
 }
+// Candle width per chart resolution, also list of valid ones
+var CandleResolutions = map[string]time.Duration{
+    "1m": time.Minute, "5m": 5 * time.Minute, "15m": 15 * time.Minute, "30m": 30 * time.Minute,
+    "1h": time.Hour, "4h": 4 * time.Hour, "12h": 12 * time.Hour, "1d": 24 * time.Hour, "1w": 7 * 24 * time.Hour,
+}
+// Range of candles by open time, both ends inclusive; zero To => now
+type CandleQuery struct {
+    TickType    string      // spot, mark, trade
+    Symbol      string
+    Resolution  string      // see CandleResolutions
+    From        time.Time
+    To          time.Time
+}
 type CandleMeta struct {
     TickType    string  `json:"tick_type"`
     Symbol      string  `json:"symbol"`
@@ -30,7 +47,40 @@ type CandleResponseWithMeta struct {
     Meta        CandleMeta      `json:"meta"`
     Response    CandleResponse  `json:"response"`
 }
- //}}} Candle-s
+
+
+// Sorted by time, duplicates dropped (later one wins), missing candles
+// between first and last filled flat at previous close with 0 volume;
+// step <= 0 => only sorted and deduplicated
+func FillCandleGaps(candles []Candle, step time.Duration) []Candle {
+    if len(candles) == 0 {
+        return []Candle{}
+    }
+    byTime := make(map[int64]Candle, len(candles))
+    for _, c := range candles {
+        byTime[c.Time] = c
+    }
+    sorted := make([]Candle, 0, len(byTime))
+    for _, c := range byTime {
+        sorted = append(sorted, c)
+    }
+    sort.Slice(sorted, func(i, j int) bool { return sorted[i].Time < sorted[j].Time })
+
+    stepMs := step.Milliseconds()
+    if stepMs <= 0 {
+        return sorted
+    }
+    filled := []Candle{sorted[0]}
+    for _, c := range sorted[1:] {
+        prev := filled[len(filled)-1]
+        for t := prev.Time + stepMs; t < c.Time; t += stepMs {
+            filled = append(filled, Candle{Time: t, Open: prev.Close, High: prev.Close, Low: prev.Close, Close: prev.Close})
+        }
+        filled = append(filled, c)
+    }
+    return filled
+}
+//}}} Candle-s
 
 
 //{{{ Open position-s
@@ -57,28 +107,154 @@ type OpenPositionResponse struct {
 //}}} Open position-s
 
 
-//{{{ History, past order-s
-// TODO: might be conflicting with order as orders that are active or even executed
-type Order struct {
-    Time    string  `json:"time"`
-    Price   float64 `json:"price"`
-    Size    float64 `json:"size"`
-    Side    string  `json:"side"`
-    Type    string  `json:"type"`
-    UID     string  `json:"uid"`
+//{{{ Public trade-s
+// Public trade history (/history), newest first. Type: fill, liquidation,
+// assignment, termination, block. MiFID fields only on some venues
+type PublicTrade struct {
+    Time                            string      `json:"time"`
+    TradeId                         int64       `json:"trade_id"`
+    Price                           float64     `json:"price"`
+    Size                            float64     `json:"size"`
+    Side                            string      `json:"side"`
+    Type                            string      `json:"type"`
+    UID                             string      `json:"uid"`
+    InstrumentIdentificationType    string      `json:"instrument_identification_type,omitempty"`
+    Isin                            string      `json:"isin,omitempty"`
+    ExecutionVenue                  string      `json:"execution_venue,omitempty"`
+    PriceNotation                   string      `json:"price_notation,omitempty"`
+    PriceCurrency                   string      `json:"price_currency,omitempty"`
+    NotionalAmount                  *float64    `json:"notional_amount,omitempty"`
+    NotionalCurrency                string      `json:"notional_currency,omitempty"`
+    PublicationTime                 string      `json:"publication_time,omitempty"`
+    PublicationVenue                string      `json:"publication_venue,omitempty"`
+    TransactionIdentificationCode   string      `json:"transaction_identification_code,omitempty"`
+    ToBeCleared                     *bool       `json:"to_be_cleared,omitempty"`
+}
+type PublicTradesResponse struct {
+    Result      string          `json:"result"`
+    ServerTime  string          `json:"serverTime"`
+    History     []PublicTrade   `json:"history"`
+    // Optional, only on failure
+    Error       *string         `json:"error,omitempty"`
 }
+// Order was the old name of PublicTrade, same fields plus new ones.
+//
+// Deprecated: use PublicTrade.
+type Order = PublicTrade
+//}}} Public trade-s
 
-//}}} History, past order-s 
+
+//{{{ Instrument-s
+// Contract specification, margin levels differ between flex (`numNonContractUnits`)
+// and inverse/fixed (`contracts`) contracts thus both optional
+type MarginLevel struct {
+    Contracts           *float64    `json:"contracts,omitempty"`
+    NumNonContractUnits *float64    `json:"numNonContractUnits,omitempty"`
+    InitialMargin       float64     `json:"initialMargin"`
+    MaintenanceMargin   float64     `json:"maintenanceMargin"`
+}
+type Instrument struct {
+    Symbol                      string          `json:"symbol"`
+    Type                        string          `json:"type"`            // flexible_futures, futures_inverse, ...
+    Base                        string          `json:"base"`
+    Quote                       string          `json:"quote"`
+    Pair                        string          `json:"pair"`
+    Tradeable                   bool            `json:"tradeable"`
+    PostOnly                    bool            `json:"postOnly"`
+    TickSize                    float64         `json:"tickSize"`
+    ContractSize                float64         `json:"contractSize"`
+    // Size precision, 2 => 0.01, can be negative -1 => 10
+    ContractValueTradePrecision int             `json:"contractValueTradePrecision"`
+    MaxPositionSize             float64         `json:"maxPositionSize"`
+    MarginLevels                []MarginLevel   `json:"marginLevels"`
+    OpeningDate                 string          `json:"openingDate,omitempty"`
+    // Only fixed maturity contracts
+    LastTradingTime             *string         `json:"lastTradingTime,omitempty"`
+}
+// Smallest size step and also smallest order size
+func (i Instrument) MinOrderSize() float64 {
+    return math.Pow10(-i.ContractValueTradePrecision)
+}
+// From first (lowest) margin level, 0 if unknown
+func (i Instrument) MaxLeverage() float64 {
+    if len(i.MarginLevels) == 0 || i.MarginLevels[0].InitialMargin <= 0 {
+        return 0
+    }
+    return 1 / i.MarginLevels[0].InitialMargin
+}
+type InstrumentsResponse struct {
+    Result      string          `json:"result"`
+    ServerTime  string          `json:"serverTime"`
+    Instruments []Instrument    `json:"instruments"`
+    // Optional, only on failure
+    Error       *string         `json:"error,omitempty"`
+}
 
 
-///{{{ Ticker
-// There are many more parameters but so far we don't care about them
+// Market condition flags, direction is null unless dislocated ("ABOVE_UPPER_BOUND", "BELOW_LOWER_BOUND")
+type InstrumentStatus struct {
+    Tradeable                                   string      `json:"tradeable"`
+    ExperiencingDislocation                     bool        `json:"experiencingDislocation"`
+    PriceDislocationDirection                   *string     `json:"priceDislocationDirection"`
+    ExperiencingExtremeVolatility               bool        `json:"experiencingExtremeVolatility"`
+    ExtremeVolatilityInitialMarginMultiplier    float64     `json:"extremeVolatilityInitialMarginMultiplier"`
+}
+type InstrumentStatusListResponse struct {
+    Result              string              `json:"result"`
+    ServerTime          string              `json:"serverTime"`
+    InstrumentStatus    []InstrumentStatus  `json:"instrumentStatus"`
+}
+// Single instrument, status fields sit next to result
+type InstrumentStatusResponse struct {
+    Result      string  `json:"result"`
+    ServerTime  string  `json:"serverTime"`
+    InstrumentStatus
+}
+//}}} Instrument-s
+
+
+//{{{ Ticker
+// Indices (in_*, rr_*) only have symbol, last, lastTime and 24h stats; empty
+// book has no bid/ask; funding only on perpetuals, thus `*` pointers
 type Ticker struct {
-    Symbol      string  `json:"symbol"`
-    MarkPrice   float64 `json:"markPrice"`
-    Change24h   float64 `json:"change24h"`
-    Suspended   bool    `json:"suspended"`
-    PostOnly    bool    `json:"postOnly"`
+    Symbol                  string      `json:"symbol"`
+    Tag                     string      `json:"tag,omitempty"`         // perpetual, month, quarter, ...
+    Pair                    string      `json:"pair,omitempty"`        // ex.: "BCH:USD"
+    MarkPrice               float64     `json:"markPrice"`
+    IndexPrice              float64     `json:"indexPrice,omitempty"`
+    Bid                     *float64    `json:"bid,omitempty"`
+    BidSize                 *float64    `json:"bidSize,omitempty"`
+    Ask                     *float64    `json:"ask,omitempty"`
+    AskSize                 *float64    `json:"askSize,omitempty"`
+    Last                    *float64    `json:"last,omitempty"`
+    LastTime                *string     `json:"lastTime,omitempty"`
+    LastSize                *float64    `json:"lastSize,omitempty"`
+    Vol24h                  float64     `json:"vol24h,omitempty"`      // contracts
+    VolumeQuote             float64     `json:"volumeQuote,omitempty"` // quote currency
+    OpenInterest            float64     `json:"openInterest,omitempty"`
+    Open24h                 float64     `json:"open24h,omitempty"`
+    High24h                 float64     `json:"high24h,omitempty"`
+    Low24h                  float64     `json:"low24h,omitempty"`
+    Change24h               float64     `json:"change24h"`             // percent
+    FundingRate             *float64    `json:"fundingRate,omitempty"`
+    FundingRatePrediction   *float64    `json:"fundingRatePrediction,omitempty"`
+    Suspended               bool        `json:"suspended"`
+    PostOnly                bool        `json:"postOnly"`
+}
+// Price trigger orders with signal (mark, index, last) are compared against,
+// 0 => ticker does not have it
+func (t Ticker) SignalPrice(signal string) float64 {
+    switch signal {
+    case "last":
+        if t.Last == nil {
+            return 0
+        }
+        return *t.Last
+    case "index":
+        return t.IndexPrice
+    default:
+        return t.MarkPrice
+    }
 }
 type TickerResponse struct {
     Result      string  `json:"result"`
@@ -88,13 +264,20 @@ type TickerResponse struct {
     // Optional, only on failure
     Error       *string `json:"error,omitempty"`
 }
+type TickersResponse struct {
+    Result      string      `json:"result"`
+    ServerTime  string      `json:"serverTime"`
+    Tickers     []Ticker    `json:"tickers"`
+    // Optional, only on failure
+    Error       *string     `json:"error,omitempty"`
+}
 //}}} Ticker
 
 
 //{{{ Order
-// CliOrdId must be unique so lets make it: '{symbol}-{orderType}-{side}@{limitPrice}'
-//  ex.: `PF_BCHUSD-[lmt/post]:buy@550`                         for limit order
-//  ex.: `PF_BCHUSD-stp:trig@550-sell@650-[mark,index,last]`    for trigger entry
+// CliOrdId must be unique, see cliordid.go for format
+//  ex.: `:PF_BCHUSD:post:buy@550:3f9a1c.1`                    for limit order
+//  ex.: `:PF_BCHUSD:stp:sell@650:trig@550-last:3f9a1c.2`      for trigger entry
 type SendOrderRequest struct {
     Symbol          string      `json:"symbol"                  url:"symbol"`
     OrderType       string      `json:"orderType"               url:"orderType"`                // lmt, post, stp, mkt
@@ -107,9 +290,7 @@ type SendOrderRequest struct {
     TriggerSignal   *string     `json:"triggerSignal,omitempty" url:"triggerSignal,omitempty"`  // mark, index, last
 }
 func (sor *SendOrderRequest) CreateLimitOrderId() {
-    sor.CliOrdId = fmt.Sprintf("%s-%s:%s@%.8f",
-        sor.Symbol, sor.OrderType, sor.Side, sor.LimitPrice,
-    )
+    sor.CliOrdId = DefaultCliOrdIdGenerator.NextCliOrdId(*sor)
 }
 func (sor *SendOrderRequest) CreateTriggerEntryOrderId() error{
     if sor.StopPrice == nil {
@@ -118,9 +299,7 @@ func (sor *SendOrderRequest) CreateTriggerEntryOrderId() error{
     if sor.TriggerSignal == nil {
         return fmt.Errorf("TriggerSignal must be set, currently: `nil`")
     }
-    sor.CliOrdId = fmt.Sprintf("%s-%s:trig@%.6f-%s@%.6f-%s",
-        sor.Symbol, sor.OrderType, *sor.StopPrice, sor.Side, sor.LimitPrice, *sor.TriggerSignal,
-    )
+    sor.CliOrdId = DefaultCliOrdIdGenerator.NextCliOrdId(*sor)
     return nil
 }
 // Many more parameters but we don't care about them so far
@@ -161,19 +340,197 @@ type OpenOrdersResponse struct {
 }
 
 
+// orders/status, closed orders are listed only a few seconds after they close
+type OrderStatusOrder struct {
+    Type            string      `json:"type"`
+    OrderId         string      `json:"orderId"`
+    CliOrdId        *string     `json:"cliOrdId"`
+    Symbol          string      `json:"symbol"`
+    Side            string      `json:"side"`
+    Quantity        float64     `json:"quantity"`
+    Filled          float64     `json:"filled"`
+    LimitPrice      float64     `json:"limitPrice"`
+    ReduceOnly      bool        `json:"reduceOnly"`
+    Timestamp       string      `json:"timestamp"`
+    LastUpdateTimestamp string  `json:"lastUpdateTimestamp"`
+}
+type OrderStatusInfo struct {
+    Order           OrderStatusOrder `json:"order"`
+    // ENTERED_BOOK, FULLY_EXECUTED, REJECTED, CANCELLED, TRIGGER_PLACED, TRIGGER_ACTIVATION_FAILURE
+    Status          string      `json:"status"`
+    UpdateReason    *string     `json:"updateReason"`
+    Error           *string     `json:"error"`
+}
+type OrderStatusResponse struct {
+    Result      string              `json:"result"`
+    ServerTime  string              `json:"serverTime"`
+    Orders      []OrderStatusInfo   `json:"orders"`
+}
+
+
 // Batch order response
 type BatchStatus struct {
-    Status  string  `json:"status"`
-    OrderId string  `json:"order_id"`
+    Status      string  `json:"status"`
+    OrderId     string  `json:"order_id"`
+    OrderTag    string  `json:"order_tag,omitempty"`   // only for send, echoes instruction
+    CliOrdId    string  `json:"cliOrdId,omitempty"`
+    // Local only: instruction not sent or its call failed, see BatchResult.Err
+    Err         error   `json:"-"`
 }
 type BatchOrderResponse struct {
     Result      string          `json:"result"`
     ServerTime  string          `json:"serverTime"`
     BatchStatus []BatchStatus   `json:"batchStatus"`
 }
+
+
+// Outcome of one batch instruction, Index is its position in the batch as built.
+// Err is set when instruction was not sent (failed local checks, failed call),
+// Status then stays empty
+type BatchResult struct {
+    Index       int
+    Order       string  // send, edit, cancel
+    OrderTag    string
+    OrderId     string
+    CliOrdId    string
+    Status      string  // Kraken status, reject reason when not placed/edited/cancelled
+    Err         error
+}
+func (r BatchResult) Accepted() bool {
+    return r.Err == nil && (r.Status == "placed" || r.Status == "edited" || r.Status == "cancelled")
+}
+
+
+// Edit resting order, OrderId or CliOrdId (one of), only set fields change
+type EditOrderRequest struct {
+    OrderId         string      `json:"orderId,omitempty"       url:"orderId,omitempty"`
+    CliOrdId        string      `json:"cliOrdId,omitempty"      url:"cliOrdId,omitempty"`
+    Size            *float64    `json:"size,omitempty"          url:"size,omitempty"`
+    LimitPrice      *float64    `json:"limitPrice,omitempty"    url:"limitPrice,omitempty"`
+    StopPrice       *float64    `json:"stopPrice,omitempty"     url:"stopPrice,omitempty"`
+}
+type EditStatus struct {
+    OrderId         string  `json:"orderId"`
+    CliOrdId        string  `json:"cliOrdId,omitempty"`
+    Status          string  `json:"status"`     // edited succ, ex.: orderForEditNotFound, invalidSize, postWouldExecute
+    ReceivedTime    string  `json:"receivedTime"`
+}
+type EditOrderResponse struct {
+    Result      string      `json:"result"`
+    ServerTime  string      `json:"serverTime"`
+    EditStatus  EditStatus  `json:"editStatus"`
+}
+
+
+// Single cancel
+type CancelStatus struct {
+    OrderId         string  `json:"order_id"`
+    CliOrdId        string  `json:"cliOrdId,omitempty"`
+    Status          string  `json:"status"`     // cancelled succ, filled, notFound
+    ReceivedTime    string  `json:"receivedTime"`
+}
+type CancelOrderResponse struct {
+    Result          string          `json:"result"`
+    ServerTime      string          `json:"serverTime"`
+    CancelStatus    CancelStatus    `json:"cancelStatus"`
+}
+
+
+// Cancel all, optionally per symbol
+type CancelledOrder struct {
+    OrderId     string  `json:"order_id"`
+    CliOrdId    string  `json:"cliOrdId,omitempty"`
+}
+type CancelAllStatus struct {
+    Status          string              `json:"status"`     // cancelled, noOrdersToCancel
+    CancelOnly      string              `json:"cancelOnly"` // all or symbol
+    ReceivedTime    string              `json:"receivedTime"`
+    CancelledOrders []CancelledOrder    `json:"cancelledOrders"`
+}
+type CancelAllOrdersResponse struct {
+    Result          string          `json:"result"`
+    ServerTime      string          `json:"serverTime"`
+    CancelStatus    CancelAllStatus `json:"cancelStatus"`
+}
+
+
+// Dead man switch (cancelallordersafter), TriggerTime is when everything gets
+// cancelled unless re-armed before, empty once disarmed
+type DeadManSwitchStatus struct {
+    CurrentTime string  `json:"currentTime"`
+    TriggerTime string  `json:"triggerTime"`
+}
+type CancelAllOrdersAfterResponse struct {
+    Result      string              `json:"result"`
+    ServerTime  string              `json:"serverTime"`
+    Status      DeadManSwitchStatus `json:"status"`
+}
 //}}} Order
 
 
+//{{{ Preference-s
+// Multi-collateral (flex) only, symbol without preference trades cross margin
+type LeveragePreference struct {
+    Symbol      string  `json:"symbol"`
+    MaxLeverage float64 `json:"maxLeverage"`
+}
+type LeveragePreferencesResponse struct {
+    Result              string                  `json:"result"`
+    ServerTime          string                  `json:"serverTime"`
+    LeveragePreferences []LeveragePreference    `json:"leveragePreferences"`
+}
+
+
+// Currency PnL is realized in, ex.: "USD", "BTC"
+type PnlPreference struct {
+    Symbol      string  `json:"symbol"`
+    PnlCurrency string  `json:"pnlCurrency"`
+}
+type PnlPreferencesResponse struct {
+    Result      string          `json:"result"`
+    ServerTime  string          `json:"serverTime"`
+    Preferences []PnlPreference `json:"preferences"`
+}
+
+
+// Set calls only acknowledge
+type PreferenceResponse struct {
+    Result      string  `json:"result"`
+    ServerTime  string  `json:"serverTime"`
+}
+//}}} Preference-s
+
+
+//{{{ Transfer-s
+// Between own futures accounts, ex.: "flex" => "cash"; unit ex.: "usd", "xbt"
+type TransferRequest struct {
+    FromAccount string  `url:"fromAccount"`
+    ToAccount   string  `url:"toAccount"`
+    Unit        string  `url:"unit"`
+    Amount      float64 `url:"amount"`
+}
+
+
+// Between users (master and its sub accounts), balance pre-check reads API key
+// owner's accounts so it has to be skipped when FromUser is someone else
+type SubaccountTransferRequest struct {
+    FromUser            string  `url:"fromUser"`
+    ToUser              string  `url:"toUser"`
+    FromAccount         string  `url:"fromAccount"`
+    ToAccount           string  `url:"toAccount"`
+    Unit                string  `url:"unit"`
+    Amount              float64 `url:"amount"`
+    SkipBalanceCheck    bool    `url:"-"`
+}
+
+
+type TransferResponse struct {
+    Result      string  `json:"result"`
+    ServerTime  string  `json:"serverTime"`
+}
+//}}} Transfer-s
+
+
 //{{{ Fill
 // Multiple different fillId's can reference same orderId, when partially exectuted
 type Fill struct {
@@ -185,12 +542,70 @@ type Fill struct {
     Price       float64 `json:"price"`
     FillTime    string  `json:"fillTime"`
     FillType    string  `json:"fillType"`
+    // Optional, only when order was sent with one
+    CliOrdId    *string `json:"cliOrdId,omitempty"`
 }
 type FillsResponse struct {
     Result      string  `json:"result"`
     ServerTime  string  `json:"serverTime"`
     Fills       []Fill  `json:"fills"`
 }
+
+
+// Quote currencies Kraken uses in futures symbols, longest first so USDT wins over USD
+var quoteCurrencies = []string{"USDT", "USDC", "USD", "EUR", "GBP"}
+
+
+// "PF_XRPUSD" => "XRP", "USD"; "FI_XBTUSD_250926" => "XBT", "USD"
+func SplitSymbol(symbol string) (string, string, error) {
+    parts := strings.Split(symbol, "_")
+    if len(parts) < 2 {
+        return "", "", fmt.Errorf("unknown symbol format: %q", symbol)
+    }
+    pair := parts[1]
+    for _, quote := range quoteCurrencies {
+        if strings.HasSuffix(pair, quote) && len(pair) > len(quote) {
+            return strings.TrimSuffix(pair, quote), quote, nil
+        }
+    }
+    return "", "", fmt.Errorf("unknown quote currency in symbol: %q", symbol)
+}
+
+
+// Exchange fill => DB row
+// Linear (PF_, FF_): size is in coin, contract size 1
+// Inverse (PI_, FI_): size is number of 1 USD contracts, coin = size / price
+func (f Fill) ToOrderFill(owner string) (OrderFill, error) {
+    coin, currency, err := SplitSymbol(f.Symbol)
+    if err != nil {
+        return OrderFill{}, err
+    }
+    var coinAmount, currencyAmount float64
+    switch {
+    case strings.HasPrefix(f.Symbol, "PF_"), strings.HasPrefix(f.Symbol, "FF_"):
+        coinAmount, currencyAmount = f.Size, f.Size*f.Price
+    case strings.HasPrefix(f.Symbol, "PI_"), strings.HasPrefix(f.Symbol, "FI_"):
+        if f.Price <= 0 {
+            return OrderFill{}, fmt.Errorf("inverse fill %s without price", f.FillId)
+        }
+        coinAmount, currencyAmount = f.Size/f.Price, f.Size
+    default:
+        return OrderFill{}, fmt.Errorf("unknown contract type in symbol: %q", f.Symbol)
+    }
+    return OrderFill{
+        FillId:         f.FillId,
+        Symbol:         f.Symbol,
+        Side:           f.Side,
+        Price:          f.Price,
+        CoinAmount:     coinAmount,
+        Coin:           coin,
+        CurrencyAmount: currencyAmount,
+        Currency:       currency,
+        FillType:       f.FillType,
+        DateTime:       f.FillTime,
+        Owner:          owner,
+    }, nil
+}
 //}}} Fill
 
 
@@ -223,3 +638,35 @@ type OrderFill struct {
 
 //}}} OrderFill (DB)
 
+
+//{{{ Transfer (DB)
+// Audit row, one per attempt; FromUser/ToUser only for sub account transfers
+// Status: success, rejected (nothing moved), unknown (lost response, check exchange)
+type Transfer struct {
+    Id          int64
+    Owner       string
+    FromUser    *string
+    ToUser      *string
+    FromAccount string
+    ToAccount   string
+    Unit        string
+    Amount      float64
+    Status      string
+    Error       *string
+    DateTime    time.Time
+}
+
+//}}} Transfer (DB)
+
+
+//{{{ FundingRate (DB)
+// Market data, not per owner; one row per symbol and funding time
+type FundingRate struct {
+    Symbol          string
+    Rate            float64     // absolute, quote currency per contract
+    RelativeRate    float64     // fraction of price per period
+    DateTime        time.Time
+}
+
+//}}} FundingRate (DB)
+
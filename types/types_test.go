@@ -0,0 +1,177 @@
+package types
+
+import (
+    "bytes"
+    "encoding/json"
+    "math"
+    "testing"
+    "time"
+)
+
+
+// Kraken doc samples decoded strictly, new field upstream => test fails instead of value silently dropped
+func decodeStrict(data string, out any) error {
+    dec := json.NewDecoder(bytes.NewReader([]byte(data)))
+    dec.DisallowUnknownFields()
+    return dec.Decode(out)
+}
+
+
+//{{{ Ticker
+func TestTickersDecodeStrict(t *testing.T) {
+    data := `{"result":"success","serverTime":"2025-09-23T16:55:10.557Z","tickers":[
+        {"tag":"perpetual","pair":"XBT:USD","symbol":"PF_XBTUSD","markPrice":60210.5,"bid":60200,"bidSize":1.5,"ask":60220,"askSize":0.8,
+         "vol24h":1523.4,"volumeQuote":91234567.8,"openInterest":1834.2,"open24h":59800,"high24h":60500,"low24h":59500,
+         "last":60211,"lastTime":"2025-09-23T16:55:09.871Z","lastSize":0.01,"indexPrice":60205.3,
+         "fundingRate":0.0000123,"fundingRatePrediction":0.0000098,"suspended":false,"postOnly":false,"change24h":0.686},
+        {"symbol":"in_xbtusd","last":60205.3,"lastTime":"2025-09-23T16:55:10.000Z","tag":"perpetual","pair":"XBT:USD","markPrice":0,"suspended":false,"postOnly":false,"change24h":0.5}
+    ]}`
+    var resp TickersResponse
+    if err := decodeStrict(data, &resp); err != nil {
+        t.Fatalf("Decode failed: %v", err)
+    }
+    if len(resp.Tickers) != 2 {
+        t.Fatalf("Wrong number of tickers\nExpected:\t2\nGot:\t\t%d", len(resp.Tickers))
+    }
+    xbt, index := resp.Tickers[0], resp.Tickers[1]
+    if xbt.FundingRate == nil || *xbt.FundingRate != 0.0000123 || xbt.OpenInterest != 1834.2 || xbt.Ask == nil || *xbt.Ask != 60220 || xbt.IndexPrice != 60205.3 {
+        t.Errorf("Wrong perpetual ticker: %+v", xbt)
+    }
+    if index.Bid != nil || index.FundingRate != nil || index.Last == nil || *index.Last != 60205.3 {
+        t.Errorf("Index ticker should only have last: %+v", index)
+    }
+
+    // Missing optionals stay missing on the way out
+    out, err := json.Marshal(index)
+    if err != nil {
+        t.Fatalf("Marshal failed: %v", err)
+    }
+    if bytes.Contains(out, []byte(`"bid"`)) || bytes.Contains(out, []byte(`"fundingRate"`)) {
+        t.Errorf("Absent fields marshalled: %s", out)
+    }
+}
+//}}} Ticker
+
+
+//{{{ Instrument status
+func TestInstrumentStatusDecodeStrict(t *testing.T) {
+    tests := []struct {
+        name        string
+        data        string
+        out         any
+    }{
+        {"List",    `{"result":"success","serverTime":"2025-09-23T16:55:10.557Z","instrumentStatus":[{"tradeable":"PF_XBTUSD","experiencingDislocation":true,"priceDislocationDirection":"ABOVE_UPPER_BOUND","experiencingExtremeVolatility":false,"extremeVolatilityInitialMarginMultiplier":1}]}`, &InstrumentStatusListResponse{}},
+        {"Single",  `{"result":"success","serverTime":"2025-09-23T16:55:10.557Z","tradeable":"PF_XBTUSD","experiencingDislocation":false,"priceDislocationDirection":null,"experiencingExtremeVolatility":true,"extremeVolatilityInitialMarginMultiplier":2}`, &InstrumentStatusResponse{}},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            if err := decodeStrict(tc.data, tc.out); err != nil {
+                t.Fatalf("Decode failed: %v", err)
+            }
+        })
+    }
+    var single InstrumentStatusResponse
+    _ = json.Unmarshal([]byte(tests[1].data), &single)
+    if single.Tradeable != "PF_XBTUSD" || !single.ExperiencingExtremeVolatility || single.ExtremeVolatilityInitialMarginMultiplier != 2 || single.PriceDislocationDirection != nil {
+        t.Errorf("Wrong single status: %+v", single)
+    }
+}
+//}}} Instrument status
+
+
+//{{{ Public trade
+func TestPublicTradesDecodeStrict(t *testing.T) {
+    data := `{"result":"success","serverTime":"2025-09-23T16:55:10.557Z","history":[
+        {"time":"2025-09-23T16:55:09.871Z","trade_id":100,"price":60211,"size":0.01,"side":"buy","type":"fill","uid":"a3c4d5e6-0000-4000-8000-000000000001",
+         "instrument_identification_type":"ISIN","isin":"GB00J62YGL67","execution_venue":"KRFL","price_notation":"MONE","price_currency":"USD",
+         "notional_amount":602.11,"notional_currency":"USD","publication_time":"2025-09-23T16:55:09.871Z","publication_venue":"KRFL",
+         "transaction_identification_code":"a3c4d5e6-0000-4000-8000-000000000001","to_be_cleared":false},
+        {"time":"2025-09-23T16:55:08.100Z","trade_id":99,"price":60209,"size":0.5,"side":"sell","type":"liquidation","uid":"a3c4d5e6-0000-4000-8000-000000000002"}
+    ]}`
+    var resp PublicTradesResponse
+    if err := decodeStrict(data, &resp); err != nil {
+        t.Fatalf("Decode failed: %v", err)
+    }
+    if len(resp.History) != 2 || resp.History[0].TradeId != 100 || resp.History[0].NotionalAmount == nil || *resp.History[0].NotionalAmount != 602.11 || resp.History[1].Type != "liquidation" {
+        t.Errorf("Wrong trades: %+v", resp.History)
+    }
+}
+//}}} Public trade
+
+
+//{{{ Candle gaps
+func TestFillCandleGaps(t *testing.T) {
+    c := func(minute int64, close float64) Candle {
+        return Candle{Time: minute * 60000, Open: close, High: close, Low: close, Close: close, Volume: 1}
+    }
+    tests := []struct {
+        name            string
+        candles         []Candle
+        expectTimes     []int64     // minutes
+        expectVolume    []float64
+    }{
+        {"Empty",           nil,                                            []int64{},          []float64{}},
+        {"NoGap",           []Candle{c(0, 1), c(1, 2), c(2, 3)},            []int64{0, 1, 2},   []float64{1, 1, 1}},
+        {"Unsorted",        []Candle{c(2, 3), c(0, 1), c(1, 2)},            []int64{0, 1, 2},   []float64{1, 1, 1}},
+        {"Duplicate",       []Candle{c(0, 1), c(1, 2), c(1, 2)},            []int64{0, 1},      []float64{1, 1}},
+        {"Gap",             []Candle{c(0, 1), c(3, 4)},                     []int64{0, 1, 2, 3}, []float64{1, 0, 0, 1}},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            got := FillCandleGaps(tc.candles, time.Minute)
+            if len(got) != len(tc.expectTimes) {
+                t.Fatalf("Wrong number of candles\nExpected:\t%d\nGot:\t\t%d", len(tc.expectTimes), len(got))
+            }
+            for i := range got {
+                if got[i].Time != tc.expectTimes[i]*60000 || got[i].Volume != tc.expectVolume[i] {
+                    t.Errorf("Wrong candle %d\nExpected:\t%d %v\nGot:\t\t%d %v", i, tc.expectTimes[i]*60000, tc.expectVolume[i], got[i].Time, got[i].Volume)
+                }
+            }
+        })
+    }
+
+    // Filler is flat at previous close
+    got := FillCandleGaps([]Candle{c(0, 5), c(2, 7)}, time.Minute)
+    if f := got[1]; f.Open != 5 || f.High != 5 || f.Low != 5 || f.Close != 5 {
+        t.Errorf("Wrong filler candle: %+v", f)
+    }
+}
+//}}} Candle gaps
+
+
+//{{{ Fill to order fill
+func TestToOrderFill(t *testing.T) {
+    tests := []struct {
+        name                string
+        fill                Fill
+        expectCoin          string
+        expectCoinAmount    float64
+        expectCurrAmount    float64
+        expectErr           bool
+    }{
+        {"SuccLinearPerp",      Fill{FillId: "f1", Symbol: "PF_XBTUSD", Size: 0.5, Price: 100000},          "XBT",  0.5,    50000,  false},
+        {"SuccLinearFixed",     Fill{FillId: "f2", Symbol: "FF_XBTUSD_251226", Size: 2, Price: 100000},     "XBT",  2,      200000, false},
+        {"SuccInversePerp",     Fill{FillId: "f3", Symbol: "PI_XBTUSD", Size: 5000, Price: 100000},         "XBT",  0.05,   5000,   false},
+        {"SuccInverseFixed",    Fill{FillId: "f4", Symbol: "FI_ETHUSD_250926", Size: 300, Price: 3000},     "ETH",  0.1,    300,    false},
+        {"FailInverseNoPrice",  Fill{FillId: "f5", Symbol: "PI_XBTUSD", Size: 5000},                        "",     0,      0,      true},
+        {"FailUnknownType",     Fill{FillId: "f6", Symbol: "XX_XBTUSD", Size: 1, Price: 1},                 "",     0,      0,      true},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            of, err := tc.fill.ToOrderFill("owner")
+            if (err != nil) != tc.expectErr {
+                t.Fatalf("Unexpected error: %v", err)
+            }
+            if tc.expectErr {
+                return
+            }
+            if of.Coin != tc.expectCoin || math.Abs(of.CoinAmount-tc.expectCoinAmount) > 1e-12 || math.Abs(of.CurrencyAmount-tc.expectCurrAmount) > 1e-9 {
+                t.Errorf("Wrong amounts\nExpected:\t%s %v %v\nGot:\t\t%s %v %v", tc.expectCoin, tc.expectCoinAmount, tc.expectCurrAmount, of.Coin, of.CoinAmount, of.CurrencyAmount)
+            }
+        })
+    }
+}
+//}}} Fill to order fill
@@ -0,0 +1,204 @@
+package types
+
+import (
+    "errors"
+    "fmt"
+    "math"
+)
+// Pre-trade checks, catches mistakes before Kraken rejects the order
+
+
+const MaxCliOrdIdLen = 100
+
+
+var (
+    validOrderTypes     = map[string]bool{"lmt": true, "post": true, "stp": true, "mkt": true, "take_profit": true}
+    validSides          = map[string]bool{"buy": true, "sell": true}
+    validTriggerSignals = map[string]bool{"mark": true, "index": true, "last": true}
+    validTickTypes      = map[string]bool{"spot": true, "mark": true, "trade": true}
+)
+
+
+//{{{ Validation error
+type ValidationError struct {
+    Field   string
+    Reason  string
+}
+func (e *ValidationError) Error() string {
+    return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+//}}} Validation error
+
+
+//{{{ Validate
+func IsTriggerOrder(orderType string) bool {
+    return orderType == "stp" || orderType == "take_profit"
+}
+
+
+// All problems joined, errors.As(err, &*ValidationError) gives first one
+func (sor SendOrderRequest) Validate() error {
+    var errs []error
+    invalid := func(field, format string, args ...any) {
+        errs = append(errs, &ValidationError{Field: field, Reason: fmt.Sprintf(format, args...)})
+    }
+
+    if sor.Symbol == "" {
+        invalid("symbol", "required")
+    }
+    if !validOrderTypes[sor.OrderType] {
+        invalid("orderType", "%q is not one of lmt, post, stp, mkt, take_profit", sor.OrderType)
+    }
+    if !validSides[sor.Side] {
+        invalid("side", "%q is not one of buy, sell", sor.Side)
+    }
+    if sor.Size <= 0 {
+        invalid("size", "must be positive, got %v", sor.Size)
+    }
+    if sor.LimitPrice < 0 {
+        invalid("limitPrice", "must not be negative, got %v", sor.LimitPrice)
+    }
+    if (sor.OrderType == "lmt" || sor.OrderType == "post") && sor.LimitPrice <= 0 {
+        invalid("limitPrice", "required for %s order", sor.OrderType)
+    }
+    if IsTriggerOrder(sor.OrderType) {
+        if sor.StopPrice == nil || *sor.StopPrice <= 0 {
+            invalid("stopPrice", "required for %s order", sor.OrderType)
+        }
+        if sor.TriggerSignal == nil {
+            invalid("triggerSignal", "required for %s order", sor.OrderType)
+        }
+    }
+    if sor.TriggerSignal != nil && !validTriggerSignals[*sor.TriggerSignal] {
+        invalid("triggerSignal", "%q is not one of mark, index, last", *sor.TriggerSignal)
+    }
+    if len(sor.CliOrdId) > MaxCliOrdIdLen {
+        invalid("cliOrdId", "%d characters, max %d", len(sor.CliOrdId), MaxCliOrdIdLen)
+    }
+    return errors.Join(errs...)
+}
+
+
+// Exactly one of OrderId/CliOrdId, at least one field to change
+func (eor EditOrderRequest) Validate() error {
+    var errs []error
+    invalid := func(field, format string, args ...any) {
+        errs = append(errs, &ValidationError{Field: field, Reason: fmt.Sprintf(format, args...)})
+    }
+
+    if (eor.OrderId == "") == (eor.CliOrdId == "") {
+        invalid("orderId", "exactly one of orderId, cliOrdId required")
+    }
+    if eor.Size == nil && eor.LimitPrice == nil && eor.StopPrice == nil {
+        invalid("size", "nothing to edit, set size, limitPrice or stopPrice")
+    }
+    if eor.Size != nil && *eor.Size <= 0 {
+        invalid("size", "must be positive, got %v", *eor.Size)
+    }
+    if eor.LimitPrice != nil && *eor.LimitPrice <= 0 {
+        invalid("limitPrice", "must be positive, got %v", *eor.LimitPrice)
+    }
+    if eor.StopPrice != nil && *eor.StopPrice <= 0 {
+        invalid("stopPrice", "must be positive, got %v", *eor.StopPrice)
+    }
+    return errors.Join(errs...)
+}
+
+
+// Accounts set and different, unit set, amount positive
+func (tr TransferRequest) Validate() error {
+    return validateTransfer("", "", tr.FromAccount, tr.ToAccount, tr.Unit, tr.Amount)
+}
+
+
+// Same as TransferRequest, same account is fine as long as users differ
+func (str SubaccountTransferRequest) Validate() error {
+    var errs []error
+    if str.FromUser == "" || str.ToUser == "" {
+        errs = append(errs, &ValidationError{Field: "fromUser", Reason: "fromUser and toUser required"})
+    }
+    errs = append(errs, validateTransfer(str.FromUser, str.ToUser, str.FromAccount, str.ToAccount, str.Unit, str.Amount))
+    return errors.Join(errs...)
+}
+
+
+func validateTransfer(fromUser, toUser, fromAccount, toAccount, unit string, amount float64) error {
+    var errs []error
+    invalid := func(field, format string, args ...any) {
+        errs = append(errs, &ValidationError{Field: field, Reason: fmt.Sprintf(format, args...)})
+    }
+
+    if fromAccount == "" || toAccount == "" {
+        invalid("fromAccount", "fromAccount and toAccount required")
+    } else if fromAccount == toAccount && fromUser == toUser {
+        invalid("toAccount", "same as fromAccount")
+    }
+    if unit == "" {
+        invalid("unit", "required")
+    }
+    if amount <= 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+        invalid("amount", "must be positive, got %v", amount)
+    }
+    return errors.Join(errs...)
+}
+
+
+// Stop has to be on the far side of market or it triggers right away:
+//  stp buy / take_profit sell     => stopPrice above market
+//  stp sell / take_profit buy     => stopPrice below market
+// No-op for non trigger orders
+func (sor SendOrderRequest) ValidateStopSide(marketPrice float64) error {
+    if !IsTriggerOrder(sor.OrderType) || sor.StopPrice == nil || marketPrice <= 0 {
+        return nil
+    }
+    stop := *sor.StopPrice
+    above := (sor.OrderType == "stp") == (sor.Side == "buy")
+    if above && stop <= marketPrice {
+        return &ValidationError{Field: "stopPrice", Reason: fmt.Sprintf("%s %s stop %v must be above market %v", sor.OrderType, sor.Side, stop, marketPrice)}
+    }
+    if !above && stop >= marketPrice {
+        return &ValidationError{Field: "stopPrice", Reason: fmt.Sprintf("%s %s stop %v must be below market %v", sor.OrderType, sor.Side, stop, marketPrice)}
+    }
+    return nil
+}
+//}}} Validate
+
+
+//{{{ Candle query
+func ValidateTickType(tickType string) error {
+    if !validTickTypes[tickType] {
+        return &ValidationError{Field: "tickType", Reason: fmt.Sprintf("%q is not one of spot, mark, trade", tickType)}
+    }
+    return nil
+}
+
+
+func ValidateResolution(resolution string) error {
+    if _, ok := CandleResolutions[resolution]; !ok {
+        return &ValidationError{Field: "resolution", Reason: fmt.Sprintf("%q is not one of 1m, 5m, 15m, 30m, 1h, 4h, 12h, 1d, 1w", resolution)}
+    }
+    return nil
+}
+
+
+// All problems joined, same as SendOrderRequest.Validate
+func (q CandleQuery) Validate() error {
+    var errs []error
+    if err := ValidateTickType(q.TickType); err != nil {
+        errs = append(errs, err)
+    }
+    if q.Symbol == "" {
+        errs = append(errs, &ValidationError{Field: "symbol", Reason: "required"})
+    }
+    if err := ValidateResolution(q.Resolution); err != nil {
+        errs = append(errs, err)
+    }
+    if q.From.IsZero() {
+        errs = append(errs, &ValidationError{Field: "from", Reason: "required"})
+    }
+    if !q.To.IsZero() && q.To.Before(q.From) {
+        errs = append(errs, &ValidationError{Field: "to", Reason: "before from"})
+    }
+    return errors.Join(errs...)
+}
+//}}} Candle query
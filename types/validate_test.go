@@ -0,0 +1,149 @@
+package types
+
+import (
+    "errors"
+    "strings"
+    "testing"
+    "time"
+)
+
+
+//{{{ Validate
+func TestValidate(t *testing.T) {
+    floatPtr := func(f float64) *float64 { return &f }
+    strPtr := func(s string) *string { return &s }
+    valid := SendOrderRequest{
+        Symbol:     "PF_BCHUSD",
+        OrderType:  "post",
+        Side:       "buy",
+        Size:       0.25,
+        LimitPrice: 524,
+    }
+    with := func(change func(*SendOrderRequest)) SendOrderRequest {
+        sor := valid
+        change(&sor)
+        return sor
+    }
+
+    tests := []struct {
+        name            string
+        sor             SendOrderRequest
+        expectField     string
+    }{
+        {"SuccPost",                valid,                                                                  ""},
+        {"SuccMkt",                 with(func(s *SendOrderRequest) { s.OrderType = "mkt"; s.LimitPrice = 0 }), ""},
+        {"SuccStp",                 with(func(s *SendOrderRequest) { s.OrderType = "stp"; s.StopPrice = floatPtr(500); s.TriggerSignal = strPtr("mark") }), ""},
+        {"FailOrderType",           with(func(s *SendOrderRequest) { s.OrderType = "limit" }),              "orderType"},
+        {"FailSide",                with(func(s *SendOrderRequest) { s.Side = "long" }),                    "side"},
+        {"FailZeroSize",            with(func(s *SendOrderRequest) { s.Size = 0 }),                         "size"},
+        {"FailNegativeSize",        with(func(s *SendOrderRequest) { s.Size = -1 }),                        "size"},
+        {"FailNoLimitPrice",        with(func(s *SendOrderRequest) { s.LimitPrice = 0 }),                   "limitPrice"},
+        {"FailStpNoStopPrice",      with(func(s *SendOrderRequest) { s.OrderType = "stp"; s.TriggerSignal = strPtr("mark") }), "stopPrice"},
+        {"FailStpNoTrigger",        with(func(s *SendOrderRequest) { s.OrderType = "take_profit"; s.StopPrice = floatPtr(500) }), "triggerSignal"},
+        {"FailTriggerSignal",       with(func(s *SendOrderRequest) { s.TriggerSignal = strPtr("spot") }),   "triggerSignal"},
+        {"FailCliOrdIdTooLong",     with(func(s *SendOrderRequest) { s.CliOrdId = strings.Repeat("x", 101) }), "cliOrdId"},
+        {"FailNoSymbol",            with(func(s *SendOrderRequest) { s.Symbol = "" }),                      "symbol"},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            err := tc.sor.Validate()
+            if tc.expectField == "" {
+                if err != nil {
+                    t.Fatalf("Unexpected error: %v", err)
+                }
+                return
+            }
+            var validationErr *ValidationError
+            if !errors.As(err, &validationErr) {
+                t.Fatalf("Expected ValidationError, got: %v", err)
+            }
+            if validationErr.Field != tc.expectField {
+                t.Errorf("Wrong field\nExpected:\t%s\nGot:\t\t%s (%v)", tc.expectField, validationErr.Field, err)
+            }
+        })
+    }
+}
+//}}} Validate
+
+
+//{{{ Validate stop side
+// Mark 550, last 600, index 500; no triggerSignal => mark
+func TestValidateStopSide(t *testing.T) {
+    floatPtr := func(f float64) *float64 { return &f }
+    ticker := Ticker{Symbol: "PF_BCHUSD", MarkPrice: 550, Last: floatPtr(600), IndexPrice: 500}
+    tests := []struct {
+        name        string
+        orderType   string
+        side        string
+        signal      string
+        stopPrice   float64
+        expectErr   bool
+    }{
+        {"SuccStpBuyAbove",         "stp",          "buy",  "",         560,    false},
+        {"FailStpBuyBelow",         "stp",          "buy",  "",         540,    true},
+        {"SuccStpSellBelow",        "stp",          "sell", "",         540,    false},
+        {"FailStpSellAbove",        "stp",          "sell", "",         560,    true},
+        {"SuccTakeProfitBuyBelow",  "take_profit",  "buy",  "",         540,    false},
+        {"FailTakeProfitBuyAbove",  "take_profit",  "buy",  "",         560,    true},
+        {"SuccTakeProfitSellAbove", "take_profit",  "sell", "",         560,    false},
+        {"FailTakeProfitSellAtMkt", "take_profit",  "sell", "",         550,    true},
+        {"SuccNotTrigger",          "lmt",          "buy",  "",         560,    false},
+        {"SuccStpSellBelowMark",    "stp",          "sell", "mark",     540,    false},
+        {"FailStpSellAboveMark",    "stp",          "sell", "mark",     560,    true},
+        {"SuccStpSellBelowLast",    "stp",          "sell", "last",     590,    false},
+        {"FailStpSellAboveLast",    "stp",          "sell", "last",     610,    true},
+        {"SuccStpSellBelowIndex",   "stp",          "sell", "index",    490,    false},
+        {"FailStpSellAboveIndex",   "stp",          "sell", "index",    510,    true},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            sor := SendOrderRequest{OrderType: tc.orderType, Side: tc.side, StopPrice: floatPtr(tc.stopPrice)}
+            if tc.signal != "" {
+                sor.TriggerSignal = &tc.signal
+            }
+            err := sor.ValidateStopSide(ticker.SignalPrice(tc.signal))
+            if (err != nil) != tc.expectErr {
+                t.Errorf("Unexpected result: %v", err)
+            }
+        })
+    }
+}
+//}}} Validate stop side
+
+
+//{{{ Validate candle query
+func TestCandleQueryValidate(t *testing.T) {
+    from := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+    tests := []struct {
+        name        string
+        query       CandleQuery
+        expectField string
+    }{
+        {"Succ",            CandleQuery{TickType: "trade", Symbol: "PF_BCHUSD", Resolution: "1h", From: from, To: from.Add(time.Hour)},    ""},
+        {"SuccOpenEnd",     CandleQuery{TickType: "mark", Symbol: "PF_BCHUSD", Resolution: "1w", From: from},                           ""},
+        {"FailTickType",    CandleQuery{TickType: "last", Symbol: "PF_BCHUSD", Resolution: "1h", From: from},                           "tickType"},
+        {"FailSymbol",      CandleQuery{TickType: "trade", Resolution: "1h", From: from},                                              "symbol"},
+        {"FailResolution",  CandleQuery{TickType: "trade", Symbol: "PF_BCHUSD", Resolution: "2h", From: from},                          "resolution"},
+        {"FailNoFrom",      CandleQuery{TickType: "trade", Symbol: "PF_BCHUSD", Resolution: "1h"},                                     "from"},
+        {"FailToBeforeFrom", CandleQuery{TickType: "trade", Symbol: "PF_BCHUSD", Resolution: "1h", From: from, To: from.Add(-time.Hour)}, "to"},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            err := tc.query.Validate()
+            if tc.expectField == "" {
+                if err != nil {
+                    t.Errorf("Unexpected error: %v", err)
+                }
+                return
+            }
+            var validationErr *ValidationError
+            if !errors.As(err, &validationErr) || validationErr.Field != tc.expectField {
+                t.Errorf("Wrong field\nExpected:\t%s\nGot:\t\t%v", tc.expectField, err)
+            }
+        })
+    }
+}
+//}}} Validate candle query